@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/docker"
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/scheduler"
+)
+
+// jobExecTimeout is the timeout of exec jobs in seconds. Nobody waits on
+// them, so they get the longest the agent allows.
+const jobExecTimeout = 30 * 60
+
+// jobShutdownTimeout is how long an agent whose session ended waits for
+// running jobs before exiting.
+const jobShutdownTimeout = 10 * time.Minute
+
+// jobs runs the scheduled jobs of this agent; set in main unless the
+// backend is the mock.
+var jobs *scheduler.Scheduler
+
+// jobRunner performs jobs against dm.
+func jobRunner(dm docker.DockerClient) scheduler.Runner {
+	return func(job common.Job) (string, error) {
+		return runJob(dm, job)
+	}
+}
+
+func runJob(dm docker.DockerClient, job common.Job) (string, error) {
+	if job.Action == common.JobPruneImages {
+		res, err := dm.PruneImages(job.All)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Removed %d images, freed %d bytes", res.Deleted, res.Reclaimed), nil
+	}
+
+	id, err := findEnv(dm, job.Env)
+	if err != nil {
+		return "", err
+	}
+	switch job.Action {
+	case common.JobRestart:
+		if err := dm.RestartContainer(id, 0); err != nil {
+			return "", err
+		}
+		return "Restarted " + job.Env, nil
+
+	case common.JobBackup:
+		info, err := dm.BackupEnv(common.BackupEnvPayload{ID: id})
+		if err != nil {
+			return "", err
+		}
+		return "Wrote " + info.Name, nil
+
+	case common.JobExec:
+		res, err := dm.Exec(common.ExecPayload{ID: id, Cmd: []string{"sh", "-c", job.Command}, Timeout: jobExecTimeout})
+		if err != nil {
+			return "", err
+		}
+		out := strings.TrimSpace(res.Stdout + res.Stderr)
+		if res.TimedOut && res.KillError != "" {
+			return "", fmt.Errorf("timed out, %s: %s", res.KillError, out)
+		}
+		if res.TimedOut {
+			return "", fmt.Errorf("timed out: %s", out)
+		}
+		if res.ExitCode != 0 {
+			return "", fmt.Errorf("exit code %d: %s", res.ExitCode, out)
+		}
+		return out, nil
+
+	case common.JobInput:
+		if err := dm.SendInput(id, job.Command); err != nil {
+			return "", err
+		}
+		return "Sent " + job.Command, nil
+	}
+	return "", fmt.Errorf("unknown action %q", job.Action)
+}
+
+// waitJobs waits up to timeout for running jobs to finish and reports
+// whether they did.
+func waitJobs(timeout time.Duration) bool {
+	if jobs == nil {
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// findEnv looks up an environment by name at run time, since updating an
+// environment gives it a new container ID.
+func findEnv(dm docker.DockerClient, name string) (string, error) {
+	list, err := dm.ListContainers()
+	if err != nil {
+		return "", err
+	}
+	for _, c := range list {
+		if c.Name == name {
+			return c.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no environment named %s", name)
+}
@@ -7,24 +7,62 @@ import (
 	"io"
 	"net"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
 	"github.com/COMPANYNAMEHERE/PerSSH/internal/docker"
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/scheduler"
 	"github.com/COMPANYNAMEHERE/PerSSH/internal/sysinfo"
 )
 
 func main() {
 	listenAddr := flag.String("listen", "", "Address to listen on (e.g. :8080)")
+	shellID := flag.String("shell", "", "Open an interactive shell in a container (used by the client over a pty)")
+	backend := flag.String("backend", "", "Container engine: docker, podman or mock (detected when empty, falling back to mock)")
+	host := flag.String("host", "", "Docker or Podman API socket, e.g. unix:///run/podman/podman.sock (detected when empty)")
+	schedulerOnly := flag.Bool("scheduler", false, "Only run scheduled jobs, e.g. as a service so they run without a client connected")
 	flag.Parse()
 
 	// Initialize Docker Manager
-	dm, err := docker.NewManager()
+	dm, err := docker.NewManager(*backend, *host)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to connect to Docker: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to connect to the container engine: %v\n", err)
+		if *shellID == "" && *listenAddr == "" && !*schedulerOnly {
+			// The client reads stdout only; tell it why before exiting
+			json.NewEncoder(os.Stdout).Encode(common.Response{ID: common.EventAgentError, Error: "Failed to connect to the container engine: " + err.Error()})
+		}
 		os.Exit(1)
 	}
 	defer dm.Close()
 
+	if *shellID != "" {
+		if err := runShell(dm, *shellID); err != nil {
+			fmt.Fprintf(os.Stderr, "Shell error: %v\r\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Every agent serves jobs; the first one started runs them. A mock
+	// agent would take the lock, fail every job and use up catch-up runs
+	// in the real history, so it has no scheduler.
+	if dm.Backend().Name != common.BackendMock {
+		jobs = scheduler.New(scheduler.NewStore(scheduler.DefaultPath()), jobRunner(dm))
+	} else if *schedulerOnly {
+		fmt.Fprintf(os.Stderr, "Error: The scheduler needs Docker or Podman, not the mock backend\n")
+		os.Exit(1)
+	}
+	if *schedulerOnly {
+		jobs.Serve(nil)
+		return
+	}
+	stop := make(chan struct{})
+	if jobs != nil {
+		go jobs.Serve(stop)
+	}
+
 	if *listenAddr != "" {
 		// Server Mode
 		fmt.Printf("PerSSH Server starting...\n")
@@ -63,13 +101,31 @@ func main() {
 		}
 
 		processLoop(os.Stdin, os.Stdout, dm)
+
+		// The session is gone, but a job cut off halfway could leave a
+		// world with saving off or an environment stopped
+		close(stop)
+		if !waitJobs(jobShutdownTimeout) {
+			fmt.Fprintf(os.Stderr, "Exiting with jobs still running after %s\n", jobShutdownTimeout)
+		}
 	}
 }
 
+// eventSink pushes unsolicited responses (e.g. console output) to the client.
+type eventSink func(common.Response) error
+
 func processLoop(r io.Reader, w io.Writer, dm docker.DockerClient) {
 	decoder := json.NewDecoder(r)
 	encoder := json.NewEncoder(w)
 
+	// Events are written from other goroutines; keep whole lines intact
+	var mu sync.Mutex
+	emit := func(resp common.Response) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return encoder.Encode(resp)
+	}
+
 	for {
 		var req common.Request
 		if err := decoder.Decode(&req); err != nil {
@@ -79,21 +135,32 @@ func processLoop(r io.Reader, w io.Writer, dm docker.DockerClient) {
 			// If JSON is malformed, we lose synchronization.
 			// Log it and stop.
 			fmt.Fprintf(os.Stderr, "Decode error: %v\n", err)
-			sendErrorTo(w, "DECODE", "Failed to decode request: "+err.Error())
+			emit(common.Response{ID: "DECODE", Success: false, Error: "Failed to decode request: " + err.Error()})
 			break
 		}
 
 		fmt.Fprintf(os.Stderr, "Received Request: ID=%s Type=%s\n", req.ID, req.Type)
 
-		resp := handleRequest(req, dm)
-		
+		switch req.Type {
+		case common.CmdContainerStats, common.CmdExec, common.CmdPullImage, common.CmdBackupEnv, common.CmdRestoreBackup, common.CmdRunJob:
+			// Sampling takes about a second, commands up to their
+			// timeout and pulls, backups and jobs minutes; don't hold
+			// up other requests
+			go func(req common.Request) {
+				emit(handleRequest(req, dm, emit))
+			}(req)
+			continue
+		}
+
+		resp := handleRequest(req, dm, emit)
+
 		if resp.Success {
 			fmt.Fprintf(os.Stderr, "Sending Success Response: ID=%s\n", resp.ID)
 		} else {
 			fmt.Fprintf(os.Stderr, "Sending Error Response: ID=%s Error=%s\n", resp.ID, resp.Error)
 		}
 
-		if err := encoder.Encode(resp); err != nil {
+		if err := emit(resp); err != nil {
 			// Write failed, stop processing for this connection
 			fmt.Fprintf(os.Stderr, "Encode error: %v\n", err)
 			break
@@ -101,7 +168,7 @@ func processLoop(r io.Reader, w io.Writer, dm docker.DockerClient) {
 	}
 }
 
-func handleRequest(req common.Request, dm docker.DockerClient) common.Response {
+func handleRequest(req common.Request, dm docker.DockerClient, emit eventSink) common.Response {
 	resp := common.Response{
 		ID:      req.ID,
 		Success: true,
@@ -118,6 +185,7 @@ func handleRequest(req common.Request, dm docker.DockerClient) common.Response {
 			resp.Error = err.Error()
 		} else {
 			stats.DockerRunning = dm.IsRunning()
+			stats.Backend = dm.Backend()
 			resp.Data = stats
 		}
 
@@ -142,7 +210,9 @@ func handleRequest(req common.Request, dm docker.DockerClient) common.Response {
 				resp.Success = false
 				resp.Error = "Failed to parse creation payload: " + err.Error()
 			} else {
-				id, err := dm.CreateContainer(payload)
+				id, err := dm.CreateContainer(payload, func(p common.PullProgress) {
+					emit(common.Response{ID: common.EventPull, Success: true, Data: p})
+				})
 				if err != nil {
 					resp.Success = false
 					resp.Error = err.Error()
@@ -156,6 +226,109 @@ func handleRequest(req common.Request, dm docker.DockerClient) common.Response {
 			}
 		}
 
+	case common.CmdGetEnvConfig:
+		id, ok := req.Payload.(string)
+		if !ok {
+			resp.Success = false
+			resp.Error = "Payload must be a string (container ID)"
+		} else if payload, err := dm.GetEnvConfig(id); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = payload
+		}
+
+	case common.CmdUpdateEnv:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.UpdateEnvPayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.ID == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for UPDATE_ENV"
+		} else if err := checkManaged(dm, payload.ID, payload.Force); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			id, err := dm.RecreateContainer(payload.ID, payload.Payload, func(p common.PullProgress) {
+				emit(common.Response{ID: common.EventPull, Success: true, Data: p})
+			})
+			if err != nil {
+				resp.Success = false
+				resp.Error = err.Error()
+			}
+			// Set even on error when only cleaning up the old container failed
+			resp.Data = id
+		}
+
+	case common.CmdAdoptEnv:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.AdoptEnvPayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.ID == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for ADOPT_ENV"
+		} else {
+			id, err := dm.AdoptContainer(payload.ID, payload.Type)
+			if err != nil {
+				resp.Success = false
+				resp.Error = err.Error()
+			}
+			resp.Data = id
+		}
+
+	case common.CmdPullImage:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.PullImagePayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.Image == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for PULL_IMAGE"
+		} else if err := dm.PullImage(payload.Image, payload.Auth, func(p common.PullProgress) {
+			emit(common.Response{ID: common.EventPull, Success: true, Data: p})
+		}); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdListImages:
+		images, err := dm.ListImages()
+		if err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = images
+		}
+
+	case common.CmdRemoveImage:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.RemoveImagePayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.Image == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for REMOVE_IMAGE"
+		} else if err := dm.RemoveImage(payload.Image, payload.Force); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdPruneImages:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.PruneImagesPayload
+		if req.Payload != nil && json.Unmarshal(b, &payload) != nil {
+			resp.Success = false
+			resp.Error = "Invalid payload format for PRUNE_IMAGES"
+		} else if res, err := dm.PruneImages(payload.All); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = res
+		}
+
+	case common.CmdDiskUsage:
+		usage, err := dm.DiskUsage()
+		if err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = usage
+		}
+
 	case common.CmdStartEnv:
 		id, ok := req.Payload.(string)
 		if !ok {
@@ -176,28 +349,341 @@ func handleRequest(req common.Request, dm docker.DockerClient) common.Response {
 			resp.Error = err.Error()
 		}
 
+	case common.CmdRestartEnv:
+		// A bare ID restarts with the container's stop timeout
+		var payload common.RestartEnvPayload
+		if id, ok := req.Payload.(string); ok {
+			payload.ID = id
+		} else {
+			b, _ := json.Marshal(req.Payload)
+			json.Unmarshal(b, &payload)
+		}
+		if payload.ID == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for RESTART_ENV"
+		} else if err := dm.RestartContainer(payload.ID, payload.Timeout); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdPauseEnv, common.CmdUnpauseEnv:
+		id, ok := req.Payload.(string)
+		if !ok {
+			resp.Success = false
+			resp.Error = "Payload must be a string (container ID)"
+			break
+		}
+		var err error
+		if req.Type == common.CmdPauseEnv {
+			err = dm.PauseContainer(id)
+		} else {
+			err = dm.UnpauseContainer(id)
+		}
+		if err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdRenameEnv:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.RenameEnvPayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.ID == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for RENAME_ENV"
+		} else if err := dm.RenameContainer(payload.ID, payload.Name); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdKillEnv:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.KillEnvPayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.ID == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for KILL_ENV"
+		} else if err := checkManaged(dm, payload.ID, payload.Force); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else if err := dm.KillContainer(payload.ID, payload.Signal); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
 	case common.CmdRemoveEnv:
+		// Older clients send the bare ID, which keeps volumes and isn't forced
+		payload := common.RemoveEnvPayload{KeepVolumes: true}
+		if id, ok := req.Payload.(string); ok {
+			payload.ID = id
+		} else {
+			b, _ := json.Marshal(req.Payload)
+			json.Unmarshal(b, &payload)
+		}
+		if payload.ID == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for REMOVE_ENV"
+		} else if err := checkManaged(dm, payload.ID, payload.Force); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else if err := dm.RemoveContainer(payload.ID, !payload.KeepVolumes); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdDeployStack:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.DeployStackPayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.Name == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for DEPLOY_STACK"
+		} else if err := dm.DeployStack(payload.Name, []byte(payload.Compose), payload.Auth, func(p common.PullProgress) {
+			emit(common.Response{ID: common.EventPull, Success: true, Data: p})
+		}); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdStartStack, common.CmdStopStack:
+		name, ok := req.Payload.(string)
+		if !ok {
+			resp.Success = false
+			resp.Error = "Payload must be a string (stack name)"
+			break
+		}
+		var err error
+		if req.Type == common.CmdStartStack {
+			err = dm.StartStack(name)
+		} else {
+			err = dm.StopStack(name)
+		}
+		if err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdRemoveStack:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.RemoveStackPayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.Name == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for REMOVE_STACK"
+		} else if err := dm.RemoveStack(payload.Name, !payload.KeepVolumes); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdContainerStats:
+		// Optional container ID; all running containers when empty
+		id, _ := req.Payload.(string)
+		stats, err := dm.ContainerStats(id)
+		if err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = stats
+		}
+
+	case common.CmdExec:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.ExecPayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.ID == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for EXEC"
+		} else if res, err := dm.Exec(payload); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = res
+		}
+
+	case common.CmdInspectEnv:
 		id, ok := req.Payload.(string)
 		if !ok {
 			resp.Success = false
 			resp.Error = "Payload must be a string (container ID)"
-		} else if err := dm.RemoveContainer(id); err != nil {
+		} else if info, err := dm.InspectEnv(id); err != nil {
 			resp.Success = false
 			resp.Error = err.Error()
+		} else {
+			resp.Data = info
 		}
 
-	case common.CmdGetLogs:
+	case common.CmdBackupEnv:
+		// A bare ID backs up without stopping
+		var payload common.BackupEnvPayload
+		if id, ok := req.Payload.(string); ok {
+			payload.ID = id
+		} else {
+			b, _ := json.Marshal(req.Payload)
+			json.Unmarshal(b, &payload)
+		}
+		if payload.ID == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for BACKUP_ENV"
+		} else {
+			info, err := dm.BackupEnv(payload)
+			if err != nil {
+				resp.Success = false
+				resp.Error = err.Error()
+			}
+			// Set even on error when only resuming the environment failed
+			if info.Name != "" {
+				resp.Data = info
+			}
+		}
+
+	case common.CmdListBackups:
+		list, err := dm.ListBackups()
+		if err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = list
+		}
+
+	case common.CmdRestoreBackup:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.RestoreBackupPayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.ID == "" || payload.Name == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for RESTORE_BACKUP"
+		} else if err := checkManaged(dm, payload.ID, payload.Force); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else if err := dm.RestoreBackup(payload.ID, payload.Name); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdDeleteBackup:
+		name, ok := req.Payload.(string)
+		if !ok {
+			resp.Success = false
+			resp.Error = "Payload must be a string (backup name)"
+		} else if err := dm.DeleteBackup(name); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdListJobs:
+		if jobs == nil {
+			resp.Success = false
+			resp.Error = "Scheduler not available"
+		} else if list, err := jobs.Store().List(); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = list
+		}
+
+	case common.CmdSaveJob:
+		b, _ := json.Marshal(req.Payload)
+		var job common.Job
+		if err := json.Unmarshal(b, &job); err != nil {
+			resp.Success = false
+			resp.Error = "Invalid payload format for SAVE_JOB"
+		} else if jobs == nil {
+			resp.Success = false
+			resp.Error = "Scheduler not available"
+		} else if saved, err := jobs.Store().Save(job, time.Now()); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = saved
+		}
+
+	case common.CmdDeleteJob, common.CmdRunJob:
+		id, ok := req.Payload.(string)
+		if !ok {
+			resp.Success = false
+			resp.Error = "Payload must be a string (job ID)"
+		} else if jobs == nil {
+			resp.Success = false
+			resp.Error = "Scheduler not available"
+		} else if req.Type == common.CmdDeleteJob {
+			if err := jobs.Store().Delete(id); err != nil {
+				resp.Success = false
+				resp.Error = err.Error()
+			}
+		} else if run, err := jobs.RunJob(id); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			// A failed job is a successful request; the run says how it went
+			resp.Data = run
+		}
+
+	case common.CmdGetHealth:
 		id, ok := req.Payload.(string)
 		if !ok {
 			resp.Success = false
 			resp.Error = "Payload must be a string (container ID)"
+		} else if health, err := dm.GetHealth(id); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = health
+		}
+
+	case common.CmdListVolumes:
+		volumes, err := dm.ListVolumes()
+		if err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = volumes
+		}
+
+	case common.CmdListNetworks:
+		networks, err := dm.ListNetworks()
+		if err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = networks
+		}
+
+	case common.CmdCreateNetwork:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.CreateNetworkPayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.Name == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for CREATE_NETWORK"
+		} else if id, err := dm.CreateNetwork(payload); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = id
+		}
+
+	case common.CmdRemoveNetwork:
+		name, ok := req.Payload.(string)
+		if !ok {
+			resp.Success = false
+			resp.Error = "Payload must be a string (network name)"
+		} else if err := dm.RemoveNetwork(name); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdGetLogs:
+		// A bare ID returns the last 100 lines
+		var q common.LogsQuery
+		if id, ok := req.Payload.(string); ok {
+			q.ID = id
+		} else {
+			b, _ := json.Marshal(req.Payload)
+			json.Unmarshal(b, &q)
+		}
+		if q.ID == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for GET_LOGS"
 		} else {
-			logs, err := dm.GetLogs(id)
+			res, err := dm.GetLogs(q)
 			if err != nil {
 				resp.Success = false
 				resp.Error = err.Error()
 			} else {
-				resp.Data = logs
+				resp.Data = res
 			}
 		}
 
@@ -227,6 +713,63 @@ func handleRequest(req common.Request, dm docker.DockerClient) common.Response {
 			resp.Error = "Invalid payload format for SEND_INPUT"
 		}
 
+	case common.CmdAttachEnv:
+		id, ok := req.Payload.(string)
+		if !ok {
+			resp.Success = false
+			resp.Error = "Payload must be a string (container ID)"
+		} else {
+			err := dm.AttachConsole(id, func(out common.ConsoleOutput) {
+				push := common.Response{ID: common.EventConsole, Success: true, Data: out}
+				if err := emit(push); err != nil {
+					// Client is gone; stop streaming to it
+					go dm.DetachConsole(id)
+				}
+			})
+			if err != nil {
+				resp.Success = false
+				resp.Error = err.Error()
+			} else {
+				resp.Data = id
+			}
+		}
+
+	case common.CmdDetachEnv:
+		id, ok := req.Payload.(string)
+		if !ok {
+			resp.Success = false
+			resp.Error = "Payload must be a string (container ID)"
+		} else if err := dm.DetachConsole(id); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		}
+
+	case common.CmdGetLimits:
+		id, ok := req.Payload.(string)
+		if !ok {
+			resp.Success = false
+			resp.Error = "Payload must be a string (container ID)"
+		} else if limits, err := dm.GetLimits(id); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else {
+			resp.Data = limits
+		}
+
+	case common.CmdUpdateLimits:
+		b, _ := json.Marshal(req.Payload)
+		var payload common.UpdateLimitsPayload
+		if err := json.Unmarshal(b, &payload); err != nil || payload.ID == "" {
+			resp.Success = false
+			resp.Error = "Invalid payload format for UPDATE_LIMITS"
+		} else if err := dm.UpdateLimits(payload.ID, payload.Resources); err != nil {
+			resp.Success = false
+			resp.Error = err.Error()
+		} else if limits, err := dm.GetLimits(payload.ID); err == nil {
+			// Reply with what actually applies now
+			resp.Data = limits
+		}
+
 	default:
 		resp.Success = false
 		resp.Error = "Unknown command: " + string(req.Type)
@@ -235,6 +778,29 @@ func handleRequest(req common.Request, dm docker.DockerClient) common.Response {
 	return resp
 }
 
+// checkManaged refuses destructive actions on containers not created or
+// adopted by PerSSH unless force is set. IDs that match no container are
+// left for the action itself to report.
+func checkManaged(dm docker.DockerClient, id string, force bool) error {
+	if force {
+		return nil
+	}
+	list, err := dm.ListContainers()
+	if err != nil {
+		return err
+	}
+	for _, c := range list {
+		// Listed IDs are short, requests may carry the full one
+		if c.ID == id || c.Name == id || strings.HasPrefix(id, c.ID) {
+			if !c.Managed() {
+				return fmt.Errorf("%s is not managed by PerSSH; adopt it first or force the action", c.Name)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
 func sendError(id, msg string) {
 	sendErrorTo(os.Stdout, id, msg)
 }
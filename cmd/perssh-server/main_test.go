@@ -2,10 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
 	"github.com/COMPANYNAMEHERE/PerSSH/internal/docker"
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/scheduler"
 )
 
 func TestHandlePing(t *testing.T) {
@@ -17,7 +21,7 @@ func TestHandlePing(t *testing.T) {
 		Type: common.CmdPing,
 	}
 
-	resp := handleRequest(req, dm)
+	resp := handleRequest(req, dm, discardEvents)
 
 	if resp.ID != "test-1" {
 		t.Errorf("Expected ID test-1, got %s", resp.ID)
@@ -40,7 +44,7 @@ func TestHandleUnknownCommand(t *testing.T) {
 		Type: "GHOST_CMD",
 	}
 
-	resp := handleRequest(req, dm)
+	resp := handleRequest(req, dm, discardEvents)
 
 	if resp.Success {
 		t.Error("Expected failure for unknown command, but got success")
@@ -51,6 +55,539 @@ func TestHandleUnknownCommand(t *testing.T) {
 	}
 }
 
+func discardEvents(common.Response) error { return nil }
+
+func TestTelemetryReportsBackend(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "unix://"+filepath.Join(t.TempDir(), "docker.sock"))
+	dm, err := docker.NewManager("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := handleRequest(common.Request{ID: "telemetry", Type: common.CmdGetTelemetry}, dm, discardEvents)
+	if !resp.Success {
+		t.Skipf("No telemetry here: %s", resp.Error)
+	}
+	b := resp.Data.(*common.TelemetryData).Backend
+	if b.Name != common.BackendMock || b.Fallback == "" {
+		t.Errorf("Expected the mock with its reason, got %+v", b)
+	}
+}
+
+func TestAttachStreamsInput(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, err := dm.CreateContainer(common.CreateEnvPayload{Name: "mc", Image: "itzg/minecraft-server"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []common.Response
+	record := func(r common.Response) error {
+		events = append(events, r)
+		return nil
+	}
+
+	resp := handleRequest(common.Request{ID: "attach", Type: common.CmdAttachEnv, Payload: id}, dm, record)
+	if !resp.Success {
+		t.Fatalf("Attach failed: %s", resp.Error)
+	}
+
+	input := map[string]interface{}{"id": id, "data": "say hi"}
+	resp = handleRequest(common.Request{ID: "input", Type: common.CmdSendInput, Payload: input}, dm, record)
+	if !resp.Success {
+		t.Fatalf("Input failed: %s", resp.Error)
+	}
+
+	if len(events) != 1 || events[0].ID != common.EventConsole {
+		t.Fatalf("Expected one console event, got %+v", events)
+	}
+	out, ok := events[0].Data.(common.ConsoleOutput)
+	if !ok || out.ID != id || out.Data != "> say hi\n" {
+		t.Errorf("Unexpected console output: %+v", events[0].Data)
+	}
+
+	handleRequest(common.Request{ID: "detach", Type: common.CmdDetachEnv, Payload: id}, dm, record)
+	handleRequest(common.Request{ID: "input", Type: common.CmdSendInput, Payload: input}, dm, record)
+	if len(events) != 1 {
+		t.Errorf("Expected no output after detach, got %d events", len(events))
+	}
+}
+
+func TestUpdateLimits(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, err := dm.CreateContainer(common.CreateEnvPayload{Name: "mc", RamLimit: "2g"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Payloads arrive as generic maps after JSON decoding
+	payload := map[string]interface{}{"id": id, "resources": map[string]interface{}{"cpus": 1.5, "pids_limit": 256}}
+	resp := handleRequest(common.Request{ID: "limits", Type: common.CmdUpdateLimits, Payload: payload}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Update failed: %s", resp.Error)
+	}
+	limits, ok := resp.Data.(common.Resources)
+	if !ok || limits.Memory != "2g" || limits.CPUs != 1.5 || limits.PidsLimit != 256 {
+		t.Errorf("Unexpected effective limits: %+v", resp.Data)
+	}
+
+	payload["resources"] = map[string]interface{}{"storage_size": "10g"}
+	resp = handleRequest(common.Request{ID: "limits", Type: common.CmdUpdateLimits, Payload: payload}, dm, discardEvents)
+	if resp.Success {
+		t.Error("Expected storage size update to be rejected")
+	}
+}
+
+func TestRemoveKeepsVolumesForBareID(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "mc", Mounts: []common.Mount{{Target: "/data"}}}, nil)
+
+	resp := handleRequest(common.Request{ID: "action", Type: common.CmdRemoveEnv, Payload: id}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Remove failed: %s", resp.Error)
+	}
+	vols, _ := dm.ListVolumes()
+	if len(vols) != 1 {
+		t.Fatalf("Expected volume to be kept, got %+v", vols)
+	}
+
+	id, _ = dm.CreateContainer(common.CreateEnvPayload{Name: "mc", Mounts: []common.Mount{{Target: "/data"}}}, nil)
+	payload := map[string]interface{}{"id": id, "keep_volumes": false}
+	resp = handleRequest(common.Request{ID: "action", Type: common.CmdRemoveEnv, Payload: payload}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Remove failed: %s", resp.Error)
+	}
+	if vols, _ := dm.ListVolumes(); len(vols) != 0 {
+		t.Errorf("Expected volume to be deleted, got %+v", vols)
+	}
+}
+
+func TestCreatePushesPullProgress(t *testing.T) {
+	dm := docker.NewMockManager()
+
+	var events []common.Response
+	record := func(r common.Response) error {
+		events = append(events, r)
+		return nil
+	}
+
+	payload := common.CreateEnvPayload{Name: "web", Image: "nginx:latest"}
+	resp := handleRequest(common.Request{ID: "create", Type: common.CmdCreateEnv, Payload: payload}, dm, record)
+	if !resp.Success {
+		t.Fatalf("Create failed: %s", resp.Error)
+	}
+
+	if len(events) == 0 {
+		t.Fatal("Expected pull progress events")
+	}
+	last, ok := events[len(events)-1].Data.(common.PullProgress)
+	if events[len(events)-1].ID != common.EventPull || !ok || !last.Done || last.Image != "nginx:latest" {
+		t.Errorf("Unexpected final pull event: %+v", events[len(events)-1])
+	}
+}
+
+func TestRestartAcceptsBareID(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "mc"}, nil)
+
+	resp := handleRequest(common.Request{ID: "action", Type: common.CmdRestartEnv, Payload: id}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Restart failed: %s", resp.Error)
+	}
+
+	payload := map[string]interface{}{"id": id, "signal": "nope"}
+	resp = handleRequest(common.Request{ID: "action", Type: common.CmdKillEnv, Payload: payload}, dm, discardEvents)
+	if resp.Success {
+		t.Error("Expected unknown signal to be rejected")
+	}
+}
+
+func TestUpdateEnvRoundTrip(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "web", Image: "nginx:1.25"}, nil)
+
+	resp := handleRequest(common.Request{ID: "envconfig", Type: common.CmdGetEnvConfig, Payload: id}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Get config failed: %s", resp.Error)
+	}
+	payload := resp.Data.(common.CreateEnvPayload)
+	payload.Image = "nginx:1.27"
+
+	var events []common.Response
+	record := func(r common.Response) error {
+		events = append(events, r)
+		return nil
+	}
+	// Goes through JSON like a real client request
+	b, _ := json.Marshal(common.UpdateEnvPayload{ID: id, Payload: payload})
+	var generic interface{}
+	json.Unmarshal(b, &generic)
+	resp = handleRequest(common.Request{ID: "update", Type: common.CmdUpdateEnv, Payload: generic}, dm, record)
+	if !resp.Success {
+		t.Fatalf("Update failed: %s", resp.Error)
+	}
+	if len(events) == 0 {
+		t.Error("Expected pull progress for the new image")
+	}
+	list, _ := dm.ListContainers()
+	if len(list) != 1 || list[0].Image != "nginx:1.27" || list[0].ID != resp.Data {
+		t.Errorf("Unexpected containers after update: %+v", list)
+	}
+}
+
+func TestStackRequests(t *testing.T) {
+	dm := docker.NewMockManager()
+	compose := "services:\n  web:\n    image: nginx:1.25\n  cache:\n    image: redis:7\n"
+
+	var events []common.Response
+	record := func(r common.Response) error {
+		events = append(events, r)
+		return nil
+	}
+	b, _ := json.Marshal(common.DeployStackPayload{Name: "shop", Compose: compose})
+	var generic interface{}
+	json.Unmarshal(b, &generic)
+	resp := handleRequest(common.Request{ID: "create", Type: common.CmdDeployStack, Payload: generic}, dm, record)
+	if !resp.Success {
+		t.Fatalf("Deploy failed: %s", resp.Error)
+	}
+	if len(events) == 0 {
+		t.Error("Expected pull progress for the stack images")
+	}
+	list, _ := dm.ListContainers()
+	if len(list) != 2 || list[0].Stack != "shop" {
+		t.Fatalf("Unexpected containers: %+v", list)
+	}
+
+	resp = handleRequest(common.Request{ID: "action", Type: common.CmdStopStack, Payload: "shop"}, dm, discardEvents)
+	if !resp.Success {
+		t.Errorf("Stop failed: %s", resp.Error)
+	}
+	resp = handleRequest(common.Request{ID: "action", Type: common.CmdRemoveStack, Payload: map[string]interface{}{"Name": "shop"}}, dm, discardEvents)
+	if !resp.Success {
+		t.Errorf("Remove failed: %s", resp.Error)
+	}
+	if list, _ := dm.ListContainers(); len(list) != 0 {
+		t.Errorf("Stack not removed: %+v", list)
+	}
+}
+
+func TestNetworkRequests(t *testing.T) {
+	dm := docker.NewMockManager()
+	resp := handleRequest(common.Request{ID: "network", Type: common.CmdCreateNetwork, Payload: map[string]interface{}{"name": "shop"}}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Create network failed: %s", resp.Error)
+	}
+
+	b, _ := json.Marshal(common.CreateEnvPayload{Name: "db", Networks: []common.NetworkAttachment{{Name: "shop", Aliases: []string{"postgres"}}}})
+	var generic interface{}
+	json.Unmarshal(b, &generic)
+	resp = handleRequest(common.Request{ID: "create", Type: common.CmdCreateEnv, Payload: generic}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Create failed: %s", resp.Error)
+	}
+	list, _ := dm.ListContainers()
+	if len(list) != 1 || len(list[0].Networks) != 1 || list[0].Networks[0] != "shop" {
+		t.Errorf("Unexpected containers: %+v", list)
+	}
+
+	resp = handleRequest(common.Request{ID: "network", Type: common.CmdRemoveNetwork, Payload: "shop"}, dm, discardEvents)
+	if resp.Success {
+		t.Error("Removed a network in use")
+	}
+	dm.RemoveContainer(list[0].ID, false)
+	resp = handleRequest(common.Request{ID: "network", Type: common.CmdRemoveNetwork, Payload: "shop"}, dm, discardEvents)
+	if !resp.Success {
+		t.Errorf("Remove network failed: %s", resp.Error)
+	}
+}
+
+func TestGetHealth(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "web", Health: &common.Healthcheck{Test: []string{"true"}}}, nil)
+	dm.StartContainer(id)
+
+	resp := handleRequest(common.Request{ID: "health", Type: common.CmdGetHealth, Payload: id}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Get health failed: %s", resp.Error)
+	}
+	if h := resp.Data.(common.HealthInfo); h.Status != "healthy" {
+		t.Errorf("Unexpected health: %+v", h)
+	}
+	list, _ := dm.ListContainers()
+	if list[0].Health != "healthy" {
+		t.Errorf("List misses the health state: %+v", list[0])
+	}
+}
+
+func TestExec(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "web"}, nil)
+	dm.StartContainer(id)
+
+	// Payloads arrive as generic JSON
+	payload := map[string]interface{}{"id": id, "cmd": []interface{}{"sh", "-c", "echo hello; exit 2"}}
+	resp := handleRequest(common.Request{ID: "exec", Type: common.CmdExec, Payload: payload}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Exec failed: %s", resp.Error)
+	}
+	if res := resp.Data.(common.ExecResult); res.Stdout != "Mock exec in web: sh -c echo hello; exit 2\n" || res.ExitCode != 0 {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+
+	resp = handleRequest(common.Request{ID: "exec", Type: common.CmdExec, Payload: id}, dm, discardEvents)
+	if resp.Success {
+		t.Error("Expected an error for a bare ID")
+	}
+}
+
+func TestAdoptRejectsManaged(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "web"}, nil)
+
+	payload := map[string]interface{}{"id": id, "type": "STANDARD"}
+	resp := handleRequest(common.Request{ID: "action", Type: common.CmdAdoptEnv, Payload: payload}, dm, discardEvents)
+	if resp.Success {
+		t.Error("Expected an error for a managed container")
+	}
+	resp = handleRequest(common.Request{ID: "action", Type: common.CmdAdoptEnv, Payload: id}, dm, discardEvents)
+	if resp.Success || resp.Error != "Invalid payload format for ADOPT_ENV" {
+		t.Errorf("Expected a payload error, got %+v", resp)
+	}
+}
+
+// unmanagedMock lists every container without the PerSSH labels, like
+// containers started with plain docker.
+type unmanagedMock struct {
+	*docker.MockManager
+}
+
+func (m unmanagedMock) ListContainers() ([]common.ContainerInfo, error) {
+	list, err := m.MockManager.ListContainers()
+	for i := range list {
+		list[i].Labels = nil
+	}
+	return list, err
+}
+
+func TestDestructiveActionsNeedForceForUnmanaged(t *testing.T) {
+	dm := unmanagedMock{docker.NewMockManager()}
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "legacy", Mounts: []common.Mount{{Target: "/data"}}}, nil)
+	dm.StartContainer(id)
+
+	requests := []common.Request{
+		{Type: common.CmdKillEnv, Payload: map[string]interface{}{"id": id}},
+		{Type: common.CmdUpdateEnv, Payload: map[string]interface{}{"id": id, "payload": map[string]interface{}{"name": "legacy"}}},
+		{Type: common.CmdRestoreBackup, Payload: map[string]interface{}{"id": id, "name": "legacy.tar.gz"}},
+		{Type: common.CmdRemoveEnv, Payload: map[string]interface{}{"id": id, "keep_volumes": false}},
+		{Type: common.CmdRemoveEnv, Payload: id},
+	}
+	for _, req := range requests {
+		resp := handleRequest(req, dm, discardEvents)
+		if resp.Success || !strings.Contains(resp.Error, "not managed by PerSSH") {
+			t.Errorf("Expected %s to be refused, got %+v", req.Type, resp)
+		}
+	}
+	if list, _ := dm.ListContainers(); len(list) != 1 || list[0].Status != "running" {
+		t.Fatalf("Container changed by a refused action: %+v", list)
+	}
+
+	resp := handleRequest(common.Request{Type: common.CmdKillEnv, Payload: map[string]interface{}{"id": id, "force": true}}, dm, discardEvents)
+	if !resp.Success {
+		t.Errorf("Forced kill failed: %s", resp.Error)
+	}
+	resp = handleRequest(common.Request{Type: common.CmdRemoveEnv, Payload: map[string]interface{}{"id": id, "force": true}}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Forced remove failed: %s", resp.Error)
+	}
+	if list, _ := dm.ListContainers(); len(list) != 0 {
+		t.Errorf("Expected the container to be removed, got %+v", list)
+	}
+}
+
+func TestImageRequests(t *testing.T) {
+	dm := docker.NewMockManager()
+	dm.CreateContainer(common.CreateEnvPayload{Name: "web", Image: "nginx:1.25"}, nil)
+
+	resp := handleRequest(common.Request{ID: "image", Type: common.CmdPullImage, Payload: map[string]interface{}{"image": "redis"}}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Pull failed: %s", resp.Error)
+	}
+	resp = handleRequest(common.Request{ID: "images", Type: common.CmdListImages}, dm, discardEvents)
+	if images := resp.Data.([]common.ImageInfo); !resp.Success || len(images) != 2 {
+		t.Fatalf("Expected 2 images, got %+v", resp)
+	}
+
+	resp = handleRequest(common.Request{ID: "image", Type: common.CmdRemoveImage, Payload: map[string]interface{}{"image": "nginx:1.25"}}, dm, discardEvents)
+	if resp.Success {
+		t.Error("Expected an error for an image in use")
+	}
+
+	// Prune takes no payload for dangling images
+	resp = handleRequest(common.Request{ID: "prune", Type: common.CmdPruneImages}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Prune failed: %s", resp.Error)
+	}
+	resp = handleRequest(common.Request{ID: "prune", Type: common.CmdPruneImages, Payload: map[string]interface{}{"all": true}}, dm, discardEvents)
+	if res := resp.Data.(common.PruneResult); !resp.Success || res.Deleted != 1 {
+		t.Errorf("Expected redis pruned, got %+v", resp)
+	}
+
+	resp = handleRequest(common.Request{ID: "df", Type: common.CmdDiskUsage}, dm, discardEvents)
+	if du := resp.Data.(common.DiskUsage); !resp.Success || du.Images.Total != 1 || du.Containers.Total != 1 {
+		t.Errorf("Unexpected disk usage: %+v", resp)
+	}
+}
+
+func TestInspectEnv(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "db", Image: "postgres", EnvVars: map[string]string{"POSTGRES_PASSWORD": "hunter2"}}, nil)
+
+	resp := handleRequest(common.Request{ID: "inspect", Type: common.CmdInspectEnv, Payload: id}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Inspect failed: %s", resp.Error)
+	}
+	b, _ := json.Marshal(resp)
+	if strings.Contains(string(b), "hunter2") {
+		t.Errorf("Secret leaked into the response: %s", b)
+	}
+}
+
+func TestGetLogs(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "web"}, nil)
+
+	resp := handleRequest(common.Request{ID: "logs", Type: common.CmdGetLogs, Payload: id}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Get logs failed: %s", resp.Error)
+	}
+	if res := resp.Data.(common.LogsResult); strings.Count(res.Logs, "\n") != 100 || res.Oldest == "" {
+		t.Errorf("Expected the last 100 lines: %+v", res.Oldest)
+	}
+
+	payload := map[string]interface{}{"id": id, "tail": 5, "timestamps": true, "stderr": true}
+	resp = handleRequest(common.Request{ID: "logs", Type: common.CmdGetLogs, Payload: payload}, dm, discardEvents)
+	res := resp.Data.(common.LogsResult)
+	if strings.Count(res.Logs, "\n") != 5 || !strings.HasPrefix(res.Logs, res.Oldest+" Mock stderr") {
+		t.Errorf("Unexpected logs for the query: %q", res.Logs)
+	}
+
+	payload = map[string]interface{}{"id": id, "since": "soon"}
+	if resp := handleRequest(common.Request{ID: "logs", Type: common.CmdGetLogs, Payload: payload}, dm, discardEvents); resp.Success {
+		t.Error("Expected an invalid since to be rejected")
+	}
+}
+
+func TestBackupRequests(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dm := docker.NewMockManager()
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "mc", Mounts: []common.Mount{{Target: "/data"}}}, nil)
+
+	resp := handleRequest(common.Request{ID: "backup", Type: common.CmdBackupEnv, Payload: id}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Backup failed: %s", resp.Error)
+	}
+	name := resp.Data.(common.BackupInfo).Name
+
+	resp = handleRequest(common.Request{ID: "backups", Type: common.CmdListBackups}, dm, discardEvents)
+	if list := resp.Data.(common.BackupList); !resp.Success || len(list.Backups) != 1 || !strings.HasPrefix(list.Dir, os.Getenv("HOME")) {
+		t.Fatalf("Unexpected list: %+v", resp)
+	}
+
+	resp = handleRequest(common.Request{ID: "backup", Type: common.CmdRestoreBackup, Payload: map[string]interface{}{"id": id, "name": name}}, dm, discardEvents)
+	if !resp.Success {
+		t.Errorf("Restore failed: %s", resp.Error)
+	}
+	resp = handleRequest(common.Request{ID: "backup", Type: common.CmdRestoreBackup, Payload: map[string]interface{}{"id": id}}, dm, discardEvents)
+	if resp.Success {
+		t.Error("Expected an error without a backup name")
+	}
+
+	resp = handleRequest(common.Request{ID: "backup", Type: common.CmdDeleteBackup, Payload: name}, dm, discardEvents)
+	if !resp.Success {
+		t.Errorf("Delete failed: %s", resp.Error)
+	}
+}
+
+func TestJobRequests(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "mc"}, nil)
+	dm.StartContainer(id)
+	jobs = scheduler.New(scheduler.NewStore(filepath.Join(t.TempDir(), "jobs.json")), jobRunner(dm))
+	defer func() { jobs = nil }()
+
+	job := common.Job{Name: "greet", Schedule: "@hourly", Action: common.JobExec, Env: "mc", Command: "echo hello"}
+	resp := handleRequest(common.Request{ID: "job", Type: common.CmdSaveJob, Payload: job}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Save failed: %s", resp.Error)
+	}
+	saved := resp.Data.(common.Job)
+	if saved.ID == "" || saved.NextRun == 0 {
+		t.Errorf("Unexpected job %+v", saved)
+	}
+	job.Schedule = "sometimes"
+	if resp := handleRequest(common.Request{ID: "job", Type: common.CmdSaveJob, Payload: job}, dm, discardEvents); resp.Success {
+		t.Error("Expected error for an invalid schedule")
+	}
+
+	resp = handleRequest(common.Request{ID: "job", Type: common.CmdRunJob, Payload: saved.ID}, dm, discardEvents)
+	if run, ok := resp.Data.(common.JobRun); !resp.Success || !ok || !run.Success || run.Output != "Mock exec in mc: sh -c echo hello" {
+		t.Fatalf("Unexpected run: %+v", resp)
+	}
+
+	resp = handleRequest(common.Request{ID: "jobs", Type: common.CmdListJobs}, dm, discardEvents)
+	if list := resp.Data.([]common.Job); !resp.Success || len(list) != 1 || len(list[0].History) != 1 {
+		t.Fatalf("Unexpected list: %+v", resp)
+	}
+
+	resp = handleRequest(common.Request{ID: "job", Type: common.CmdDeleteJob, Payload: saved.ID}, dm, discardEvents)
+	if !resp.Success {
+		t.Errorf("Delete failed: %s", resp.Error)
+	}
+	resp = handleRequest(common.Request{ID: "job", Type: common.CmdRunJob, Payload: saved.ID}, dm, discardEvents)
+	if resp.Success {
+		t.Error("Expected error running a deleted job")
+	}
+}
+
+func TestRunJobActions(t *testing.T) {
+	dm := docker.NewMockManager()
+	id, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "mc"}, nil)
+	dm.StartContainer(id)
+
+	for _, job := range []common.Job{
+		{Action: common.JobRestart, Env: "mc"},
+		{Action: common.JobInput, Env: "mc", Command: "say hi"},
+		{Action: common.JobPruneImages, All: true},
+	} {
+		if _, err := runJob(dm, job); err != nil {
+			t.Errorf("%s: %v", job.Action, err)
+		}
+	}
+	if out, err := runJob(dm, common.Job{Action: common.JobExec, Env: "mc", Command: "exit 3"}); err != nil || out != "Mock exec in mc: sh -c exit 3" {
+		t.Errorf("Unexpected exec result %q, %v", out, err)
+	}
+	if _, err := runJob(dm, common.Job{Action: common.JobRestart, Env: "gone"}); err == nil {
+		t.Error("Expected error for an unknown environment")
+	}
+}
+
+func TestContainerStats(t *testing.T) {
+	dm := docker.NewMockManager()
+	running, _ := dm.CreateContainer(common.CreateEnvPayload{Name: "mc"}, nil)
+	dm.StartContainer(running)
+	dm.CreateContainer(common.CreateEnvPayload{Name: "stopped"}, nil)
+
+	resp := handleRequest(common.Request{ID: "stats", Type: common.CmdContainerStats, Payload: ""}, dm, discardEvents)
+	if !resp.Success {
+		t.Fatalf("Stats failed: %s", resp.Error)
+	}
+	stats, ok := resp.Data.([]common.ContainerStats)
+	if !ok || len(stats) != 1 || stats[0].ID != running || stats[0].MemLimit == 0 {
+		t.Errorf("Expected stats for the running container only, got %+v", resp.Data)
+	}
+}
+
 func TestJSONEncoding(t *testing.T) {
 	// Test that our structures encode/decode as expected for the protocol
 	req := common.Request{
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/docker"
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/utils"
+	"github.com/charmbracelet/x/term"
+)
+
+// runShell bridges this process's terminal (the SSH pty opened by the
+// client) to an interactive shell inside the container.
+func runShell(dm docker.DockerClient, id string) error {
+	fd := os.Stdin.Fd()
+	if term.IsTerminal(fd) {
+		// Keystrokes must reach the container's TTY unprocessed
+		state, err := term.MakeRaw(fd)
+		if err == nil {
+			defer term.Restore(fd, state)
+		}
+	}
+
+	resize := make(chan docker.TermSize, 1)
+	var last docker.TermSize
+	sendSize := func() {
+		w, h, err := term.GetSize(os.Stdout.Fd())
+		if err != nil {
+			return
+		}
+		size := docker.TermSize{Rows: uint(h), Cols: uint(w)}
+		if size == last {
+			return
+		}
+		last = size
+		select {
+		case resize <- size:
+		default:
+		}
+	}
+
+	sendSize()
+	defer close(resize)
+	stop := utils.NotifyResize(sendSize)
+	defer stop()
+
+	return dm.Shell(id, os.Stdin, os.Stdout, resize)
+}
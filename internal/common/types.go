@@ -1,6 +1,10 @@
 package common
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // CommandType defines the type of RPC command.
 type CommandType string
@@ -10,13 +14,62 @@ const (
 	CmdGetTelemetry   CommandType = "GET_TELEMETRY"
 	CmdListContainers CommandType = "LIST_CONTAINERS"
 	CmdCreateEnv      CommandType = "CREATE_ENV"
+	CmdGetEnvConfig   CommandType = "GET_ENV_CONFIG"
+	CmdUpdateEnv      CommandType = "UPDATE_ENV"
+	CmdAdoptEnv       CommandType = "ADOPT_ENV"
 	CmdStartEnv       CommandType = "START_ENV"
 	CmdStopEnv        CommandType = "STOP_ENV"
+	CmdRestartEnv     CommandType = "RESTART_ENV"
+	CmdPauseEnv       CommandType = "PAUSE_ENV"
+	CmdUnpauseEnv     CommandType = "UNPAUSE_ENV"
+	CmdRenameEnv      CommandType = "RENAME_ENV"
+	CmdKillEnv        CommandType = "KILL_ENV"
 	CmdRemoveEnv      CommandType = "REMOVE_ENV"
 	CmdGetLogs        CommandType = "GET_LOGS"
 	CmdSendInput      CommandType = "SEND_INPUT"
+	CmdAttachEnv      CommandType = "ATTACH_ENV"
+	CmdDetachEnv      CommandType = "DETACH_ENV"
+	CmdGetLimits      CommandType = "GET_LIMITS"
+	CmdUpdateLimits   CommandType = "UPDATE_LIMITS"
+	CmdListVolumes    CommandType = "LIST_VOLUMES"
+	CmdPullImage      CommandType = "PULL_IMAGE"
+	CmdListImages     CommandType = "LIST_IMAGES"
+	CmdRemoveImage    CommandType = "REMOVE_IMAGE"
+	CmdPruneImages    CommandType = "PRUNE_IMAGES"
+	CmdDiskUsage      CommandType = "DISK_USAGE"
+	CmdContainerStats CommandType = "CONTAINER_STATS"
+	CmdDeployStack    CommandType = "DEPLOY_STACK"
+	CmdStartStack     CommandType = "START_STACK"
+	CmdStopStack      CommandType = "STOP_STACK"
+	CmdRemoveStack    CommandType = "REMOVE_STACK"
+	CmdListNetworks   CommandType = "LIST_NETWORKS"
+	CmdCreateNetwork  CommandType = "CREATE_NETWORK"
+	CmdRemoveNetwork  CommandType = "REMOVE_NETWORK"
+	CmdGetHealth      CommandType = "GET_HEALTH"
+	CmdExec           CommandType = "EXEC"
+	CmdInspectEnv     CommandType = "INSPECT_ENV"
+	CmdBackupEnv      CommandType = "BACKUP_ENV"
+	CmdListBackups    CommandType = "LIST_BACKUPS"
+	CmdRestoreBackup  CommandType = "RESTORE_BACKUP"
+	CmdDeleteBackup   CommandType = "DELETE_BACKUP"
+	CmdListJobs       CommandType = "LIST_JOBS"
+	CmdSaveJob        CommandType = "SAVE_JOB"
+	CmdDeleteJob      CommandType = "DELETE_JOB"
+	CmdRunJob         CommandType = "RUN_JOB"
 )
 
+// EventConsole is the Response ID the agent uses to push console output
+// while an environment is attached.
+const EventConsole = "console"
+
+// EventAgentError is the Response ID of the only reply of an agent that
+// failed to start, sent before it exits so the client can say why.
+const EventAgentError = "agent_error"
+
+// EventPull is the Response ID of image pull progress pushed while an
+// environment is being created.
+const EventPull = "pull"
+
 // Request is the generic RPC request structure sent from Client to Server.
 type Request struct {
 	ID      string          `json:"id"`
@@ -34,15 +87,32 @@ type Response struct {
 
 // TelemetryData holds system stats.
 type TelemetryData struct {
-	Timestamp     time.Time `json:"timestamp"`
-	CPUUsage      float64   `json:"cpu_usage"`      // Percentage
-	CPUTemp       float64   `json:"cpu_temp"`       // Celsius
-	RAMUsage      float64   `json:"ram_usage"`      // Percentage
-	RAMTotal      uint64    `json:"ram_total"`      // Bytes
-	RAMUsed       uint64    `json:"ram_used"`       // Bytes
-	DiskFree      uint64    `json:"disk_free"`      // Bytes
-	DiskTotal     uint64    `json:"disk_total"`     // Bytes
-	DockerRunning bool      `json:"docker_running"` // Is daemon active?
+	Timestamp     time.Time   `json:"timestamp"`
+	CPUUsage      float64     `json:"cpu_usage"`      // Percentage
+	CPUTemp       float64     `json:"cpu_temp"`       // Celsius
+	RAMUsage      float64     `json:"ram_usage"`      // Percentage
+	RAMTotal      uint64      `json:"ram_total"`      // Bytes
+	RAMUsed       uint64      `json:"ram_used"`       // Bytes
+	DiskFree      uint64      `json:"disk_free"`      // Bytes
+	DiskTotal     uint64      `json:"disk_total"`     // Bytes
+	DockerRunning bool        `json:"docker_running"` // Is daemon active?
+	Backend       BackendInfo `json:"backend"`        // Engine in use, Docker or not
+}
+
+// Container engines the agent can drive.
+const (
+	BackendDocker = "docker"
+	BackendPodman = "podman" // Through its Docker-compatible API
+	BackendMock   = "mock"   // In-memory stand-in, for development
+)
+
+// BackendInfo describes the container engine behind the agent.
+type BackendInfo struct {
+	Name     string `json:"name"`              // BackendDocker, BackendPodman or BackendMock
+	Host     string `json:"host,omitempty"`    // API address, e.g. unix:///run/podman/podman.sock
+	Version  string `json:"version,omitempty"` // Engine version
+	Rootless bool   `json:"rootless,omitempty"`
+	Fallback string `json:"fallback,omitempty"` // Why the mock stands in; empty if it was asked for
 }
 
 // EnvironmentType defines the template used.
@@ -51,6 +121,7 @@ type EnvironmentType string
 const (
 	EnvTypeStandard  EnvironmentType = "STANDARD"
 	EnvTypeMinecraft EnvironmentType = "MINECRAFT"
+	EnvTypeCompose   EnvironmentType = "COMPOSE"
 )
 
 // MinecraftConfig holds specific settings for Minecraft environments.
@@ -72,23 +143,488 @@ const (
 
 // CreateEnvPayload defines parameters for creating a new environment.
 type CreateEnvPayload struct {
-	Name        string            `json:"name"`
-	Type        EnvironmentType   `json:"type"`
-	Image       string            `json:"image"` // For Standard
-	Ports       []string          `json:"ports"` // "8080:80"
-	EnvVars     map[string]string `json:"env_vars"`
-	RamLimit    string            `json:"ram_limit,omitempty"` // e.g., "2g"
-	
+	Name      string              `json:"name"`
+	Type      EnvironmentType     `json:"type"`
+	Image     string              `json:"image"` // For Standard
+	Ports     []string            `json:"ports"` // "8080:80"
+	EnvVars   map[string]string   `json:"env_vars"`
+	RamLimit  string              `json:"ram_limit,omitempty"` // e.g., "2g"; Resources.Memory takes precedence
+	Resources Resources           `json:"resources,omitempty"`
+	Mounts    []Mount             `json:"mounts,omitempty"`
+	Networks  []NetworkAttachment `json:"networks,omitempty"`    // Empty means the default bridge
+	Health    *Healthcheck        `json:"healthcheck,omitempty"` // Nil keeps the image's own check
+	Auth      *RegistryAuth       `json:"auth,omitempty"`        // Credentials for pulling Image
+
 	// Configuration
 	Minecraft MinecraftConfig `json:"minecraft,omitempty"`
 }
 
+// UpdateEnvPayload recreates an environment from an edited payload, as
+// returned by GET_ENV_CONFIG. The name and volumes are kept. Containers
+// not managed by PerSSH are refused unless Force is set.
+type UpdateEnvPayload struct {
+	ID      string           `json:"id"`
+	Payload CreateEnvPayload `json:"payload"`
+	Force   bool             `json:"force,omitempty"`
+}
+
+// Resources caps what an environment may use. Sizes use docker notation
+// ("512m", "2g"); zero values mean unlimited at creation and unchanged in
+// an update.
+type Resources struct {
+	Memory      string  `json:"memory,omitempty"`       // Hard memory limit
+	MemorySwap  string  `json:"memory_swap,omitempty"`  // Memory plus swap, "-1" for unlimited swap
+	CPUs        float64 `json:"cpus,omitempty"`         // CPU quota in cores, e.g. 1.5
+	CpusetCPUs  string  `json:"cpuset_cpus,omitempty"`  // Pinned CPUs, e.g. "0-3" or "0,2"
+	PidsLimit   int64   `json:"pids_limit,omitempty"`   // Max processes, -1 for unlimited
+	StorageSize string  `json:"storage_size,omitempty"` // Writable layer size; creation only, needs overlay2 on xfs with pquota
+}
+
+// Mount attaches storage to an environment. An absolute Source is a bind
+// mount of a host path, anything else names a volume. An empty Source gets
+// a volume named after the environment, see DefaultVolumeName.
+type Mount struct {
+	Source   string `json:"source,omitempty"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only,omitempty"`
+}
+
+// IsBind reports whether the mount is a host path rather than a volume.
+func (m Mount) IsBind() bool {
+	return strings.HasPrefix(m.Source, "/")
+}
+
+// DefaultVolumeName names the volume backing target for an environment,
+// e.g. "perssh-survival-data" for "/data".
+func DefaultVolumeName(env, target string) string {
+	base := strings.Trim(strings.ReplaceAll(target, "/", "-"), "-")
+	if base == "" {
+		base = "root"
+	}
+	return "perssh-" + env + "-" + base
+}
+
+// Healthcheck probes an environment from inside the container. A single
+// Test element runs through the shell, like HEALTHCHECK CMD in a
+// Dockerfile; ["NONE"] disables the image's check. Durations use Go
+// notation ("30s", "2m") and empty ones keep Docker's defaults.
+type Healthcheck struct {
+	Test        []string `json:"test"`
+	Interval    string   `json:"interval,omitempty"`     // Between probes
+	Timeout     string   `json:"timeout,omitempty"`      // Per probe
+	Retries     int      `json:"retries,omitempty"`      // Failures in a row before unhealthy
+	StartPeriod string   `json:"start_period,omitempty"` // Failures during startup do not count
+}
+
+// HealthInfo is the health state of an environment with the result of
+// its latest probe.
+type HealthInfo struct {
+	Status        string `json:"status"` // "starting", "healthy", "unhealthy" or "" without a check
+	FailingStreak int    `json:"failing_streak"`
+	LastOutput    string `json:"last_output,omitempty"`
+	LastExitCode  int    `json:"last_exit_code"`
+	LastCheck     int64  `json:"last_check,omitempty"` // Unix time the latest probe ended
+}
+
+// NetworkAttachment joins an environment to a user-defined network.
+// Containers on the same network reach it by its name and by Aliases.
+type NetworkAttachment struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// CreateNetworkPayload creates a user-defined network. Internal networks
+// have no route to the outside, only between their containers.
+type CreateNetworkPayload struct {
+	Name     string `json:"name"`
+	Driver   string `json:"driver,omitempty"` // Defaults to "bridge"
+	Subnet   string `json:"subnet,omitempty"` // CIDR, picked by Docker when empty
+	Internal bool   `json:"internal,omitempty"`
+}
+
+// NetworkInfo describes a network on the host.
+type NetworkInfo struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Driver   string   `json:"driver"`
+	Subnet   string   `json:"subnet,omitempty"`
+	Internal bool     `json:"internal,omitempty"`
+	Builtin  bool     `json:"builtin,omitempty"` // bridge, host and none; cannot be removed
+	Managed  bool     `json:"managed"`
+	UsedBy   []string `json:"used_by,omitempty"` // Container names
+}
+
+// AdoptEnvPayload turns a container not created by PerSSH into a managed
+// environment of the given type.
+type AdoptEnvPayload struct {
+	ID   string          `json:"id"`
+	Type EnvironmentType `json:"type"`
+}
+
+// RemoveEnvPayload removes an environment. Volumes used only by it are
+// deleted unless KeepVolumes is set. Containers not managed by PerSSH are
+// refused unless Force is set.
+type RemoveEnvPayload struct {
+	ID          string `json:"id"`
+	KeepVolumes bool   `json:"keep_volumes"`
+	Force       bool   `json:"force,omitempty"`
+}
+
+// RestartEnvPayload restarts an environment. Timeout is the number of
+// seconds to wait before killing it; 0 uses the container's default.
+type RestartEnvPayload struct {
+	ID      string `json:"id"`
+	Timeout int    `json:"timeout,omitempty"`
+}
+
+// RenameEnvPayload gives an environment a new container name.
+type RenameEnvPayload struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// KillEnvPayload sends a signal ("SIGHUP", "HUP" or "1") to the main
+// process. An empty signal means SIGKILL. Containers not managed by PerSSH
+// are refused unless Force is set.
+type KillEnvPayload struct {
+	ID     string `json:"id"`
+	Signal string `json:"signal,omitempty"`
+	Force  bool   `json:"force,omitempty"`
+}
+
+// DeployStackPayload creates or updates a compose stack. Deploying an
+// existing stack recreates only the services whose definition or image
+// changed and removes services no longer in the file.
+type DeployStackPayload struct {
+	Name    string         `json:"name"`
+	Compose string         `json:"compose"`        // Compose YAML
+	Auth    []RegistryAuth `json:"auth,omitempty"` // Logins, picked per image by registry
+}
+
+// RemoveStackPayload removes all containers and networks of a stack.
+// Volumes are deleted unless KeepVolumes is set.
+type RemoveStackPayload struct {
+	Name        string `json:"name"`
+	KeepVolumes bool   `json:"keep_volumes"`
+}
+
+// LogsQuery selects the logs GET_LOGS returns; a bare ID asks for the
+// last 100 lines of both streams. Since and Until take an RFC 3339 time,
+// a Unix timestamp or a duration back from now like "10m", and include
+// lines logged exactly then.
+type LogsQuery struct {
+	ID         string `json:"id"`
+	Tail       int    `json:"tail,omitempty"` // Newest lines to return; 0 uses 100
+	Since      string `json:"since,omitempty"`
+	Until      string `json:"until,omitempty"`
+	Timestamps bool   `json:"timestamps,omitempty"` // Prefix lines with their RFC 3339 time
+	Stdout     bool   `json:"stdout,omitempty"`
+	Stderr     bool   `json:"stderr,omitempty"` // Neither set selects both
+}
+
+// LogsResult is the reply to GET_LOGS.
+type LogsResult struct {
+	Logs    string `json:"logs"`
+	Oldest  string `json:"oldest,omitempty"`   // Time of the first line, to page back with Until
+	AtStart bool   `json:"at_start,omitempty"` // Nothing older matches the query
+}
+
+// ExecPayload runs a one-off command in a running environment. Cmd is
+// run directly; use ["sh", "-c", "..."] for pipes and globs.
+type ExecPayload struct {
+	ID      string   `json:"id"`
+	Cmd     []string `json:"cmd"`
+	User    string   `json:"user,omitempty"`    // "name", "uid" or "uid:gid"; the image's user when empty
+	WorkDir string   `json:"workdir,omitempty"` // Absolute path in the container
+	Env     []string `json:"env,omitempty"`     // "KEY=value"
+	Timeout int      `json:"timeout,omitempty"` // Seconds; 0 uses the agent default
+}
+
+// ExecResult is the outcome of an EXEC request. Each stream is capped;
+// Truncated reports that output was dropped.
+type ExecResult struct {
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	ExitCode  int    `json:"exit_code"` // -1 when the command timed out
+	TimedOut  bool   `json:"timed_out,omitempty"`
+	KillError string `json:"kill_error,omitempty"` // Set if a timed-out command could not be stopped
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// MaskedValue replaces the values of secret variables in EnvInspect.
+const MaskedValue = "********"
+
+// EnvInspect is the full configuration and state of an environment.
+// Variables whose name looks like a secret have their value masked and the
+// stored payload label is left out, since it holds the same variables.
+type EnvInspect struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	Image         string            `json:"image"`                  // As requested, e.g. "nginx:latest"
+	ImageID       string            `json:"image_id"`               // Local image ID
+	ImageDigest   string            `json:"image_digest,omitempty"` // Registry digest, empty for local builds
+	Created       int64             `json:"created"`
+	Entrypoint    []string          `json:"entrypoint,omitempty"`
+	Command       []string          `json:"command,omitempty"`
+	WorkDir       string            `json:"workdir,omitempty"`
+	User          string            `json:"user,omitempty"`
+	Env           []string          `json:"env,omitempty"` // "KEY=value", sorted
+	Ports         []PortBinding     `json:"ports,omitempty"`
+	Mounts        []Mount           `json:"mounts,omitempty"`
+	Networks      []NetworkEndpoint `json:"networks,omitempty"`
+	RestartPolicy string            `json:"restart_policy,omitempty"` // e.g. "unless-stopped", "on-failure:3"
+	Resources     Resources         `json:"resources"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Status        string            `json:"status"`
+	StartedAt     int64             `json:"started_at,omitempty"`  // Unix time, 0 if never started
+	FinishedAt    int64             `json:"finished_at,omitempty"` // Unix time of the last stop
+	RestartCount  int               `json:"restart_count"`
+	ExitCode      int               `json:"exit_code"` // Of the last run
+}
+
+// NetworkEndpoint is an environment's address on one network.
+type NetworkEndpoint struct {
+	Name    string   `json:"name"`
+	IP      string   `json:"ip,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// VolumeInfo describes a volume on the host.
+type VolumeInfo struct {
+	Name    string   `json:"name"`
+	Driver  string   `json:"driver"`
+	Size    int64    `json:"size"` // Bytes, -1 if the driver cannot tell
+	Managed bool     `json:"managed"`
+	UsedBy  []string `json:"used_by,omitempty"` // Container names
+}
+
+// UpdateLimitsPayload changes the limits of an existing environment.
+type UpdateLimitsPayload struct {
+	ID        string    `json:"id"`
+	Resources Resources `json:"resources"`
+}
+
 // ContainerInfo describes a running environment.
 type ContainerInfo struct {
-	ID      string            `json:"id"`
-	Name    string            `json:"name"`
-	Image   string            `json:"image"`
-	Status  string            `json:"status"` // "running", "exited", etc.
-	Created int64             `json:"created"`
-	Labels  map[string]string `json:"labels"`
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Image    string            `json:"image"`
+	Status   string            `json:"status"` // "running", "exited", etc.
+	Created  int64             `json:"created"`
+	Labels   map[string]string `json:"labels"`
+	Ports    []PortBinding     `json:"ports,omitempty"`
+	Mounts   []Mount           `json:"mounts,omitempty"`
+	Stack    string            `json:"stack,omitempty"`   // Compose stack the container belongs to
+	Service  string            `json:"service,omitempty"` // Service name within the stack
+	Networks []string          `json:"networks,omitempty"`
+	Health   string            `json:"health,omitempty"` // See HealthInfo.Status
+}
+
+// Managed reports whether the container was created or adopted by PerSSH.
+func (c ContainerInfo) Managed() bool {
+	return c.Labels["perssh.managed"] == "true"
+}
+
+// ContainerStats is a resource usage sample of one container.
+type ContainerStats struct {
+	ID         string  `json:"id"`
+	CPUPercent float64 `json:"cpu_percent"` // 100 = one full core, like docker stats
+	MemUsage   uint64  `json:"mem_usage"`   // Bytes, excluding page cache
+	MemLimit   uint64  `json:"mem_limit"`   // Bytes; the host total when unlimited
+	NetRx      uint64  `json:"net_rx"`      // Bytes since start, all interfaces
+	NetTx      uint64  `json:"net_tx"`
+	BlockRead  uint64  `json:"block_read"` // Bytes since start
+	BlockWrite uint64  `json:"block_write"`
+	PIDs       uint64  `json:"pids"`
+}
+
+// PortBinding is a container port and where it is published on the host.
+type PortBinding struct {
+	HostIP        string `json:"host_ip,omitempty"`
+	HostPort      uint16 `json:"host_port,omitempty"` // 0 if not published
+	ContainerPort uint16 `json:"container_port"`
+	Protocol      string `json:"protocol"` // "tcp", "udp"
+}
+
+// RegistryAuth holds credentials for one registry. It travels with create
+// and pull requests only; the agent never stores it.
+type RegistryAuth struct {
+	ServerAddress string `json:"server_address"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+}
+
+// String keeps the password out of logs and error messages.
+func (a RegistryAuth) String() string {
+	return fmt.Sprintf("%s@%s (password redacted)", a.Username, a.ServerAddress)
+}
+
+// GoString covers %#v as well.
+func (a RegistryAuth) GoString() string { return a.String() }
+
+// PullImagePayload pulls an image without creating an environment.
+type PullImagePayload struct {
+	Image string        `json:"image"`
+	Auth  *RegistryAuth `json:"auth,omitempty"`
+}
+
+// ImageInfo describes an image on the host.
+type ImageInfo struct {
+	ID       string   `json:"id"`             // Short ID without "sha256:"
+	Tags     []string `json:"tags,omitempty"` // Repository tags; empty for dangling images
+	Size     int64    `json:"size"`           // Bytes, including layers shared with other images
+	Created  int64    `json:"created"`        // Unix time the image was built
+	Dangling bool     `json:"dangling,omitempty"`
+	UsedBy   []string `json:"used_by,omitempty"` // Container names, running or not
+}
+
+// RemoveImagePayload removes an image by ID or tag. Removing a tag of an
+// image with several tags only untags it. Force also removes images used
+// by stopped containers.
+type RemoveImagePayload struct {
+	Image string `json:"image"`
+	Force bool   `json:"force,omitempty"`
+}
+
+// PruneImagesPayload removes dangling images, or with All every image no
+// container uses.
+type PruneImagesPayload struct {
+	All bool `json:"all,omitempty"`
+}
+
+// PruneResult reports what a prune removed.
+type PruneResult struct {
+	Deleted   int    `json:"deleted"`   // Images removed
+	Reclaimed uint64 `json:"reclaimed"` // Bytes
+}
+
+// DiskUsage summarizes the space Docker uses, like docker system df.
+type DiskUsage struct {
+	Images     UsageEntry `json:"images"`
+	Containers UsageEntry `json:"containers"` // Writable layers
+	Volumes    UsageEntry `json:"volumes"`
+	BuildCache UsageEntry `json:"build_cache"`
+}
+
+// UsageEntry is one row of DiskUsage. Reclaimable is what removing the
+// inactive objects would free.
+type UsageEntry struct {
+	Total       int   `json:"total"`
+	Active      int   `json:"active"`
+	Size        int64 `json:"size"`
+	Reclaimable int64 `json:"reclaimable"`
+}
+
+// BackupEnvPayload backs up the writable volumes and binds of an
+// environment. Minecraft servers flush the world first; Stop stops any
+// environment for the duration instead, for apps that cannot be copied
+// while they write.
+type BackupEnvPayload struct {
+	ID   string `json:"id"`
+	Stop bool   `json:"stop,omitempty"`
+}
+
+// BackupInfo describes a backup tarball in the agent's backup directory.
+type BackupInfo struct {
+	Name    string   `json:"name"`              // File name, unique in the directory
+	Env     string   `json:"env,omitempty"`     // Environment backed up; empty if unreadable
+	Size    int64    `json:"size"`              // Bytes, compressed
+	Created int64    `json:"created"`           // Unix time
+	Targets []string `json:"targets,omitempty"` // Mount targets it holds
+}
+
+// BackupList is the reply to LIST_BACKUPS. Dir is the absolute path of
+// the backup directory on the host, for transfers over SFTP.
+type BackupList struct {
+	Dir     string       `json:"dir"`
+	Backups []BackupInfo `json:"backups"`
+}
+
+// RestoreBackupPayload restores a backup into an environment, which need
+// not be the one it was taken from but must mount the same targets.
+// Containers not managed by PerSSH are refused unless Force is set.
+type RestoreBackupPayload struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Force bool   `json:"force,omitempty"`
+}
+
+// JobAction is what a scheduled job does.
+type JobAction string
+
+const (
+	JobRestart     JobAction = "restart"
+	JobBackup      JobAction = "backup"
+	JobExec        JobAction = "exec"         // Command through sh -c
+	JobInput       JobAction = "input"        // Command as a console line
+	JobPruneImages JobAction = "prune_images" // Host-wide; no environment
+)
+
+// Job is an action run on a cron schedule by the agent. Jobs are stored on
+// the host, so they run without a client connected.
+type Job struct {
+	ID         string    `json:"id"` // Assigned by the agent on the first save
+	Name       string    `json:"name"`
+	Schedule   string    `json:"schedule"` // Cron expression in the host's time zone
+	Action     JobAction `json:"action"`
+	Env        string    `json:"env,omitempty"`     // Environment name; IDs change on updates
+	Command    string    `json:"command,omitempty"` // For exec and input
+	All        bool      `json:"all,omitempty"`     // prune_images: every unused image, not only dangling ones
+	Disabled   bool      `json:"disabled,omitempty"`
+	SkipMissed bool      `json:"skip_missed,omitempty"` // Don't catch up on runs missed while no agent ran
+	NextRun    int64     `json:"next_run,omitempty"`    // Unix time; set by the agent
+	History    []JobRun  `json:"history,omitempty"`     // Newest first; set by the agent
+}
+
+// Triggers of a JobRun.
+const (
+	TriggerSchedule = "schedule"
+	TriggerCatchUp  = "catch-up" // Missed while no agent ran
+	TriggerManual   = "manual"
+)
+
+// JobRun is the result of one run of a job.
+type JobRun struct {
+	Start    int64  `json:"start"`    // Unix time
+	Duration int64  `json:"duration"` // Milliseconds
+	Trigger  string `json:"trigger"`
+	Success  bool   `json:"success"`
+	Output   string `json:"output,omitempty"` // Result or error, shortened
+}
+
+// DefaultRegistry is the registry of image references without a host.
+const DefaultRegistry = "docker.io"
+
+// RegistryHost returns the registry an image reference is pulled from,
+// following docker's rule that the first path component is a host only if
+// it contains a "." or ":" or is "localhost".
+func RegistryHost(image string) string {
+	first, _, found := strings.Cut(image, "/")
+	if !found {
+		return DefaultRegistry
+	}
+	if strings.ContainsAny(first, ".:") || first == "localhost" {
+		return first
+	}
+	return DefaultRegistry
+}
+
+// PullProgress reports one step of an image pull. Layer is empty for
+// messages about the image as a whole.
+type PullProgress struct {
+	Image   string `json:"image"`
+	Layer   string `json:"layer,omitempty"`
+	Status  string `json:"status"` // "Downloading", "Extracting", "Pull complete", ...
+	Current int64  `json:"current,omitempty"` // Bytes done in this step
+	Total   int64  `json:"total,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Done    bool   `json:"done,omitempty"` // Last event of the pull
+}
+
+// ConsoleOutput is a chunk of output from an attached environment.
+type ConsoleOutput struct {
+	ID     string `json:"id"`               // Container ID
+	Stream string `json:"stream,omitempty"` // "stdout", "stderr"
+	Data   string `json:"data,omitempty"`
+	Closed bool   `json:"closed,omitempty"` // The attach ended (e.g. container stopped)
 }
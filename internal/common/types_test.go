@@ -0,0 +1,41 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRegistryHost(t *testing.T) {
+	cases := map[string]string{
+		"nginx":                           DefaultRegistry,
+		"itzg/minecraft-server":           DefaultRegistry,
+		"ghcr.io/org/app:1.2":             "ghcr.io",
+		"registry.local:5000/team/app":    "registry.local:5000",
+		"localhost/app":                   "localhost",
+		"docker.io/library/ubuntu:latest": "docker.io",
+	}
+	for image, want := range cases {
+		if got := RegistryHost(image); got != want {
+			t.Errorf("RegistryHost(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestRegistryAuthRedacted(t *testing.T) {
+	auth := &RegistryAuth{ServerAddress: "ghcr.io", Username: "ci", Password: "s3cret"}
+	payload := CreateEnvPayload{Name: "app", Image: "ghcr.io/org/app", Auth: auth}
+
+	for _, s := range []string{fmt.Sprint(auth), fmt.Sprintf("%v", *auth), fmt.Sprintf("%#v", *auth), fmt.Sprintf("%+v", payload)} {
+		if strings.Contains(s, "s3cret") {
+			t.Errorf("Password leaked in %q", s)
+		}
+	}
+
+	// The wire format must still carry it
+	b, _ := json.Marshal(payload)
+	if !strings.Contains(string(b), "s3cret") {
+		t.Error("Password missing from JSON payload")
+	}
+}
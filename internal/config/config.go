@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/ini.v1"
 )
@@ -14,6 +15,14 @@ type ClientConfig struct {
 	Theme   ThemeConfig   `ini:"Theme"`
 	Network NetworkConfig `ini:"Network"`
 	Session SessionConfig `ini:"Session"`
+	Docker  DockerConfig  `ini:"Docker"`
+
+	// Hosts holds per-host settings, stored as [Host <address>] sections.
+	Hosts map[string]*HostConfig `ini:"-"`
+
+	// Registries holds container registry logins, stored as
+	// [Registry <host>] sections. Passwords live in the OS keyring.
+	Registries map[string]*RegistryConfig `ini:"-"`
 }
 
 type GeneralConfig struct {
@@ -28,10 +37,60 @@ type NetworkConfig struct {
 	Timeout int `ini:"Timeout"` // Seconds
 }
 
+type DockerConfig struct {
+	StopTimeout int `ini:"StopTimeout"` // Seconds before a restart kills; 0 uses the container default
+}
+
 type SessionConfig struct {
 	LastHost string `ini:"LastHost"`
 	LastUser string `ini:"LastUser"`
 	LastPort int    `ini:"LastPort"`
+	LastJump string `ini:"LastJump"` // ProxyJump list, e.g. "admin@bastion:22"
+}
+
+// HostConfig holds settings remembered for a single SSH host.
+type HostConfig struct {
+	// Forwards are tunnel specs, e.g. "L 127.0.0.1:25565 localhost:25565 mc".
+	Forwards []string `ini:"Forwards" delim:";"`
+	// Backend picks the agent's container engine: docker, podman or mock.
+	// Detected when empty.
+	Backend string `ini:"Backend,omitempty"`
+}
+
+// RegistryConfig holds the login for a container registry.
+type RegistryConfig struct {
+	Username string `ini:"Username"`
+}
+
+const (
+	hostSectionPrefix     = "Host "
+	registrySectionPrefix = "Registry "
+)
+
+// Host returns the settings for addr, creating an empty entry if needed.
+func (c *ClientConfig) Host(addr string) *HostConfig {
+	if c.Hosts == nil {
+		c.Hosts = make(map[string]*HostConfig)
+	}
+	h, ok := c.Hosts[addr]
+	if !ok {
+		h = &HostConfig{}
+		c.Hosts[addr] = h
+	}
+	return h
+}
+
+// Registry returns the login for host, creating an empty entry if needed.
+func (c *ClientConfig) Registry(host string) *RegistryConfig {
+	if c.Registries == nil {
+		c.Registries = make(map[string]*RegistryConfig)
+	}
+	r, ok := c.Registries[host]
+	if !ok {
+		r = &RegistryConfig{}
+		c.Registries[host] = r
+	}
+	return r
 }
 
 // DefaultClientConfig returns standard defaults.
@@ -49,6 +108,9 @@ func DefaultClientConfig() *ClientConfig {
 		Session: SessionConfig{
 			LastPort: 22,
 		},
+		Docker: DockerConfig{
+			StopTimeout: 10,
+		},
 	}
 }
 
@@ -69,11 +131,33 @@ func LoadClientConfig() (*ClientConfig, error) {
 		return cfg, nil
 	}
 
-	cfg := new(ClientConfig)
-	err = ini.MapTo(cfg, configPath)
+	return loadClientConfig(configPath)
+}
+
+func loadClientConfig(configPath string) (*ClientConfig, error) {
+	iniFile, err := ini.Load(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse client.ini: %w", err)
 	}
+	cfg := new(ClientConfig)
+	if err := iniFile.MapTo(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse client.ini: %w", err)
+	}
+
+	for _, sec := range iniFile.Sections() {
+		var target interface{}
+		switch {
+		case strings.HasPrefix(sec.Name(), hostSectionPrefix):
+			target = cfg.Host(strings.TrimPrefix(sec.Name(), hostSectionPrefix))
+		case strings.HasPrefix(sec.Name(), registrySectionPrefix):
+			target = cfg.Registry(strings.TrimPrefix(sec.Name(), registrySectionPrefix))
+		default:
+			continue
+		}
+		if err := sec.MapTo(target); err != nil {
+			return nil, fmt.Errorf("failed to parse section %q: %w", sec.Name(), err)
+		}
+	}
 	return cfg, nil
 }
 
@@ -83,12 +167,32 @@ func SaveClientConfig(cfg *ClientConfig) error {
 	if err != nil {
 		return err
 	}
-	configPath := filepath.Join(filepath.Dir(exePath), "client.ini")
+	return saveClientConfig(cfg, filepath.Join(filepath.Dir(exePath), "client.ini"))
+}
 
+func saveClientConfig(cfg *ClientConfig, configPath string) error {
 	iniFile := ini.Empty()
-	err = ini.ReflectFrom(iniFile, cfg)
+	err := ini.ReflectFrom(iniFile, cfg)
 	if err != nil {
 		return err
 	}
+	for addr, h := range cfg.Hosts {
+		sec, err := iniFile.NewSection(hostSectionPrefix + addr)
+		if err != nil {
+			return err
+		}
+		if err := sec.ReflectFrom(h); err != nil {
+			return err
+		}
+	}
+	for host, r := range cfg.Registries {
+		sec, err := iniFile.NewSection(registrySectionPrefix + host)
+		if err != nil {
+			return err
+		}
+		if err := sec.ReflectFrom(r); err != nil {
+			return err
+		}
+	}
 	return iniFile.SaveTo(configPath)
 }
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHostSectionsRoundtrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.ini")
+
+	cfg := DefaultClientConfig()
+	cfg.Session.LastHost = "10.0.0.5"
+	cfg.Host("10.0.0.5").Forwards = []string{
+		"L 127.0.0.1:25565 localhost:25565 mc",
+		"D 127.0.0.1:1080 -",
+	}
+	cfg.Host("10.0.0.5").Backend = "podman"
+
+	if err := saveClientConfig(cfg, path); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	loaded, err := loadClientConfig(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	if loaded.Session.LastHost != "10.0.0.5" {
+		t.Errorf("LastHost not restored: %q", loaded.Session.LastHost)
+	}
+	if !reflect.DeepEqual(loaded.Host("10.0.0.5").Forwards, cfg.Host("10.0.0.5").Forwards) {
+		t.Errorf("Forwards mismatch: %#v", loaded.Host("10.0.0.5").Forwards)
+	}
+	if loaded.Host("10.0.0.5").Backend != "podman" {
+		t.Errorf("Backend not restored: %q", loaded.Host("10.0.0.5").Backend)
+	}
+	if len(loaded.Host("other").Forwards) != 0 {
+		t.Error("Unknown host should have no forwards")
+	}
+}
+
+func TestRegistrySectionsRoundtrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.ini")
+
+	cfg := DefaultClientConfig()
+	cfg.Registry("registry.local:5000").Username = "ci"
+	cfg.Host("10.0.0.5").Forwards = []string{"D 127.0.0.1:1080 -"}
+
+	if err := saveClientConfig(cfg, path); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	loaded, err := loadClientConfig(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	if len(loaded.Registries) != 1 || loaded.Registry("registry.local:5000").Username != "ci" {
+		t.Errorf("Registries not restored: %+v", loaded.Registries)
+	}
+	if len(loaded.Hosts) != 1 {
+		t.Errorf("Registry section leaked into hosts: %+v", loaded.Hosts)
+	}
+}
@@ -0,0 +1,210 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// checkAdopt fails for containers PerSSH should not take over and for
+// types that cannot describe a single container.
+func checkAdopt(labels map[string]string, t common.EnvironmentType) error {
+	switch t {
+	case common.EnvTypeStandard, common.EnvTypeMinecraft:
+	case common.EnvTypeCompose:
+		return fmt.Errorf("containers cannot be adopted into a compose stack")
+	default:
+		return fmt.Errorf("unknown environment type %q", t)
+	}
+	if labels["perssh.managed"] == "true" {
+		return fmt.Errorf("container is already managed")
+	}
+	return nil
+}
+
+// adoptMounts turns the mounts of a container into explicit mounts, so
+// anonymous volumes are reused by name instead of being recreated empty.
+// tmpfs mounts given as HostConfig.Tmpfs are carried over by that field.
+func adoptMounts(points []container.MountPoint, tmpfs map[string]string) []mount.Mount {
+	var res []mount.Mount
+	for _, p := range points {
+		mt := mount.Mount{Type: p.Type, Target: p.Destination, ReadOnly: !p.RW}
+		switch p.Type {
+		case mount.TypeVolume:
+			mt.Source = p.Name
+		case mount.TypeBind:
+			mt.Source = p.Source
+		case mount.TypeTmpfs:
+			if _, ok := tmpfs[p.Destination]; ok {
+				continue
+			}
+		default:
+			continue
+		}
+		res = append(res, mt)
+	}
+	return res
+}
+
+// adoptEndpoints copies the network endpoints of a container. The first
+// is the one of its network mode, set at creation; the others are
+// connected afterwards. Containers sharing the host's or another
+// container's network stack have none.
+func adoptEndpoints(c container.InspectResponse) (first string, endpoints map[string]*network.EndpointSettings) {
+	mode := c.HostConfig.NetworkMode
+	if mode.IsHost() || mode.IsNone() || mode.IsContainer() || c.NetworkSettings == nil {
+		return "", nil
+	}
+	short := c.ID
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	endpoints = make(map[string]*network.EndpointSettings)
+	for name, ep := range c.NetworkSettings.Networks {
+		copied := &network.EndpointSettings{}
+		if ep != nil {
+			copied.IPAMConfig = ep.IPAMConfig
+			copied.Links = ep.Links
+			copied.DriverOpts = ep.DriverOpts
+			for _, a := range ep.Aliases {
+				// Docker adds the short ID itself
+				if a != short {
+					copied.Aliases = append(copied.Aliases, a)
+				}
+			}
+		}
+		endpoints[name] = copied
+	}
+	first = mode.NetworkName()
+	if mode.IsDefault() {
+		first = network.NetworkBridge
+	}
+	if _, ok := endpoints[first]; !ok {
+		first = ""
+	}
+	return first, endpoints
+}
+
+// adoptConfig copies the configuration of c with the PerSSH labels added.
+// The image is pinned by ID unless the reference still points to it, so
+// adopting never upgrades.
+func adoptConfig(c container.InspectResponse, t common.EnvironmentType, refID string) (*container.Config, *container.HostConfig) {
+	config := *c.Config
+	config.Labels = make(map[string]string, len(c.Config.Labels)+2)
+	for k, v := range c.Config.Labels {
+		config.Labels[k] = v
+	}
+	config.Labels["perssh.managed"] = "true"
+	config.Labels["perssh.type"] = string(t)
+	if refID != c.Image {
+		config.Image = c.Image
+	}
+	// Docker defaults the hostname to the short ID; let it pick the new one
+	if len(c.ID) >= 12 && config.Hostname == c.ID[:12] {
+		config.Hostname = ""
+	}
+
+	hostConfig := *c.HostConfig
+	hostConfig.Binds = nil
+	hostConfig.Mounts = adoptMounts(c.Mounts, hostConfig.Tmpfs)
+	return &config, &hostConfig
+}
+
+// AdoptContainer makes an existing container a managed environment of
+// type t. Labels cannot be changed in place, so the container is
+// recreated from its own configuration under the same name, keeping its
+// volumes; it is restored if the copy fails to come up.
+func (m *RealManager) AdoptContainer(id string, t common.EnvironmentType) (string, error) {
+	ctx := context.Background()
+	old, err := m.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if old.Config == nil || old.HostConfig == nil {
+		return "", fmt.Errorf("incomplete inspect data for %s", id)
+	}
+	if err := checkAdopt(old.Config.Labels, t); err != nil {
+		return "", err
+	}
+	if old.HostConfig.AutoRemove {
+		return "", fmt.Errorf("container was started with --rm and would be removed when stopped")
+	}
+	name := strings.TrimPrefix(old.Name, "/")
+
+	refID := ""
+	if img, err := m.cli.ImageInspect(ctx, old.Config.Image); err == nil {
+		refID = img.ID
+	}
+	config, hostConfig := adoptConfig(old, t, refID)
+	first, endpoints := adoptEndpoints(old)
+	var netConfig *network.NetworkingConfig
+	if first != "" {
+		netConfig = &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{first: endpoints[first]}}
+	}
+
+	wasRunning := old.State != nil && (old.State.Running || old.State.Restarting)
+	if wasRunning {
+		if err := m.cli.ContainerStop(ctx, old.ID, container.StopOptions{}); err != nil {
+			return "", err
+		}
+	}
+	backup := fmt.Sprintf("%s-old-%d", name, time.Now().Unix())
+	if err := m.cli.ContainerRename(ctx, old.ID, backup); err != nil {
+		return "", m.rollback(ctx, old.ID, "", "", wasRunning, err)
+	}
+
+	resp, err := m.cli.ContainerCreate(ctx, config, hostConfig, netConfig, &v1.Platform{}, name)
+	if err != nil {
+		return "", m.rollback(ctx, old.ID, "", name, wasRunning, err)
+	}
+	for netName, ep := range endpoints {
+		if netName == first {
+			continue
+		}
+		if err := m.cli.NetworkConnect(ctx, netName, resp.ID, ep); err != nil {
+			return "", m.rollback(ctx, old.ID, resp.ID, name, wasRunning, fmt.Errorf("failed to join network %s: %w", netName, err))
+		}
+	}
+	if wasRunning {
+		if err := m.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+			return "", m.rollback(ctx, old.ID, resp.ID, name, wasRunning, err)
+		}
+		if err := m.waitStarted(ctx, resp.ID); err != nil {
+			return "", m.rollback(ctx, old.ID, resp.ID, name, wasRunning, err)
+		}
+	}
+
+	if err := m.cli.ContainerRemove(ctx, old.ID, container.RemoveOptions{Force: true}); err != nil {
+		return resp.ID, fmt.Errorf("container adopted, but the old container %s was not removed: %w", backup, err)
+	}
+	return resp.ID, nil
+}
+
+func (m *MockManager) AdoptContainer(id string, t common.EnvironmentType) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.containers[id]
+	if !ok {
+		return "", fmt.Errorf("container not found")
+	}
+	if err := checkAdopt(c.Labels, t); err != nil {
+		return "", err
+	}
+	labels := make(map[string]string, len(c.Labels)+2)
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	labels["perssh.managed"] = "true"
+	labels["perssh.type"] = string(t)
+	c.Labels = labels
+	m.containers[id] = c
+	m.payloads[id] = common.CreateEnvPayload{Name: c.Name, Type: t, Image: c.Image, Mounts: c.Mounts}
+	return id, nil
+}
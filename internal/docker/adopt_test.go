@@ -0,0 +1,125 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+)
+
+func TestCheckAdopt(t *testing.T) {
+	if err := checkAdopt(nil, common.EnvTypeMinecraft); err != nil {
+		t.Error(err)
+	}
+	if err := checkAdopt(map[string]string{"perssh.managed": "true"}, common.EnvTypeStandard); err == nil {
+		t.Error("Expected an error for a managed container")
+	}
+	for _, typ := range []common.EnvironmentType{common.EnvTypeCompose, "", "WEB"} {
+		if err := checkAdopt(nil, typ); err == nil {
+			t.Errorf("Expected an error for type %q", typ)
+		}
+	}
+}
+
+func adoptInspect() container.InspectResponse {
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:    "0123456789abcdef",
+			Name:  "/legacy",
+			Image: "sha256:old",
+			HostConfig: &container.HostConfig{
+				NetworkMode: "shop",
+				Binds:       []string{"/srv/data:/data"},
+				Tmpfs:       map[string]string{"/run": ""},
+			},
+		},
+		Mounts: []container.MountPoint{
+			{Type: mount.TypeBind, Source: "/srv/data", Destination: "/data", RW: true},
+			{Type: mount.TypeVolume, Name: "3f1c0ffee", Destination: "/var/lib/app", RW: true},
+			{Type: mount.TypeTmpfs, Destination: "/run", RW: true},
+		},
+		Config: &container.Config{
+			Hostname: "0123456789ab",
+			Image:    "app:latest",
+			Labels:   map[string]string{"maintainer": "ops"},
+		},
+		NetworkSettings: &container.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"shop":   {Aliases: []string{"0123456789ab", "api"}},
+				"public": {},
+			},
+		},
+	}
+}
+
+func TestAdoptConfig(t *testing.T) {
+	c := adoptInspect()
+	config, hostConfig := adoptConfig(c, common.EnvTypeStandard, "sha256:old")
+	if config.Labels["perssh.managed"] != "true" || config.Labels["perssh.type"] != "STANDARD" || config.Labels["maintainer"] != "ops" {
+		t.Errorf("Unexpected labels: %v", config.Labels)
+	}
+	if _, ok := c.Config.Labels["perssh.managed"]; ok {
+		t.Error("The original labels were modified")
+	}
+	if config.Image != "app:latest" || config.Hostname != "" {
+		t.Errorf("Unexpected image or hostname: %q %q", config.Image, config.Hostname)
+	}
+	if hostConfig.Binds != nil || len(hostConfig.Mounts) != 2 {
+		t.Fatalf("Expected binds folded into 2 mounts, got %v %+v", hostConfig.Binds, hostConfig.Mounts)
+	}
+	if m := hostConfig.Mounts[1]; m.Type != mount.TypeVolume || m.Source != "3f1c0ffee" || m.Target != "/var/lib/app" {
+		t.Errorf("The anonymous volume must be reused by name: %+v", m)
+	}
+
+	// The tag moved on since the container was created
+	config, _ = adoptConfig(c, common.EnvTypeStandard, "sha256:new")
+	if config.Image != "sha256:old" {
+		t.Errorf("Expected the image pinned by ID, got %q", config.Image)
+	}
+}
+
+func TestAdoptEndpoints(t *testing.T) {
+	c := adoptInspect()
+	first, endpoints := adoptEndpoints(c)
+	if first != "shop" || len(endpoints) != 2 {
+		t.Fatalf("Unexpected endpoints: %q %v", first, endpoints)
+	}
+	if a := endpoints["shop"].Aliases; len(a) != 1 || a[0] != "api" {
+		t.Errorf("Expected the short ID alias dropped, got %v", a)
+	}
+
+	c.HostConfig.NetworkMode = "default"
+	c.NetworkSettings.Networks = map[string]*network.EndpointSettings{"bridge": {}}
+	if first, _ := adoptEndpoints(c); first != "bridge" {
+		t.Errorf("The default mode should map to bridge, got %q", first)
+	}
+	c.HostConfig.NetworkMode = "host"
+	if first, endpoints := adoptEndpoints(c); first != "" || endpoints != nil {
+		t.Error("Host networking has no endpoints to copy")
+	}
+}
+
+func TestMockAdopt(t *testing.T) {
+	m := NewMockManager()
+	managed, _ := m.CreateContainer(common.CreateEnvPayload{Name: "mc", Type: common.EnvTypeMinecraft}, nil)
+	m.containers["legacy"] = common.ContainerInfo{ID: "legacy", Name: "legacy", Image: "app", Status: "running"}
+
+	if _, err := m.AdoptContainer(managed, common.EnvTypeStandard); err == nil {
+		t.Error("Expected an error for a managed container")
+	}
+	id, err := m.AdoptContainer("legacy", common.EnvTypeStandard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _ := m.ListContainers()
+	for _, c := range list {
+		if !c.Managed() {
+			t.Errorf("%s is still unmanaged", c.Name)
+		}
+	}
+	if p, _ := m.GetEnvConfig(id); p.Type != common.EnvTypeStandard || p.Image != "app" {
+		t.Errorf("Unexpected config after adopting: %+v", p)
+	}
+}
@@ -0,0 +1,122 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+)
+
+// connectTimeout bounds probing one socket, so a hung daemon doesn't hold
+// up the agent start.
+const connectTimeout = 5 * time.Second
+
+// candidateHosts lists the API addresses to try for backend, in order. An
+// explicit host (the -host flag or DOCKER_HOST) is the only candidate;
+// otherwise Docker comes first, then rootless and rootful Podman. Podman
+// serves the Docker API on its socket, so RealManager drives both.
+func candidateHosts(backend, override string) []string {
+	if override != "" {
+		return []string{normalizeHost(override)}
+	}
+	if h := os.Getenv("DOCKER_HOST"); h != "" {
+		return []string{h}
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		// Unset in SSH sessions without a systemd login
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	var hosts []string
+	if backend != common.BackendPodman {
+		hosts = append(hosts, "unix:///var/run/docker.sock")
+	}
+	if backend != common.BackendDocker {
+		hosts = append(hosts, "unix://"+filepath.Join(runtimeDir, "podman", "podman.sock"), "unix:///run/podman/podman.sock")
+	}
+	return hosts
+}
+
+// normalizeHost accepts a bare socket path as well as an address.
+func normalizeHost(host string) string {
+	if strings.HasPrefix(host, "/") {
+		return "unix://" + host
+	}
+	return host
+}
+
+// backendName tells Podman from Docker by the components of a version
+// reply.
+func backendName(v types.Version) string {
+	names := []string{v.Platform.Name}
+	for _, c := range v.Components {
+		names = append(names, c.Name)
+	}
+	for _, n := range names {
+		if strings.Contains(strings.ToLower(n), "podman") {
+			return common.BackendPodman
+		}
+	}
+	return common.BackendDocker
+}
+
+// isRootless reports a daemon running without root, Docker's rootless mode
+// included.
+func isRootless(info system.Info) bool {
+	for _, opt := range info.SecurityOptions {
+		if strings.Contains(opt, "name=rootless") {
+			return true
+		}
+	}
+	return false
+}
+
+// connect opens a client for host and identifies the engine behind it.
+func connect(host string) (*client.Client, common.BackendInfo, error) {
+	if path, ok := strings.CutPrefix(host, "unix://"); ok {
+		// Skip the ping timeout for engines that aren't installed
+		if _, err := os.Stat(path); err != nil {
+			return nil, common.BackendInfo{}, fmt.Errorf("%s: no such socket", path)
+		}
+	}
+
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+	if host == os.Getenv("DOCKER_HOST") {
+		// Keep the TLS settings that go with it
+		opts = append(opts, client.FromEnv)
+	} else {
+		opts = append(opts, client.WithHost(host))
+	}
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, common.BackendInfo{}, fmt.Errorf("%s: %w", host, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	v, err := cli.ServerVersion(ctx)
+	if err != nil {
+		cli.Close()
+		return nil, common.BackendInfo{}, fmt.Errorf("%s: %w", host, err)
+	}
+	backend := common.BackendInfo{Name: backendName(v), Host: host, Version: v.Version}
+	if info, err := cli.Info(ctx); err == nil {
+		backend.Rootless = isRootless(info)
+	}
+	return cli, backend, nil
+}
+
+func (m *RealManager) Backend() common.BackendInfo {
+	return m.backend
+}
+
+func (m *MockManager) Backend() common.BackendInfo {
+	return common.BackendInfo{Name: common.BackendMock, Fallback: m.fallback}
+}
@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/system"
+)
+
+func TestCandidateHosts(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	hosts := candidateHosts("", "")
+	want := []string{"unix:///var/run/docker.sock", "unix:///run/user/1000/podman/podman.sock", "unix:///run/podman/podman.sock"}
+	if strings.Join(hosts, " ") != strings.Join(want, " ") {
+		t.Errorf("Got %v, want %v", hosts, want)
+	}
+	if hosts := candidateHosts(common.BackendPodman, ""); strings.Join(hosts, " ") != strings.Join(want[1:], " ") {
+		t.Errorf("Podman got %v", hosts)
+	}
+	if hosts := candidateHosts(common.BackendDocker, ""); strings.Join(hosts, " ") != want[0] {
+		t.Errorf("Docker got %v", hosts)
+	}
+
+	if hosts := candidateHosts("", "/run/podman/podman.sock"); len(hosts) != 1 || hosts[0] != "unix:///run/podman/podman.sock" {
+		t.Errorf("Override not used alone: %v", hosts)
+	}
+	t.Setenv("DOCKER_HOST", "tcp://10.0.0.5:2376")
+	if hosts := candidateHosts(common.BackendPodman, ""); len(hosts) != 1 || hosts[0] != "tcp://10.0.0.5:2376" {
+		t.Errorf("DOCKER_HOST not used alone: %v", hosts)
+	}
+}
+
+func TestBackendName(t *testing.T) {
+	podman := types.Version{Version: "5.2.2", Components: []types.ComponentVersion{{Name: "Podman Engine", Version: "5.2.2"}}}
+	if got := backendName(podman); got != common.BackendPodman {
+		t.Errorf("Podman reported as %s", got)
+	}
+	docker := types.Version{Version: "27.3.1", Components: []types.ComponentVersion{{Name: "Engine"}, {Name: "containerd"}}}
+	docker.Platform.Name = "Docker Engine - Community"
+	if got := backendName(docker); got != common.BackendDocker {
+		t.Errorf("Docker reported as %s", got)
+	}
+}
+
+func TestIsRootless(t *testing.T) {
+	if !isRootless(system.Info{SecurityOptions: []string{"name=seccomp,profile=default", "name=rootless"}}) {
+		t.Error("Expected rootless")
+	}
+	if isRootless(system.Info{SecurityOptions: []string{"name=apparmor"}}) {
+		t.Error("Expected rootful")
+	}
+}
+
+func TestNewManager(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "podman.sock")
+	if _, err := NewManager("", missing); err == nil || !strings.Contains(err.Error(), "no such socket") {
+		t.Errorf("Expected an error instead of the mock, got %v", err)
+	}
+	t.Setenv("DOCKER_HOST", "unix://"+missing)
+	if _, err := NewManager(common.BackendPodman, ""); err == nil {
+		t.Error("Expected an error for a requested backend that is missing")
+	}
+
+	// Only detection falls back, and says why
+	dm, err := NewManager("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := dm.Backend(); b.Name != common.BackendMock || !strings.Contains(b.Fallback, "no such socket") {
+		t.Errorf("Unexpected fallback %+v", b)
+	}
+	if err := dm.Shell("mock-1", strings.NewReader(""), io.Discard, nil); err == nil || !strings.Contains(err.Error(), "No Docker") {
+		t.Errorf("Expected the fallback to refuse a shell, got %v", err)
+	}
+	dm, err = NewManager(common.BackendMock, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := dm.Backend(); b.Name != common.BackendMock || b.Fallback != "" {
+		t.Errorf("Unexpected requested mock %+v", b)
+	}
+	if _, err := NewManager(common.BackendMock, missing); err == nil {
+		t.Error("Expected an error for a host with the mock")
+	}
+	if _, err := NewManager("lxc", ""); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
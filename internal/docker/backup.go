@@ -0,0 +1,656 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	backupExt      = ".tar.gz"
+	backupVersion  = 1
+	manifestName   = "perssh-backup.json" // First entry of every backup
+	backupMountDir = "mounts"             // Mount i is stored under mounts/<i>/
+	helperImage    = "busybox:stable"     // Clears volumes before a restore
+)
+
+// backupManifest describes what a backup holds. Mounts are in the order
+// they are stored.
+type backupManifest struct {
+	Version int                    `json:"version"`
+	Env     string                 `json:"env"`
+	Type    common.EnvironmentType `json:"type,omitempty"`
+	Image   string                 `json:"image,omitempty"`
+	Created int64                  `json:"created"`
+	Mounts  []common.Mount         `json:"mounts"`
+}
+
+func (man backupManifest) info(name string, size int64) common.BackupInfo {
+	info := common.BackupInfo{Name: name, Env: man.Env, Size: size, Created: man.Created}
+	for _, mt := range man.Mounts {
+		info.Targets = append(info.Targets, mt.Target)
+	}
+	return info
+}
+
+// defaultBackupDir is where the agent keeps backups. Clients upload into
+// it over SFTP, so it holds nothing else.
+func defaultBackupDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".perssh", "backups")
+}
+
+// backupName names a backup after its environment and time, so backups
+// sort by age within an environment.
+func backupName(env string, t time.Time) string {
+	return env + "-" + t.UTC().Format("20060102-150405") + backupExt
+}
+
+// checkBackupName rejects names that are not a backup file directly in
+// the backup directory.
+func checkBackupName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid backup name %q", name)
+	}
+	if !strings.HasSuffix(name, backupExt) {
+		return fmt.Errorf("backup %q is not a %s file", name, backupExt)
+	}
+	return nil
+}
+
+// backupMounts picks the mounts to back up. Read-only mounts are
+// configuration provided from elsewhere and could not be restored.
+func backupMounts(mounts []common.Mount) []common.Mount {
+	var res []common.Mount
+	for _, mt := range mounts {
+		if !mt.ReadOnly {
+			res = append(res, mt)
+		}
+	}
+	return res
+}
+
+// restoreTargets matches the mounts of a backup to those of the
+// environment it is restored into, by target.
+func restoreTargets(man backupManifest, mounts []common.Mount) ([]common.Mount, error) {
+	byTarget := make(map[string]common.Mount)
+	for _, mt := range backupMounts(mounts) {
+		byTarget[mt.Target] = mt
+	}
+	res := make([]common.Mount, 0, len(man.Mounts))
+	for _, mt := range man.Mounts {
+		own, ok := byTarget[mt.Target]
+		if !ok {
+			return nil, fmt.Errorf("environment has no writable mount at %s", mt.Target)
+		}
+		res = append(res, own)
+	}
+	return res, nil
+}
+
+// rerootEntry moves a tar entry named relative to root under newRoot. Hard
+// links name their target the same way.
+func rerootEntry(hdr *tar.Header, root, newRoot string) error {
+	rename := func(name string) (string, error) {
+		rel := strings.TrimPrefix(path.Clean("/"+name), "/")
+		if root != "" {
+			if rel != root && !strings.HasPrefix(rel, root+"/") {
+				return "", fmt.Errorf("unexpected entry %q outside %s", name, root)
+			}
+			rel = strings.TrimPrefix(strings.TrimPrefix(rel, root), "/")
+		}
+		return path.Join(newRoot, rel), nil
+	}
+	name, err := rename(hdr.Name)
+	if err != nil {
+		return err
+	}
+	if hdr.Typeflag == tar.TypeDir {
+		name += "/"
+	}
+	hdr.Name = name
+	if hdr.Typeflag == tar.TypeLink {
+		if hdr.Linkname, err = rename(hdr.Linkname); err != nil {
+			return err
+		}
+	}
+	// Let the writer pick a format that fits the new name
+	hdr.Format = tar.FormatUnknown
+	return nil
+}
+
+// writeBackup writes the manifest and then each mount. copyMount returns
+// the content of mount i as CopyFromContainer does: a tar stream whose
+// entries start with the base name of the target.
+func writeBackup(w io.Writer, man backupManifest, copyMount func(i int) (io.ReadCloser, error)) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	b, err := json.MarshalIndent(man, "", "  ")
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(b)), ModTime: time.Unix(man.Created, 0), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := tw.Write(b); err != nil {
+		return err
+	}
+
+	for i, mt := range man.Mounts {
+		r, err := copyMount(i)
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", mt.Target, err)
+		}
+		err = copyEntries(tw, tar.NewReader(r), path.Base(mt.Target), path.Join(backupMountDir, strconv.Itoa(i)))
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", mt.Target, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// copyEntries copies every entry of tr to tw, moved from root to newRoot.
+func copyEntries(tw *tar.Writer, tr *tar.Reader, root, newRoot string) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := rerootEntry(hdr, root, newRoot); err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// saveBackup writes a backup into dir under a temporary name, so a failed
+// backup never shows up in the list.
+func saveBackup(dir string, man backupManifest, copyMount func(i int) (io.ReadCloser, error)) (common.BackupInfo, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return common.BackupInfo{}, err
+	}
+	name := backupName(man.Env, time.Unix(man.Created, 0))
+	final := filepath.Join(dir, name)
+	if _, err := os.Stat(final); err == nil {
+		return common.BackupInfo{}, fmt.Errorf("backup %s already exists", name)
+	}
+	part := final + ".part"
+	f, err := os.OpenFile(part, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return common.BackupInfo{}, err
+	}
+	err = writeBackup(f, man, copyMount)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(part, final)
+	}
+	if err != nil {
+		os.Remove(part)
+		return common.BackupInfo{}, err
+	}
+	st, err := os.Stat(final)
+	if err != nil {
+		return common.BackupInfo{}, err
+	}
+	return man.info(name, st.Size()), nil
+}
+
+// readManifest reads the manifest at the start of a backup. The returned
+// reader continues with the mounts.
+func readManifest(r io.Reader) (backupManifest, *tar.Reader, error) {
+	var man backupManifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return man, nil, fmt.Errorf("not a PerSSH backup: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return man, nil, fmt.Errorf("not a PerSSH backup: missing %s", manifestName)
+	}
+	if err := json.NewDecoder(io.LimitReader(tr, 1<<20)).Decode(&man); err != nil {
+		return man, nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	if man.Version > backupVersion {
+		return man, nil, fmt.Errorf("backup was made by a newer PerSSH (format %d)", man.Version)
+	}
+	return man, tr, nil
+}
+
+// openBackup opens a backup in dir and reads its manifest. The caller
+// closes the file.
+func openBackup(dir, name string) (*os.File, backupManifest, *tar.Reader, error) {
+	if err := checkBackupName(name); err != nil {
+		return nil, backupManifest{}, nil, err
+	}
+	f, err := os.Open(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil, backupManifest{}, nil, fmt.Errorf("no such backup: %s", name)
+	} else if err != nil {
+		return nil, backupManifest{}, nil, err
+	}
+	man, tr, err := readManifest(f)
+	if err != nil {
+		f.Close()
+		return nil, man, nil, err
+	}
+	return f, man, tr, nil
+}
+
+// errMountStopped fails writes to a mount whose restore already ended.
+var errMountStopped = errors.New("restore of the mount stopped early")
+
+// splitMounts calls fn with the content of each mount in a backup as a tar
+// stream relative to the mount target, like CopyToContainer expects.
+func splitMounts(tr *tar.Reader, n int, fn func(i int, r io.Reader) error) error {
+	cur := -1
+	var (
+		pw   *io.PipeWriter
+		tw   *tar.Writer
+		done chan error
+	)
+	// finish ends the stream of the current mount and waits for fn
+	finish := func(err error) error {
+		if pw == nil {
+			return err
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+		ferr := <-done
+		pw = nil
+		// When fn gave up, its error says why
+		if ferr != nil && (err == nil || errors.Is(err, errMountStopped)) {
+			return ferr
+		}
+		return err
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return finish(err)
+		}
+		dir, rest, _ := strings.Cut(strings.TrimPrefix(hdr.Name, backupMountDir+"/"), "/")
+		i, err := strconv.Atoi(dir)
+		if err != nil || i < 0 || i >= n || !strings.HasPrefix(hdr.Name, backupMountDir+"/") {
+			return finish(fmt.Errorf("unexpected entry %q in backup", hdr.Name))
+		}
+		if i != cur {
+			if i < cur {
+				return finish(fmt.Errorf("backup entries out of order at %q", hdr.Name))
+			}
+			if err := finish(nil); err != nil {
+				return err
+			}
+			cur = i
+			pr, w := io.Pipe()
+			pw, tw, done = w, tar.NewWriter(w), make(chan error, 1)
+			go func(i int) {
+				err := fn(i, pr)
+				// Unblocks the writer if fn stopped reading early
+				pr.CloseWithError(errMountStopped)
+				done <- err
+			}(i)
+		}
+		if rest == "" {
+			// The mount root itself, which exists already
+			continue
+		}
+		if err := rerootEntry(hdr, path.Join(backupMountDir, dir), ""); err != nil {
+			return finish(err)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return finish(err)
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return finish(err)
+		}
+	}
+	return finish(nil)
+}
+
+// listBackups reads the manifests of the backups in dir, newest first.
+// Files without one, e.g. unfinished uploads, are listed without an
+// environment.
+func listBackups(dir string) (common.BackupList, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return common.BackupList{}, err
+	}
+	list := common.BackupList{Dir: abs}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return list, nil
+	} else if err != nil {
+		return list, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || checkBackupName(e.Name()) != nil {
+			continue
+		}
+		st, err := e.Info()
+		if err != nil {
+			continue
+		}
+		info := common.BackupInfo{Name: e.Name(), Size: st.Size(), Created: st.ModTime().Unix()}
+		if f, man, _, err := openBackup(dir, e.Name()); err == nil {
+			f.Close()
+			info = man.info(e.Name(), st.Size())
+		}
+		list.Backups = append(list.Backups, info)
+	}
+	sort.Slice(list.Backups, func(i, j int) bool {
+		a, b := list.Backups[i], list.Backups[j]
+		if a.Created != b.Created {
+			return a.Created > b.Created
+		}
+		return a.Name < b.Name
+	})
+	return list, nil
+}
+
+func deleteBackup(dir, name string) error {
+	if err := checkBackupName(name); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no such backup: %s", name)
+	}
+	return err
+}
+
+// BackupEnv writes the writable mounts of an environment to a tarball in
+// the backup directory. A running Minecraft server stops saving while it
+// is copied, after flushing the world; without RCON, or when asked to,
+// the environment is stopped instead and started again afterwards.
+func (m *RealManager) BackupEnv(p common.BackupEnvPayload) (common.BackupInfo, error) {
+	ctx := context.Background()
+	c, err := m.cli.ContainerInspect(ctx, p.ID)
+	if err != nil {
+		return common.BackupInfo{}, err
+	}
+	if c.ContainerJSONBase == nil || c.Config == nil {
+		return common.BackupInfo{}, fmt.Errorf("incomplete inspect data for %s", p.ID)
+	}
+	man := backupManifest{
+		Version: backupVersion,
+		Env:     strings.TrimPrefix(c.Name, "/"),
+		Type:    common.EnvironmentType(c.Config.Labels["perssh.type"]),
+		Image:   c.Config.Image,
+		Created: time.Now().Unix(),
+		Mounts:  backupMounts(fromMountPoints(c.Mounts)),
+	}
+	if len(man.Mounts) == 0 {
+		return common.BackupInfo{}, fmt.Errorf("%s has no volumes to back up", man.Env)
+	}
+
+	resume := func() error { return nil }
+	if c.State != nil && c.State.Running {
+		if resume, err = m.quiesce(ctx, c.ID, man.Type, p.Stop); err != nil {
+			return common.BackupInfo{}, err
+		}
+	}
+	info, err := saveBackup(m.backups, man, func(i int) (io.ReadCloser, error) {
+		r, _, err := m.cli.CopyFromContainer(ctx, c.ID, man.Mounts[i].Target)
+		return r, err
+	})
+	if rerr := resume(); rerr != nil {
+		if err == nil {
+			return info, fmt.Errorf("backup %s written, but %w", info.Name, rerr)
+		}
+		return info, fmt.Errorf("%w; also %v", err, rerr)
+	}
+	return info, err
+}
+
+// quiesce makes the data of a running environment safe to copy and
+// returns how to undo that.
+func (m *RealManager) quiesce(ctx context.Context, id string, t common.EnvironmentType, stop bool) (func() error, error) {
+	if t == common.EnvTypeMinecraft && !stop {
+		if err := m.rcon(id, "save-off"); err == nil {
+			if err := m.rcon(id, "save-all", "flush"); err == nil {
+				return func() error {
+					if err := m.rcon(id, "save-on"); err != nil {
+						return fmt.Errorf("saving could not be turned back on: %w", err)
+					}
+					return nil
+				}, nil
+			}
+			m.rcon(id, "save-on")
+		}
+	} else if !stop {
+		return func() error { return nil }, nil
+	}
+
+	if err := m.cli.ContainerStop(ctx, id, container.StopOptions{}); err != nil {
+		return nil, err
+	}
+	return func() error {
+		if err := m.cli.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+			return fmt.Errorf("the environment failed to start again: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// rcon runs rcon-cli in a Minecraft container. The itzg images ship it
+// with RCON enabled by default.
+func (m *RealManager) rcon(id string, args ...string) error {
+	res, err := m.Exec(common.ExecPayload{ID: id, Cmd: append([]string{"rcon-cli"}, args...), Timeout: 120})
+	if err != nil {
+		return err
+	}
+	if res.TimedOut || res.ExitCode != 0 {
+		return fmt.Errorf("rcon-cli %s failed: %s", strings.Join(args, " "), strings.TrimSpace(res.Stderr+res.Stdout))
+	}
+	return nil
+}
+
+// RestoreBackup replaces the content of an environment's mounts with a
+// backup. The environment is stopped meanwhile and started again if it
+// was running. A failed restore leaves the mounts partly restored; the
+// backup is untouched and can be restored again.
+func (m *RealManager) RestoreBackup(id, name string) error {
+	ctx := context.Background()
+	f, man, tr, err := openBackup(m.backups, name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c, err := m.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return err
+	}
+	targets, err := restoreTargets(man, fromMountPoints(c.Mounts))
+	if err != nil {
+		return err
+	}
+
+	running := c.State != nil && (c.State.Running || c.State.Restarting)
+	if running {
+		if err := m.cli.ContainerStop(ctx, c.ID, container.StopOptions{}); err != nil {
+			return err
+		}
+	}
+	err = m.clearMounts(ctx, targets)
+	if err == nil {
+		err = splitMounts(tr, len(targets), func(i int, r io.Reader) error {
+			// Keep the owners from the backup; without this Docker gives
+			// everything to root and non-root images can't write their data
+			return m.cli.CopyToContainer(ctx, c.ID, targets[i].Target, r, container.CopyToContainerOptions{CopyUIDGID: true})
+		})
+	}
+	if running {
+		if serr := m.cli.ContainerStart(ctx, c.ID, container.StartOptions{}); serr != nil {
+			if err == nil {
+				return fmt.Errorf("backup restored, but the environment failed to start: %w", serr)
+			}
+			return fmt.Errorf("%w; also failed to start the environment: %v", err, serr)
+		}
+	}
+	return err
+}
+
+// clearMounts empties mounts with a short-lived helper container, since
+// the copy API can add files but not remove them.
+func (m *RealManager) clearMounts(ctx context.Context, mounts []common.Mount) error {
+	if _, err := m.cli.ImageInspect(ctx, helperImage); err != nil {
+		if err := m.PullImage(helperImage, nil, nil); err != nil {
+			return err
+		}
+	}
+	cmd := []string{"find"}
+	var mts []mount.Mount
+	for i, mt := range mounts {
+		dir := fmt.Sprintf("/clear/%d", i)
+		cmd = append(cmd, dir)
+		t := mount.TypeVolume
+		if mt.IsBind() {
+			t = mount.TypeBind
+		}
+		mts = append(mts, mount.Mount{Type: t, Source: mt.Source, Target: dir})
+	}
+	cmd = append(cmd, "-mindepth", "1", "-delete")
+
+	resp, err := m.cli.ContainerCreate(ctx,
+		&container.Config{Image: helperImage, Cmd: cmd},
+		&container.HostConfig{Mounts: mts, NetworkMode: "none"},
+		nil, &v1.Platform{}, "")
+	if err != nil {
+		return err
+	}
+	defer m.cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+
+	waitC, errC := m.cli.ContainerWait(ctx, resp.ID, container.WaitConditionNextExit)
+	if err := m.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return err
+	}
+	select {
+	case w := <-waitC:
+		if w.Error != nil {
+			return fmt.Errorf("failed to clear mounts: %s", w.Error.Message)
+		}
+		if w.StatusCode != 0 {
+			return fmt.Errorf("failed to clear mounts (exit code %d)", w.StatusCode)
+		}
+	case err := <-errC:
+		return err
+	}
+	return nil
+}
+
+func (m *RealManager) ListBackups() (common.BackupList, error) {
+	return listBackups(m.backups)
+}
+
+func (m *RealManager) DeleteBackup(name string) error {
+	return deleteBackup(m.backups, name)
+}
+
+// mockMountTar is what CopyFromContainer returns for an empty directory.
+func mockMountTar(target string) io.ReadCloser {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: path.Base(target) + "/", Mode: 0755, Typeflag: tar.TypeDir, ModTime: time.Now()})
+	tw.Close()
+	return io.NopCloser(&buf)
+}
+
+// BackupEnv writes a real backup of the empty mock mounts.
+func (m *MockManager) BackupEnv(p common.BackupEnvPayload) (common.BackupInfo, error) {
+	m.mu.Lock()
+	c, ok := m.containers[p.ID]
+	m.mu.Unlock()
+	if !ok {
+		return common.BackupInfo{}, fmt.Errorf("container not found")
+	}
+	man := backupManifest{
+		Version: backupVersion,
+		Env:     c.Name,
+		Type:    common.EnvironmentType(c.Labels["perssh.type"]),
+		Image:   c.Image,
+		Created: time.Now().Unix(),
+		Mounts:  backupMounts(c.Mounts),
+	}
+	if len(man.Mounts) == 0 {
+		return common.BackupInfo{}, fmt.Errorf("%s has no volumes to back up", man.Env)
+	}
+	return saveBackup(m.backups, man, func(i int) (io.ReadCloser, error) {
+		return mockMountTar(man.Mounts[i].Target), nil
+	})
+}
+
+// RestoreBackup checks the backup against the environment and reads it
+// through, without anything to restore into.
+func (m *MockManager) RestoreBackup(id, name string) error {
+	f, man, tr, err := openBackup(m.backups, name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	m.mu.Lock()
+	c, ok := m.containers[id]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("container not found")
+	}
+	targets, err := restoreTargets(man, c.Mounts)
+	if err != nil {
+		return err
+	}
+	return splitMounts(tr, len(targets), func(i int, r io.Reader) error {
+		_, err := io.Copy(io.Discard, r)
+		return err
+	})
+}
+
+func (m *MockManager) ListBackups() (common.BackupList, error) {
+	return listBackups(m.backups)
+}
+
+func (m *MockManager) DeleteBackup(name string) error {
+	return deleteBackup(m.backups, name)
+}
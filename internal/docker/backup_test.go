@@ -0,0 +1,221 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+)
+
+func TestCheckBackupName(t *testing.T) {
+	for _, name := range []string{"mc-20250101-120000.tar.gz", "upload.tar.gz"} {
+		if err := checkBackupName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../x.tar.gz", "a/b.tar.gz", `a\b.tar.gz`, ".hidden.tar.gz", "mc.tar.gz.part", "mc.zip"} {
+		if err := checkBackupName(name); err == nil {
+			t.Errorf("%q: expected error", name)
+		}
+	}
+}
+
+func TestBackupMounts(t *testing.T) {
+	mounts := []common.Mount{
+		{Source: "mc-data", Target: "/data"},
+		{Source: "/srv/config", Target: "/config", ReadOnly: true},
+	}
+	if res := backupMounts(mounts); len(res) != 1 || res[0].Target != "/data" {
+		t.Errorf("Unexpected mounts %+v", res)
+	}
+}
+
+func TestRestoreTargets(t *testing.T) {
+	man := backupManifest{Mounts: []common.Mount{{Source: "old-data", Target: "/data"}}}
+	res, err := restoreTargets(man, []common.Mount{{Source: "/srv/logs", Target: "/logs"}, {Source: "new-data", Target: "/data"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Source != "new-data" {
+		t.Errorf("Unexpected targets %+v", res)
+	}
+	if _, err := restoreTargets(man, []common.Mount{{Source: "new-data", Target: "/data", ReadOnly: true}}); err == nil {
+		t.Error("Expected error for a read-only target")
+	}
+	if _, err := restoreTargets(man, nil); err == nil {
+		t.Error("Expected error for a missing target")
+	}
+}
+
+// containerTar builds a stream like CopyFromContainer returns for dir.
+func containerTar(t *testing.T, dir string, files map[string]string) io.ReadCloser {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0755})
+	for name, content := range files {
+		// Owned like the data of the itzg Minecraft image
+		tw.WriteHeader(&tar.Header{Name: dir + "/" + name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content)), Uid: 1000, Gid: 1000})
+		tw.Write([]byte(content))
+	}
+	tw.WriteHeader(&tar.Header{Name: dir + "/hard", Typeflag: tar.TypeLink, Linkname: dir + "/level.dat"})
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return io.NopCloser(&buf)
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	man := backupManifest{
+		Version: backupVersion,
+		Env:     "mc",
+		Created: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC).Unix(),
+		Mounts:  []common.Mount{{Source: "mc-data", Target: "/data"}, {Source: "mc-mods", Target: "/srv/data"}},
+	}
+	files := []map[string]string{{"level.dat": "world"}, {"level.dat": "mods"}}
+	info, err := saveBackup(dir, man, func(i int) (io.ReadCloser, error) {
+		return containerTar(t, "data", files[i]), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "mc-20250102-030405.tar.gz" || info.Env != "mc" || info.Size == 0 || len(info.Targets) != 2 {
+		t.Errorf("Unexpected info %+v", info)
+	}
+	if _, err := saveBackup(dir, man, nil); err == nil {
+		t.Error("Expected error for an existing backup")
+	}
+
+	list, err := listBackups(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Backups) != 1 || list.Backups[0].Name != info.Name || list.Backups[0].Created != man.Created {
+		t.Errorf("Unexpected list %+v", list)
+	}
+
+	f, got, tr, err := openBackup(dir, info.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got.Env != "mc" || len(got.Mounts) != 2 {
+		t.Errorf("Unexpected manifest %+v", got)
+	}
+	restored := make(map[int]map[string]string)
+	owners := make(map[int]string)
+	err = splitMounts(tr, 2, func(i int, r io.Reader) error {
+		restored[i] = make(map[string]string)
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			b, _ := io.ReadAll(tr)
+			restored[i][hdr.Name] = string(b) + hdr.Linkname
+			if hdr.Name == "level.dat" {
+				owners[i] = fmt.Sprintf("%d:%d", hdr.Uid, hdr.Gid)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"world", "mods"} {
+		if owners[i] != "1000:1000" {
+			t.Errorf("Mount %d lost the owner of level.dat: %s", i, owners[i])
+		}
+		if restored[i]["level.dat"] != want || restored[i]["hard"] != "level.dat" || len(restored[i]) != 2 {
+			t.Errorf("Mount %d restored as %v", i, restored[i])
+		}
+	}
+}
+
+func TestSplitMountsStopsEarly(t *testing.T) {
+	dir := t.TempDir()
+	man := backupManifest{Env: "mc", Created: time.Now().Unix(), Mounts: []common.Mount{{Source: "mc-data", Target: "/data"}}}
+	big := map[string]string{"region.mca": strings.Repeat("x", 1<<20)}
+	info, err := saveBackup(dir, man, func(i int) (io.ReadCloser, error) { return containerTar(t, "data", big), nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _, tr, err := openBackup(dir, info.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	failed := errors.New("daemon said no")
+	if err := splitMounts(tr, 1, func(int, io.Reader) error { return failed }); err != failed {
+		t.Errorf("Expected the error of fn, got %v", err)
+	}
+}
+
+func TestReadManifestRejectsForeignFiles(t *testing.T) {
+	if _, _, err := readManifest(strings.NewReader("not gzip")); err == nil {
+		t.Error("Expected error")
+	}
+	var buf bytes.Buffer
+	if err := writeBackup(&buf, backupManifest{Version: backupVersion + 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := readManifest(&buf); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected a version error, got %v", err)
+	}
+}
+
+func TestMockBackup(t *testing.T) {
+	m := NewMockManager()
+	m.backups = t.TempDir()
+	id, err := m.CreateContainer(common.CreateEnvPayload{Name: "mc", Image: "itzg/minecraft-server", Type: common.EnvTypeMinecraft, Mounts: []common.Mount{{Target: "/data"}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bare, _ := m.CreateContainer(common.CreateEnvPayload{Name: "bare", Image: "nginx"}, nil)
+
+	info, err := m.BackupEnv(common.BackupEnvPayload{ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Env != "mc" || len(info.Targets) != 1 || info.Targets[0] != "/data" {
+		t.Errorf("Unexpected backup %+v", info)
+	}
+	if _, err := m.BackupEnv(common.BackupEnvPayload{ID: bare}); err == nil {
+		t.Error("Expected error for an environment without volumes")
+	}
+
+	list, err := m.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Dir != m.backups || len(list.Backups) != 1 {
+		t.Errorf("Unexpected list %+v", list)
+	}
+
+	if err := m.RestoreBackup(id, info.Name); err != nil {
+		t.Errorf("Restore failed: %v", err)
+	}
+	if err := m.RestoreBackup(bare, info.Name); err == nil {
+		t.Error("Expected error restoring into an environment without /data")
+	}
+	if err := m.RestoreBackup(id, "../"+info.Name); err == nil {
+		t.Error("Expected error for a path outside the backup directory")
+	}
+
+	if err := m.DeleteBackup(info.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeleteBackup(info.Name); err == nil {
+		t.Error("Expected error deleting twice")
+	}
+}
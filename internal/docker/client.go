@@ -2,6 +2,8 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -11,45 +13,115 @@ import (
 
 	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
-	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 type DockerClient interface {
 	Close()
 	IsRunning() bool
+	Backend() common.BackendInfo
 	ListContainers() ([]common.ContainerInfo, error)
-	CreateContainer(payload common.CreateEnvPayload) (string, error)
+	PullImage(ref string, auth *common.RegistryAuth, progress PullHandler) error
+	ListImages() ([]common.ImageInfo, error)
+	RemoveImage(ref string, force bool) error
+	PruneImages(all bool) (common.PruneResult, error)
+	DiskUsage() (common.DiskUsage, error)
+	CreateContainer(payload common.CreateEnvPayload, progress PullHandler) (string, error)
+	GetEnvConfig(id string) (common.CreateEnvPayload, error)
+	InspectEnv(id string) (common.EnvInspect, error)
+	BackupEnv(p common.BackupEnvPayload) (common.BackupInfo, error)
+	ListBackups() (common.BackupList, error)
+	RestoreBackup(id, name string) error
+	DeleteBackup(name string) error
+	RecreateContainer(id string, payload common.CreateEnvPayload, progress PullHandler) (string, error)
+	AdoptContainer(id string, t common.EnvironmentType) (string, error)
 	StartContainer(id string) error
 	StopContainer(id string) error
-	RemoveContainer(id string) error
-	GetLogs(id string) (string, error)
+	RestartContainer(id string, timeout int) error
+	PauseContainer(id string) error
+	UnpauseContainer(id string) error
+	RenameContainer(id, name string) error
+	KillContainer(id, signal string) error
+	RemoveContainer(id string, removeVolumes bool) error
+	DeployStack(name string, compose []byte, auths []common.RegistryAuth, progress PullHandler) error
+	StartStack(name string) error
+	StopStack(name string) error
+	RemoveStack(name string, removeVolumes bool) error
+	ListVolumes() ([]common.VolumeInfo, error)
+	ListNetworks() ([]common.NetworkInfo, error)
+	CreateNetwork(payload common.CreateNetworkPayload) (string, error)
+	RemoveNetwork(name string) error
+	ContainerStats(id string) ([]common.ContainerStats, error)
+	GetHealth(id string) (common.HealthInfo, error)
+	Exec(payload common.ExecPayload) (common.ExecResult, error)
+	GetLogs(q common.LogsQuery) (common.LogsResult, error)
+	GetLimits(id string) (common.Resources, error)
+	UpdateLimits(id string, r common.Resources) error
 	SendInput(id string, data string) error
+	AttachConsole(id string, handler ConsoleHandler) error
+	DetachConsole(id string) error
+	Shell(id string, stdin io.Reader, stdout io.Writer, resize <-chan TermSize) error
 }
 
 type RealManager struct {
 	cli *client.Client
+
+	mu       sync.Mutex
+	consoles map[string]*console
+	backups  string // Backup directory
+	backend  common.BackendInfo
 }
 
-func NewManager() (DockerClient, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Docker not available, using mock manager")
+// NewManager connects to backend: BackendDocker, BackendPodman, or the
+// first engine that answers when empty, see candidateHosts. BackendMock
+// needs no engine. Only detection falls back to MockManager, which then
+// reports why through Backend.
+func NewManager(backend, host string) (DockerClient, error) {
+	switch backend {
+	case common.BackendMock:
+		if host != "" {
+			return nil, fmt.Errorf("the mock backend takes no host")
+		}
+		fmt.Fprintln(os.Stderr, "Using mock manager as requested")
 		return NewMockManager(), nil
+	case "", common.BackendDocker, common.BackendPodman:
+	default:
+		return nil, fmt.Errorf("unknown backend %q (want docker, podman or mock)", backend)
 	}
 
-	// Verify connection
-	if _, err := cli.Ping(context.Background()); err != nil {
-		fmt.Fprintln(os.Stderr, "Docker ping failed, using mock manager")
-		return NewMockManager(), nil
+	var errs []string
+	for _, h := range candidateHosts(backend, host) {
+		cli, info, err := connect(h)
+		if err == nil && backend != "" && info.Name != backend {
+			cli.Close()
+			err = fmt.Errorf("%s: found %s, not %s", h, info.Name, backend)
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Using %s %s at %s\n", info.Name, info.Version, info.Host)
+		return &RealManager{cli: cli, backend: info, consoles: make(map[string]*console), backups: defaultBackupDir()}, nil
+	}
+	if backend != "" || host != "" {
+		return nil, errors.New(strings.Join(errs, "; "))
 	}
 
-	return &RealManager{cli: cli}, nil
+	m := NewMockManager()
+	m.fallback = "No Docker or Podman found (" + strings.Join(errs, "; ") + ")"
+	fmt.Fprintf(os.Stderr, "%s, using mock manager\n", m.fallback)
+	return m, nil
 }
 
 func (m *RealManager) Close() {
+	m.mu.Lock()
+	for id, c := range m.consoles {
+		c.resp.Close()
+		delete(m.consoles, id)
+	}
+	m.mu.Unlock()
 	m.cli.Close()
 }
 
@@ -67,12 +139,25 @@ func (m *RealManager) ListContainers() ([]common.ContainerInfo, error) {
 	var res []common.ContainerInfo
 	for _, c := range containers {
 		info := common.ContainerInfo{
-			ID:      c.ID[:12],
-			Name:    "",
-			Image:   c.Image,
-			Status:  c.State, // "running", "exited"
-			Created: c.Created,
-			Labels:  c.Labels,
+			ID:       c.ID[:12],
+			Name:     "",
+			Image:    c.Image,
+			Status:   c.State, // "running", "exited"
+			Created:  c.Created,
+			Labels:   c.Labels,
+			Mounts:   fromMountPoints(c.Mounts),
+			Stack:    c.Labels[stackLabel],
+			Service:  c.Labels[serviceLabel],
+			Networks: summaryNetworks(c.NetworkSettings),
+			Health:   healthFromStatus(c.Status),
+		}
+		for _, p := range c.Ports {
+			info.Ports = append(info.Ports, common.PortBinding{
+				HostIP:        p.IP,
+				HostPort:      p.PublicPort,
+				ContainerPort: p.PrivatePort,
+				Protocol:      p.Type,
+			})
 		}
 		if len(c.Names) > 0 {
 			info.Name = c.Names[0][1:] // Remove leading slash
@@ -82,22 +167,83 @@ func (m *RealManager) ListContainers() ([]common.ContainerInfo, error) {
 	return res, nil
 }
 
-func (m *RealManager) CreateContainer(payload common.CreateEnvPayload) (string, error) {
+// CreateContainer pulls the image, reporting progress, and creates the
+// container. A failed pull is returned as a *PullError.
+func (m *RealManager) CreateContainer(payload common.CreateEnvPayload, progress PullHandler) (string, error) {
 	ctx := context.Background()
 
-	// Pull Image
-	out, err := m.cli.ImagePull(ctx, payload.Image, image.PullOptions{})
-	if err == nil {
-		// We must read the stream to completion or close it
-		io.Copy(io.Discard, out)
-		out.Close()
+	// Validate ports and limits before pulling so bad input fails fast
+	spec, err := m.prepareCreate(ctx, payload, "")
+	if err != nil {
+		return "", err
+	}
+	if err := m.PullImage(payload.Image, payload.Auth, progress); err != nil {
+		return "", err
+	}
+	return m.create(ctx, payload, spec)
+}
+
+// createSpec is a validated payload, converted for the Docker API.
+type createSpec struct {
+	exposed   nat.PortSet
+	bindings  nat.PortMap
+	resources container.Resources
+	storage   map[string]string
+	mounts    []common.Mount
+	networks  []common.NetworkAttachment
+	health    *container.HealthConfig
+}
+
+// prepareCreate validates a payload. Ports published by the container
+// named replacing are treated as free, since it is about to be replaced.
+func (m *RealManager) prepareCreate(ctx context.Context, payload common.CreateEnvPayload, replacing string) (createSpec, error) {
+	var spec createSpec
+	var err error
+	spec.exposed, spec.bindings, err = parsePorts(payload.Ports)
+	if err != nil {
+		return spec, err
+	}
+	spec.resources, spec.storage, err = toHostResources(payloadResources(payload))
+	if err != nil {
+		return spec, err
+	}
+	spec.mounts, err = resolveMounts(payload.Name, payload.Mounts)
+	if err != nil {
+		return spec, err
 	}
+	if err := checkNetworks(payload.Networks); err != nil {
+		return spec, err
+	}
+	if err := m.checkNetworksExist(ctx, payload.Networks); err != nil {
+		return spec, err
+	}
+	spec.networks = payload.Networks
+	spec.health, err = toHealthConfig(payload.Health)
+	if err != nil {
+		return spec, err
+	}
+	if len(spec.bindings) > 0 {
+		used, err := m.publishedPorts(ctx)
+		if err != nil {
+			return spec, err
+		}
+		probe := probeListen
+		if own, ok := used[replacing]; ok {
+			delete(used, replacing)
+			probe = skipPorts(probeListen, own)
+		}
+		if err := checkPortConflicts(spec.bindings, used, probe); err != nil {
+			return spec, err
+		}
+	}
+	return spec, nil
+}
 
+// create creates the container for a prepared payload. The image must
+// already be present.
+func (m *RealManager) create(ctx context.Context, payload common.CreateEnvPayload, spec createSpec) (string, error) {
 	// Prepare Env Vars
 	envMap := make(map[string]string)
-	for k, v := range payload.EnvVars {
-		envMap[k] = v
-	}
 
 	// Minecraft Specific Logic
 	if payload.Type == common.EnvTypeMinecraft {
@@ -135,41 +281,54 @@ func (m *RealManager) CreateContainer(payload common.CreateEnvPayload) (string,
 		}
 	}
 
+	// Explicit variables win over module settings, so they can be edited
+	// when the environment is updated
+	for k, v := range payload.EnvVars {
+		envMap[k] = v
+	}
+
+	stored, err := encodePayload(payload, spec.mounts)
+	if err != nil {
+		return "", err
+	}
 	config := &container.Config{
 		Image: payload.Image,
 		Env:   mapToEnvList(envMap),
 		Labels: map[string]string{
 			"perssh.managed": "true",
 			"perssh.type":    string(payload.Type),
+			payloadLabel:     stored,
 		},
+		Healthcheck: spec.health,
 		OpenStdin:   true,
 		AttachStdin: true,
 	}
 
 	hostConfig := &container.HostConfig{
 		RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
+		PortBindings:  spec.bindings,
+		Resources:     spec.resources,
+		StorageOpt:    spec.storage,
+		Mounts:        toDockerMounts(spec.mounts),
 	}
-	
-	// Port binding (naive mapping for now, assuming host mode or simple 1:1)
-	// payload.Ports is ["8080:80"]
-	// We need to parse this into PortBindings if we want specific ports.
-	// For MVP, lets just use PublishAllPorts=true or parse.
-	// Let's implement PublishAllPorts as a fallback, or better, parse the list.
-	if len(payload.Ports) > 0 {
-		// Simple implementation: we trust Docker's nat.ParsePortSpecs? No, that's not exposed easily here.
-		// We need to construct nat.PortMap.
-		// Since importing nat is annoying with current imports, let's use PublishAllPorts for now
-		// OR: Assuming the user provides valid mappings.
-		// Ideally we should use nat.ParsePortSpecs.
-		// But to keep it simple and compile-safe without new imports:
-		hostConfig.PublishAllPorts = true 
+	config.ExposedPorts = spec.exposed
+
+	if err := m.ensureVolumes(ctx, payload.Name, spec.mounts); err != nil {
+		return "", err
 	}
 
 	// Create
-	resp, err := m.cli.ContainerCreate(ctx, config, hostConfig, &network.NetworkingConfig{}, &v1.Platform{}, payload.Name)
+	netConfig := networkingFor(spec.networks, hostConfig)
+	resp, err := m.cli.ContainerCreate(ctx, config, hostConfig, netConfig, &v1.Platform{}, payload.Name)
 	if err != nil {
 		return "", err
 	}
+	if len(spec.networks) > 1 {
+		if err := m.connectNetworks(ctx, resp.ID, spec.networks[1:]); err != nil {
+			m.cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+			return "", err
+		}
+	}
 
 	return resp.ID, nil
 }
@@ -182,39 +341,32 @@ func (m *RealManager) StopContainer(id string) error {
 	return m.cli.ContainerStop(context.Background(), id, container.StopOptions{})
 }
 
-func (m *RealManager) RemoveContainer(id string) error {
-	return m.cli.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true})
-}
-
-func (m *RealManager) GetLogs(id string) (string, error) {
-	// Fetch last 100 lines
-	opts := container.LogsOptions{ShowStdout: true, ShowStderr: true, Tail: "100"}
-	out, err := m.cli.ContainerLogs(context.Background(), id, opts)
-	if err != nil {
-		return "", err
-	}
-	defer out.Close()
-
-	b, err := io.ReadAll(out)
-	return string(b), err
-}
-
-func (m *RealManager) SendInput(id string, data string) error {
+// RemoveContainer force-removes a container. With removeVolumes the named
+// volumes created for it go too, unless another container still uses them.
+func (m *RealManager) RemoveContainer(id string, removeVolumes bool) error {
 	ctx := context.Background()
-	opts := container.AttachOptions{
-		Stream: true,
-		Stdin:  true,
+
+	var mounts []common.Mount
+	var envs []string
+	if removeVolumes {
+		inspect, err := m.cli.ContainerInspect(ctx, id)
+		if err != nil {
+			return err
+		}
+		mounts = fromMountPoints(inspect.Mounts)
+		// Volumes are labelled with the name at creation, which the
+		// payload label keeps after a rename
+		envs = append(envs, strings.TrimPrefix(inspect.Name, "/"))
+		var p common.CreateEnvPayload
+		if inspect.Config != nil && json.Unmarshal([]byte(inspect.Config.Labels[payloadLabel]), &p) == nil {
+			envs = append(envs, p.Name)
+		}
 	}
 
-	resp, err := m.cli.ContainerAttach(ctx, id, opts)
-	if err != nil {
+	if err := m.cli.ContainerRemove(ctx, id, container.RemoveOptions{Force: true, RemoveVolumes: removeVolumes}); err != nil {
 		return err
 	}
-	defer resp.Close()
-
-	// Write data + newline
-	_, err = resp.Conn.Write([]byte(data + "\n"))
-	return err
+	return m.removeVolumes(ctx, mounts, envs...)
 }
 
 func mapToEnvList(m map[string]string) []string {
@@ -226,14 +378,30 @@ func mapToEnvList(m map[string]string) []string {
 }
 
 // MockManager for environments without Docker
+
 type MockManager struct {
 	containers map[string]common.ContainerInfo
+	consoles   map[string]ConsoleHandler
+	limits     map[string]common.Resources
+	payloads   map[string]common.CreateEnvPayload // As stored in the payload label
+	volumes    map[string]string // Name to the environment or stack that created it
+	networks   map[string]common.NetworkInfo // User-defined only
+	images     map[string]time.Time          // Pulled refs and when
+	backups    string                        // Backup directory, real files
+	fallback   string                        // Why detection ended up here, see BackendInfo
 	mu         sync.Mutex
 }
 
 func NewMockManager() *MockManager {
 	return &MockManager{
 		containers: make(map[string]common.ContainerInfo),
+		consoles:   make(map[string]ConsoleHandler),
+		limits:     make(map[string]common.Resources),
+		payloads:   make(map[string]common.CreateEnvPayload),
+		volumes:    make(map[string]string),
+		networks:   make(map[string]common.NetworkInfo),
+		images:     make(map[string]time.Time),
+		backups:    defaultBackupDir(),
 	}
 }
 
@@ -251,17 +419,62 @@ func (m *MockManager) ListContainers() ([]common.ContainerInfo, error) {
 	return list, nil
 }
 
-func (m *MockManager) CreateContainer(payload common.CreateEnvPayload) (string, error) {
+func (m *MockManager) CreateContainer(payload common.CreateEnvPayload, progress PullHandler) (string, error) {
+	if err := m.PullImage(payload.Image, payload.Auth, progress); err != nil {
+		return "", err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	_, bindings, err := parsePorts(payload.Ports)
+	if err != nil {
+		return "", err
+	}
+	used := make(map[string][]common.PortBinding)
+	for _, c := range m.containers {
+		used[c.Name] = c.Ports
+	}
+	if err := checkPortConflicts(bindings, used, nil); err != nil {
+		return "", err
+	}
+
+	if _, _, err := toHostResources(payloadResources(payload)); err != nil {
+		return "", err
+	}
+	mounts, err := resolveMounts(payload.Name, payload.Mounts)
+	if err != nil {
+		return "", err
+	}
+	networks, err := m.mockNetworks(payload.Networks)
+	if err != nil {
+		return "", err
+	}
+	if _, err := toHealthConfig(payload.Health); err != nil {
+		return "", err
+	}
+	for _, mt := range mounts {
+		if _, ok := m.volumes[mt.Source]; !ok && !mt.IsBind() {
+			m.volumes[mt.Source] = payload.Name
+		}
+	}
+
 	id := fmt.Sprintf("mock-%d", time.Now().UnixNano())
+	m.limits[id] = payloadResources(payload)
+	stored := payload
+	stored.Auth = nil
+	stored.Mounts = mounts
+	m.payloads[id] = stored
 	m.containers[id] = common.ContainerInfo{
-		ID:      id,
-		Name:    payload.Name,
-		Image:   payload.Image,
-		Status:  "created",
-		Created: time.Now().Unix(),
+		ID:       id,
+		Name:     payload.Name,
+		Image:    payload.Image,
+		Status:   "created",
+		Created:  time.Now().Unix(),
+		Labels:   map[string]string{"perssh.managed": "true", "perssh.type": string(payload.Type)},
+		Ports:    bindingsFromMap(bindings),
+		Mounts:   mounts,
+		Networks: networks,
 	}
 	return id, nil
 }
@@ -271,6 +484,7 @@ func (m *MockManager) StartContainer(id string) error {
 	defer m.mu.Unlock()
 	if c, ok := m.containers[id]; ok {
 		c.Status = "running"
+		c.Health = m.mockHealth(id)
 		m.containers[id] = c
 		return nil
 	}
@@ -282,23 +496,36 @@ func (m *MockManager) StopContainer(id string) error {
 	defer m.mu.Unlock()
 	if c, ok := m.containers[id]; ok {
 		c.Status = "exited"
+		c.Health = ""
 		m.containers[id] = c
 		return nil
 	}
 	return fmt.Errorf("container not found")
 }
 
-func (m *MockManager) RemoveContainer(id string) error {
+func (m *MockManager) RemoveContainer(id string, removeVolumes bool) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	c := m.containers[id]
+	created := m.payloads[id].Name
 	delete(m.containers, id)
-	return nil
-}
-
-func (m *MockManager) GetLogs(id string) (string, error) {
-	return "Mock Logs for " + id, nil
-}
+	delete(m.limits, id)
+	delete(m.payloads, id)
+	if !removeVolumes {
+		return nil
+	}
 
-func (m *MockManager) SendInput(id string, data string) error {
+	inUse := make(map[string]bool)
+	for _, other := range m.containers {
+		for _, mt := range other.Mounts {
+			inUse[mt.Source] = true
+		}
+	}
+	for _, mt := range c.Mounts {
+		owner := m.volumes[mt.Source]
+		if !mt.IsBind() && !inUse[mt.Source] && owner != "" && (owner == c.Name || owner == created) {
+			delete(m.volumes, mt.Source)
+		}
+	}
 	return nil
 }
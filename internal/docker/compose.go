@@ -0,0 +1,499 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"gopkg.in/yaml.v3"
+)
+
+// serviceName matches compose service keys. Unlike container names they
+// may be a single character, the stack prefix makes the container valid.
+var serviceName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// composeFile is the subset of the Compose specification the agent
+// understands. Unknown keys are ignored, like docker compose does for
+// extensions; keys we cannot honour (build) are rejected.
+type composeFile struct {
+	Services map[string]*composeService  `yaml:"services"`
+	Networks map[string]*composeResource `yaml:"networks"`
+	Volumes  map[string]*composeResource `yaml:"volumes"`
+}
+
+type composeService struct {
+	Image       string         `yaml:"image"`
+	Build       interface{}    `yaml:"build"`
+	Command     stringOrList   `yaml:"command"`
+	Entrypoint  stringOrList   `yaml:"entrypoint"`
+	Environment mapOrList      `yaml:"environment"`
+	Labels      mapOrList      `yaml:"labels"`
+	Ports       []string       `yaml:"ports"`
+	Volumes     []string       `yaml:"volumes"`
+	DependsOn   namesOrMap     `yaml:"depends_on"`
+	Networks    namesOrMap     `yaml:"networks"`
+	Restart     string         `yaml:"restart"`
+	User        string         `yaml:"user"`
+	WorkingDir  string         `yaml:"working_dir"`
+	Healthcheck *composeHealth `yaml:"healthcheck"`
+}
+
+type composeHealth struct {
+	Test        healthTest `yaml:"test"`
+	Interval    string     `yaml:"interval"`
+	Timeout     string     `yaml:"timeout"`
+	Retries     int        `yaml:"retries"`
+	StartPeriod string     `yaml:"start_period"`
+	Disable     bool       `yaml:"disable"`
+}
+
+// healthcheck converts the compose form; nil keeps the image's check.
+func (h *composeHealth) healthcheck() *common.Healthcheck {
+	if h == nil {
+		return nil
+	}
+	if h.Disable {
+		return &common.Healthcheck{Test: []string{"NONE"}}
+	}
+	return &common.Healthcheck{
+		Test:        h.Test,
+		Interval:    h.Interval,
+		Timeout:     h.Timeout,
+		Retries:     h.Retries,
+		StartPeriod: h.StartPeriod,
+	}
+}
+
+// healthTest is a list as in the Dockerfile JSON form, or a string run
+// by the shell.
+type healthTest []string
+
+func (t *healthTest) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*t = []string{"CMD-SHELL", n.Value}
+		return nil
+	}
+	var list []string
+	if err := n.Decode(&list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// composeResource is a top-level network or volume. Null entries
+// ("db-data:") decode as nil and mean all defaults.
+type composeResource struct {
+	Driver   string `yaml:"driver"`
+	External bool   `yaml:"external"`
+	Name     string `yaml:"name"`
+}
+
+// stringOrList accepts `command: npm start` as well as a list. Strings
+// are split like a shell would, honouring quotes.
+type stringOrList []string
+
+func (s *stringOrList) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		words, err := splitWords(n.Value)
+		*s = words
+		return err
+	}
+	var list []string
+	if err := n.Decode(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// mapOrList accepts `KEY: value` mappings and `- KEY=value` lists.
+type mapOrList map[string]string
+
+func (m *mapOrList) UnmarshalYAML(n *yaml.Node) error {
+	res := make(map[string]string)
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v := n.Content[i+1]
+			if v.Tag == "!!null" {
+				res[n.Content[i].Value] = ""
+				continue
+			}
+			res[n.Content[i].Value] = v.Value
+		}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			k, v, _ := strings.Cut(item.Value, "=")
+			res[k] = v
+		}
+	default:
+		return fmt.Errorf("line %d: expected a mapping or a list", n.Line)
+	}
+	*m = res
+	return nil
+}
+
+// namesOrMap accepts a list of names or a mapping keyed by name, as used
+// by depends_on and networks. Per-entry options are ignored.
+type namesOrMap []string
+
+func (s *namesOrMap) UnmarshalYAML(n *yaml.Node) error {
+	var names []string
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(n.Content); i += 2 {
+			names = append(names, n.Content[i].Value)
+		}
+		sort.Strings(names)
+	case yaml.SequenceNode:
+		if err := n.Decode(&names); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("line %d: expected a mapping or a list", n.Line)
+	}
+	*s = names
+	return nil
+}
+
+// splitWords splits a command line on spaces, keeping quoted parts
+// together. Escapes are not interpreted.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	var quote rune
+	inWord := false
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// parseRestart converts a compose restart value such as "on-failure:3".
+// Services restart unless stopped by default.
+func parseRestart(s string) (container.RestartPolicy, error) {
+	if s == "" {
+		return container.RestartPolicy{Name: container.RestartPolicyUnlessStopped}, nil
+	}
+	name, count, hasCount := strings.Cut(s, ":")
+	p := container.RestartPolicy{Name: container.RestartPolicyMode(name)}
+	switch {
+	case p.IsOnFailure() && hasCount:
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("invalid restart retry count in %q", s)
+		}
+		p.MaximumRetryCount = n
+	case hasCount:
+		return p, fmt.Errorf("restart %q takes no retry count", name)
+	case p.IsNone() || p.IsAlways() || p.IsUnlessStopped() || p.IsOnFailure():
+	default:
+		return p, fmt.Errorf("unknown restart policy %q", s)
+	}
+	return p, nil
+}
+
+// parseCompose decodes and checks a compose file.
+func parseCompose(data []byte) (*composeFile, error) {
+	var cf composeFile
+	if err := yaml.Unmarshal(data, &cf); err != nil {
+		return nil, fmt.Errorf("invalid compose file: %w", err)
+	}
+	if len(cf.Services) == 0 {
+		return nil, fmt.Errorf("compose file has no services")
+	}
+	for name, svc := range cf.Services {
+		if svc == nil {
+			return nil, fmt.Errorf("service %s is empty", name)
+		}
+		if !serviceName.MatchString(name) {
+			return nil, fmt.Errorf("invalid service name %q", name)
+		}
+		if svc.Build != nil {
+			return nil, fmt.Errorf("service %s: build is not supported, push the image to a registry and use image", name)
+		}
+		if svc.Image == "" {
+			return nil, fmt.Errorf("service %s has no image", name)
+		}
+		if _, _, err := parsePorts(svc.Ports); err != nil {
+			return nil, fmt.Errorf("service %s: %w", name, err)
+		}
+		for _, dep := range svc.DependsOn {
+			if _, ok := cf.Services[dep]; !ok {
+				return nil, fmt.Errorf("service %s depends on unknown service %s", name, dep)
+			}
+		}
+		for _, n := range svc.Networks {
+			if _, ok := cf.Networks[n]; !ok && n != "default" {
+				return nil, fmt.Errorf("service %s uses undeclared network %s", name, n)
+			}
+		}
+		for _, v := range svc.Volumes {
+			if _, err := cf.serviceMount("check", v); err != nil {
+				return nil, fmt.Errorf("service %s: %w", name, err)
+			}
+		}
+		if _, err := toHealthConfig(svc.Healthcheck.healthcheck()); err != nil {
+			return nil, fmt.Errorf("service %s: %w", name, err)
+		}
+		if _, err := parseRestart(svc.Restart); err != nil {
+			return nil, fmt.Errorf("service %s: %w", name, err)
+		}
+	}
+	return &cf, nil
+}
+
+// order returns the services so that every service comes after the ones
+// it depends on. Independent services are sorted by name.
+func (cf *composeFile) order() ([]string, error) {
+	names := make([]string, 0, len(cf.Services))
+	for name := range cf.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var res []string
+	var visit func(name string, chain []string) error
+	visit = func(name string, chain []string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(chain, " -> "), name)
+		}
+		state[name] = visiting
+		deps := append([]string(nil), cf.Services[name].DependsOn...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if err := visit(dep, append(chain, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		res = append(res, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// images lists the distinct images of all services.
+func (cf *composeFile) images() []string {
+	seen := make(map[string]bool)
+	var res []string
+	for _, svc := range cf.Services {
+		if !seen[svc.Image] {
+			seen[svc.Image] = true
+			res = append(res, svc.Image)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// resourceName gives a stack network or volume its Docker name: an
+// explicit name, the bare key for external ones, else "<stack>_<key>".
+func resourceName(stack, key string, r *composeResource) string {
+	if r != nil && r.Name != "" {
+		return r.Name
+	}
+	if r != nil && r.External {
+		return key
+	}
+	return stack + "_" + key
+}
+
+// serviceNetworks returns the Docker names of the networks a service
+// joins. Services that list none join the stack's default network.
+func (cf *composeFile) serviceNetworks(stack string, svc *composeService) []string {
+	if len(svc.Networks) == 0 {
+		return []string{stack + "_default"}
+	}
+	var res []string
+	for _, n := range svc.Networks {
+		if n == "default" {
+			res = append(res, stack+"_default")
+			continue
+		}
+		res = append(res, resourceName(stack, n, cf.Networks[n]))
+	}
+	return res
+}
+
+// serviceMount converts a short-syntax volume entry: "/container/path"
+// (anonymous), "name:/path[:ro]" (declared volume) or "/host:/path[:ro]".
+func (cf *composeFile) serviceMount(stack, spec string) (mount.Mount, error) {
+	parts := strings.Split(spec, ":")
+	readOnly := false
+	if n := len(parts); n > 1 && (parts[n-1] == "ro" || parts[n-1] == "rw") {
+		readOnly = parts[n-1] == "ro"
+		parts = parts[:n-1]
+	}
+	switch len(parts) {
+	case 1:
+		if !path.IsAbs(parts[0]) {
+			return mount.Mount{}, fmt.Errorf("volume target %q must be an absolute path", parts[0])
+		}
+		return mount.Mount{Type: mount.TypeVolume, Target: parts[0], ReadOnly: readOnly}, nil
+	case 2:
+	default:
+		return mount.Mount{}, fmt.Errorf("invalid volume %q", spec)
+	}
+
+	src, target := parts[0], parts[1]
+	if !path.IsAbs(target) {
+		return mount.Mount{}, fmt.Errorf("volume target %q must be an absolute path", target)
+	}
+	if path.IsAbs(src) {
+		return mount.Mount{Type: mount.TypeBind, Source: src, Target: target, ReadOnly: readOnly}, nil
+	}
+	if strings.HasPrefix(src, ".") || strings.HasPrefix(src, "~") {
+		return mount.Mount{}, fmt.Errorf("relative bind mount %q is not supported, the compose file is uploaded without its directory", src)
+	}
+	r, ok := cf.Volumes[src]
+	if !ok {
+		return mount.Mount{}, fmt.Errorf("volume %s is not declared under volumes", src)
+	}
+	return mount.Mount{Type: mount.TypeVolume, Source: resourceName(stack, src, r), Target: target, ReadOnly: readOnly}, nil
+}
+
+// serviceSpec is one service converted for the Docker API.
+type serviceSpec struct {
+	name     string // Container name
+	config   *container.Config
+	host     *container.HostConfig
+	networks []string
+	hash     string // Changes whenever the container has to be recreated
+}
+
+// Labels on stack containers, networks and volumes.
+const (
+	stackLabel   = "perssh.stack"
+	serviceLabel = "perssh.service"
+	orderLabel   = "perssh.order" // Start position within the stack
+	hashLabel    = "perssh.config-hash"
+)
+
+// serviceSpec builds the container for service svc. imageID is part of
+// the hash so a re-pulled tag recreates the container.
+func (cf *composeFile) serviceSpec(stack, svc string, order int, imageID string) (serviceSpec, error) {
+	s := cf.Services[svc]
+	exposed, bindings, err := parsePorts(s.Ports)
+	if err != nil {
+		return serviceSpec{}, err
+	}
+	var mounts []mount.Mount
+	for _, v := range s.Volumes {
+		mt, err := cf.serviceMount(stack, v)
+		if err != nil {
+			return serviceSpec{}, err
+		}
+		mounts = append(mounts, mt)
+	}
+
+	env := make([]string, 0, len(s.Environment))
+	for k, v := range s.Environment {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+
+	labels := map[string]string{}
+	for k, v := range s.Labels {
+		labels[k] = v
+	}
+	labels["perssh.managed"] = "true"
+	labels["perssh.type"] = string(common.EnvTypeCompose)
+	labels[stackLabel] = stack
+	labels[serviceLabel] = svc
+
+	restart, err := parseRestart(s.Restart)
+	if err != nil {
+		return serviceSpec{}, err
+	}
+	health, err := toHealthConfig(s.Healthcheck.healthcheck())
+	if err != nil {
+		return serviceSpec{}, err
+	}
+
+	spec := serviceSpec{
+		name: stack + "-" + svc,
+		config: &container.Config{
+			Image:        s.Image,
+			Cmd:          []string(s.Command),
+			Entrypoint:   []string(s.Entrypoint),
+			Env:          env,
+			Labels:       labels,
+			User:         s.User,
+			WorkingDir:   s.WorkingDir,
+			ExposedPorts: exposed,
+			Healthcheck:  health,
+		},
+		host: &container.HostConfig{
+			RestartPolicy: restart,
+			PortBindings:  bindings,
+			Mounts:        mounts,
+		},
+		networks: cf.serviceNetworks(stack, s),
+	}
+
+	b, err := json.Marshal(struct {
+		Config   *container.Config
+		Host     *container.HostConfig
+		Networks []string
+		ImageID  string
+	}{spec.config, spec.host, spec.networks, imageID})
+	if err != nil {
+		return serviceSpec{}, err
+	}
+	sum := sha256.Sum256(b)
+	spec.hash = hex.EncodeToString(sum[:8])
+	// Added after hashing so services added before this one don't
+	// recreate it
+	labels[hashLabel] = spec.hash
+	labels[orderLabel] = fmt.Sprint(order)
+	return spec, nil
+}
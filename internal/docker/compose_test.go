@@ -0,0 +1,215 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+)
+
+const testCompose = `
+services:
+  web:
+    image: nginx:1.25
+    ports: ["8080:80"]
+    command: nginx -g "daemon off;"
+    environment:
+      - MODE=prod
+    depends_on:
+      db:
+        condition: service_started
+    networks: [front, default]
+  db:
+    image: postgres:16
+    environment:
+      POSTGRES_PASSWORD: secret
+    volumes:
+      - data:/var/lib/postgresql/data
+networks:
+  front: {}
+volumes:
+  data: {}
+`
+
+func TestParseCompose(t *testing.T) {
+	cf, err := parseCompose([]byte(testCompose))
+	if err != nil {
+		t.Fatal(err)
+	}
+	web := cf.Services["web"]
+	if got := strings.Join(web.Command, "|"); got != "nginx|-g|daemon off;" {
+		t.Errorf("Unexpected command: %q", got)
+	}
+	if web.Environment["MODE"] != "prod" || cf.Services["db"].Environment["POSTGRES_PASSWORD"] != "secret" {
+		t.Errorf("Environment not decoded: %v %v", web.Environment, cf.Services["db"].Environment)
+	}
+	if len(web.DependsOn) != 1 || web.DependsOn[0] != "db" {
+		t.Errorf("Unexpected depends_on: %v", web.DependsOn)
+	}
+
+	order, err := cf.order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(order, ",") != "db,web" {
+		t.Errorf("Unexpected order: %v", order)
+	}
+	if got := cf.serviceNetworks("shop", web); strings.Join(got, ",") != "shop_front,shop_default" {
+		t.Errorf("Unexpected networks: %v", got)
+	}
+}
+
+func TestParseComposeRejects(t *testing.T) {
+	tests := map[string]string{
+		"build":       "services:\n  a:\n    build: .\n",
+		"no image":    "services:\n  a:\n    ports: [\"80:80\"]\n",
+		"relative":    "services:\n  a:\n    image: x\n    volumes: [\"./site:/srv\"]\n",
+		"undeclared":  "services:\n  a:\n    image: x\n    volumes: [\"data:/srv\"]\n",
+		"unknown dep": "services:\n  a:\n    image: x\n    depends_on: [b]\n",
+		"network":     "services:\n  a:\n    image: x\n    networks: [back]\n",
+		"empty":       "version: \"3\"\n",
+		"restart":     "services:\n  a:\n    image: x\n    restart: sometimes\n",
+		"retries":     "services:\n  a:\n    image: x\n    restart: always:3\n",
+	}
+	for name, data := range tests {
+		if _, err := parseCompose([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseRestart(t *testing.T) {
+	tests := map[string]container.RestartPolicy{
+		"":             {Name: container.RestartPolicyUnlessStopped},
+		"no":           {Name: container.RestartPolicyDisabled},
+		"always":       {Name: container.RestartPolicyAlways},
+		"on-failure":   {Name: container.RestartPolicyOnFailure},
+		"on-failure:3": {Name: container.RestartPolicyOnFailure, MaximumRetryCount: 3},
+	}
+	for in, want := range tests {
+		if got, err := parseRestart(in); err != nil || got != want {
+			t.Errorf("%q: got %+v, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"sometimes", "on-failure:x", "on-failure:-1", "unless-stopped:2"} {
+		if _, err := parseRestart(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestComposeOrderCycle(t *testing.T) {
+	cf, err := parseCompose([]byte("services:\n  a:\n    image: x\n    depends_on: [b]\n  b:\n    image: x\n    depends_on: [a]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cf.order(); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected a cycle error, got %v", err)
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	if got := resourceName("shop", "data", nil); got != "shop_data" {
+		t.Errorf("Got %s", got)
+	}
+	if got := resourceName("shop", "data", &composeResource{Name: "pgdata"}); got != "pgdata" {
+		t.Errorf("Got %s", got)
+	}
+	if got := resourceName("shop", "proxy", &composeResource{External: true}); got != "proxy" {
+		t.Errorf("Got %s", got)
+	}
+}
+
+func TestServiceSpec(t *testing.T) {
+	cf, _ := parseCompose([]byte(testCompose))
+	spec, err := cf.serviceSpec("shop", "db", 0, "sha256:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.name != "shop-db" || spec.config.Labels[stackLabel] != "shop" || spec.config.Labels[serviceLabel] != "db" {
+		t.Errorf("Unexpected spec: %s %v", spec.name, spec.config.Labels)
+	}
+	if len(spec.host.Mounts) != 1 || spec.host.Mounts[0].Source != "shop_data" || spec.host.Mounts[0].Type != mount.TypeVolume {
+		t.Errorf("Unexpected mounts: %+v", spec.host.Mounts)
+	}
+
+	// The position in the stack does not force a recreate, a new image does
+	moved, _ := cf.serviceSpec("shop", "db", 1, "sha256:1")
+	if moved.hash != spec.hash {
+		t.Error("Hash changed with the order")
+	}
+	repulled, _ := cf.serviceSpec("shop", "db", 0, "sha256:2")
+	if repulled.hash == spec.hash {
+		t.Error("Hash ignores the image ID")
+	}
+}
+
+func TestMockStack(t *testing.T) {
+	m := NewMockManager()
+	if err := m.DeployStack("shop", []byte(testCompose), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	ids := stackIDs(m, "shop")
+	if len(ids) != 2 {
+		t.Fatalf("Expected 2 services, got %v", ids)
+	}
+
+	// Redeploying an unchanged file keeps the containers
+	if err := m.DeployStack("shop", []byte(testCompose), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	again := stackIDs(m, "shop")
+	if again["web"] != ids["web"] || again["db"] != ids["db"] {
+		t.Errorf("Unchanged services were recreated: %v -> %v", ids, again)
+	}
+
+	// Services are changed through the compose file only
+	if _, err := m.RecreateContainer(ids["web"], common.CreateEnvPayload{Image: "nginx:1.27"}, nil); err == nil || !strings.Contains(err.Error(), "redeploy") {
+		t.Errorf("Expected updating a service to be refused, got %v", err)
+	}
+	if again := stackIDs(m, "shop"); again["web"] != ids["web"] {
+		t.Errorf("Refused update changed the stack: %v", again)
+	}
+
+	// A new image recreates that service only, a dropped one is removed
+	changed := strings.Replace(testCompose, "nginx:1.25", "nginx:1.27", 1)
+	changed = strings.Replace(changed, "    depends_on:\n      db:\n        condition: service_started\n", "", 1)
+	changed = changed[:strings.Index(changed, "  db:")] + changed[strings.Index(changed, "networks:\n  front"):]
+	if err := m.DeployStack("shop", []byte(changed), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	after := stackIDs(m, "shop")
+	if len(after) != 1 || after["web"] == ids["web"] {
+		t.Errorf("Unexpected stack after change: %v", after)
+	}
+
+	if err := m.StopStack("shop"); err != nil {
+		t.Fatal(err)
+	}
+	list, _ := m.ListContainers()
+	if list[0].Status != "exited" {
+		t.Errorf("Stack not stopped: %s", list[0].Status)
+	}
+	if err := m.RemoveStack("shop", true); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := m.ListContainers(); len(list) != 0 {
+		t.Errorf("Stack not removed: %v", list)
+	}
+	if err := m.StartStack("shop"); err == nil {
+		t.Error("Expected an error for a missing stack")
+	}
+}
+
+func stackIDs(m *MockManager, stack string) map[string]string {
+	ids := make(map[string]string)
+	list, _ := m.ListContainers()
+	for _, c := range list {
+		if c.Stack == stack {
+			ids[c.Service] = c.ID
+		}
+	}
+	return ids
+}
@@ -0,0 +1,170 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ConsoleHandler receives output from an attached console.
+type ConsoleHandler func(common.ConsoleOutput)
+
+// console is a persistent attach to a container's main process. Writes are
+// serialized so rapid input cannot interleave.
+type console struct {
+	id   string
+	resp types.HijackedResponse
+	wmu  sync.Mutex
+
+	mu      sync.Mutex
+	handler ConsoleHandler
+}
+
+func (c *console) setHandler(h ConsoleHandler) {
+	c.mu.Lock()
+	c.handler = h
+	c.mu.Unlock()
+}
+
+func (c *console) emit(out common.ConsoleOutput) {
+	c.mu.Lock()
+	h := c.handler
+	c.mu.Unlock()
+	if h != nil {
+		h(out)
+	}
+}
+
+func (c *console) write(data string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.resp.Conn.Write([]byte(data))
+	return err
+}
+
+// streamWriter turns demultiplexed output into console events.
+type streamWriter struct {
+	c      *console
+	stream string
+}
+
+func (w streamWriter) Write(p []byte) (int, error) {
+	w.c.emit(common.ConsoleOutput{ID: w.c.id, Stream: w.stream, Data: string(p)})
+	return len(p), nil
+}
+
+// console returns the open attach for id, creating it if needed.
+func (m *RealManager) console(id string) (*console, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.consoles[id]; ok {
+		return c, nil
+	}
+
+	ctx := context.Background()
+	inspect, err := m.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := m.cli.ContainerAttach(ctx, id, container.AttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &console{id: id, resp: resp}
+	m.consoles[id] = c
+
+	go func() {
+		if inspect.Config != nil && inspect.Config.Tty {
+			io.Copy(streamWriter{c, "stdout"}, resp.Reader)
+		} else {
+			stdcopy.StdCopy(streamWriter{c, "stdout"}, streamWriter{c, "stderr"}, resp.Reader)
+		}
+
+		// The container stopped or the attach was closed
+		m.mu.Lock()
+		if m.consoles[id] == c {
+			delete(m.consoles, id)
+		}
+		m.mu.Unlock()
+		c.emit(common.ConsoleOutput{ID: id, Closed: true})
+	}()
+
+	return c, nil
+}
+
+// AttachConsole opens (or reuses) the persistent attach for id and streams
+// its output to handler until DetachConsole or the container stops.
+func (m *RealManager) AttachConsole(id string, handler ConsoleHandler) error {
+	c, err := m.console(id)
+	if err != nil {
+		return err
+	}
+	c.setHandler(handler)
+	return nil
+}
+
+// DetachConsole closes the attach for id.
+func (m *RealManager) DetachConsole(id string) error {
+	m.mu.Lock()
+	c, ok := m.consoles[id]
+	delete(m.consoles, id)
+	m.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	c.setHandler(nil)
+	c.resp.Close()
+	return nil
+}
+
+func (m *RealManager) SendInput(id string, data string) error {
+	c, err := m.console(id)
+	if err != nil {
+		return err
+	}
+	// Write data + newline
+	return c.write(data + "\n")
+}
+
+func (m *MockManager) AttachConsole(id string, handler ConsoleHandler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.containers[id]; !ok {
+		return fmt.Errorf("container not found")
+	}
+	m.consoles[id] = handler
+	return nil
+}
+
+func (m *MockManager) DetachConsole(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.consoles, id)
+	return nil
+}
+
+// SendInput echoes the line back on an attached console.
+func (m *MockManager) SendInput(id string, data string) error {
+	m.mu.Lock()
+	h := m.consoles[id]
+	m.mu.Unlock()
+	if h != nil {
+		h(common.ConsoleOutput{ID: id, Stream: "stdout", Data: "> " + data + "\n"})
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	execDefaultTimeout = 60 * time.Second
+	execMaxTimeout     = 30 * time.Minute // Long enough for migrations
+	execOutputMax      = 1 << 20          // Per stream, so a runaway command can't flood the RPC
+)
+
+// execMarker is set in the environment of every exec, with a value unique
+// to it, so a timed-out command and its children can be found in the
+// container.
+const execMarker = "PERSSH_EXEC"
+
+// killMarked kills every process whose environment contains $1. It runs
+// through sh in the container, as root, in the container's PID namespace.
+const killMarked = `for p in /proc/[0-9]*; do
+	case "$(cat "$p/environ" 2>/dev/null)" in *"$1"*) kill -KILL "${p#/proc/}" 2>/dev/null;; esac
+done`
+
+// cappedBuffer keeps the first max bytes written to it and drops the rest.
+// Writes never fail so the stream is drained to the end.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// checkExec validates a payload and returns its timeout.
+func checkExec(p common.ExecPayload) (time.Duration, error) {
+	if len(p.Cmd) == 0 || strings.TrimSpace(p.Cmd[0]) == "" {
+		return 0, fmt.Errorf("command is required")
+	}
+	if p.WorkDir != "" && !path.IsAbs(p.WorkDir) {
+		return 0, fmt.Errorf("working directory %q must be an absolute path", p.WorkDir)
+	}
+	for _, e := range p.Env {
+		if k, _, ok := strings.Cut(e, "="); !ok || k == "" {
+			return 0, fmt.Errorf("invalid variable %q, expected KEY=value", e)
+		}
+	}
+	timeout := execDefaultTimeout
+	if p.Timeout < 0 {
+		return 0, fmt.Errorf("timeout must not be negative")
+	} else if p.Timeout > 0 {
+		timeout = time.Duration(p.Timeout) * time.Second
+	}
+	if timeout > execMaxTimeout {
+		return 0, fmt.Errorf("timeout must be at most %s", execMaxTimeout)
+	}
+	return timeout, nil
+}
+
+// Exec runs a command in a running container and collects its output.
+// On timeout the command is killed through the engine and the partial
+// output is returned; a kill that failed is reported in KillError.
+func (m *RealManager) Exec(p common.ExecPayload) (common.ExecResult, error) {
+	timeout, err := checkExec(p)
+	if err != nil {
+		return common.ExecResult{}, err
+	}
+	ctx := context.Background()
+
+	token := make([]byte, 8)
+	rand.Read(token)
+	marker := execMarker + "=" + hex.EncodeToString(token)
+	created, err := m.cli.ContainerExecCreate(ctx, p.ID, container.ExecOptions{
+		User:         p.User,
+		WorkingDir:   p.WorkDir,
+		Env:          append(append([]string{}, p.Env...), marker),
+		Cmd:          p.Cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return common.ExecResult{}, err
+	}
+	resp, err := m.cli.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return common.ExecResult{}, err
+	}
+	defer resp.Close()
+
+	stdout := &cappedBuffer{max: execOutputMax}
+	stderr := &cappedBuffer{max: execOutputMax}
+	done := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, stderr, resp.Reader)
+		done <- err
+	}()
+
+	var res common.ExecResult
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		res.TimedOut = true
+		res.ExitCode = -1
+		if err := m.killExec(ctx, p.ID, created.ID, marker); err != nil {
+			res.KillError = err.Error()
+		}
+		// Unblocks the copy
+		resp.Close()
+		<-done
+		err = nil
+	}
+	res.Stdout, res.Stderr = stdout.buf.String(), stderr.buf.String()
+	res.Truncated = stdout.truncated || stderr.truncated
+	if err != nil {
+		return res, err
+	}
+	if res.TimedOut {
+		return res, nil
+	}
+
+	inspect, err := m.cli.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return res, err
+	}
+	res.ExitCode = inspect.ExitCode
+	return res, nil
+}
+
+// killExec stops a timed-out exec and waits for it to end. The PID the
+// engine reports belongs to its host, which may not be the agent's, and
+// the agent may not be allowed to signal it; so the command and its
+// children are killed from a second exec by their marker instead.
+func (m *RealManager) killExec(ctx context.Context, id, execID, marker string) error {
+	created, err := m.cli.ContainerExecCreate(ctx, id, container.ExecOptions{
+		User: "0",
+		Cmd:  []string{"sh", "-c", killMarked, "sh", marker},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to kill the command: %w", err)
+	}
+	if err := m.cli.ContainerExecStart(ctx, created.ID, container.ExecStartOptions{Detach: true}); err != nil {
+		return fmt.Errorf("failed to kill the command: %w", err)
+	}
+	for i := 0; i < 50; i++ {
+		inspect, err := m.cli.ContainerExecInspect(ctx, execID)
+		if err != nil {
+			return fmt.Errorf("failed to check the command: %w", err)
+		}
+		if !inspect.Running {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("the command is still running in the container")
+}
+
+// Exec answers with canned output naming the command. The mock also
+// stands in when no engine was found, so it never starts a process on the
+// host; the user, directory and timeout are only checked.
+func (m *MockManager) Exec(p common.ExecPayload) (common.ExecResult, error) {
+	if _, err := checkExec(p); err != nil {
+		return common.ExecResult{}, err
+	}
+	m.mu.Lock()
+	c, ok := m.containers[p.ID]
+	m.mu.Unlock()
+	if !ok {
+		return common.ExecResult{}, fmt.Errorf("container not found")
+	}
+	if c.Status != "running" {
+		return common.ExecResult{}, fmt.Errorf("container %s is not running", c.Name)
+	}
+	return common.ExecResult{Stdout: fmt.Sprintf("Mock exec in %s: %s\n", c.Name, strings.Join(p.Cmd, " "))}, nil
+}
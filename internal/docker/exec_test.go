@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+)
+
+func TestCheckExec(t *testing.T) {
+	timeout, err := checkExec(common.ExecPayload{Cmd: []string{"ls"}})
+	if err != nil || timeout != execDefaultTimeout {
+		t.Errorf("Expected the default timeout, got %s, %v", timeout, err)
+	}
+	timeout, _ = checkExec(common.ExecPayload{Cmd: []string{"ls"}, Timeout: 5})
+	if timeout != 5*time.Second {
+		t.Errorf("Unexpected timeout %s", timeout)
+	}
+
+	for _, p := range []common.ExecPayload{
+		{},
+		{Cmd: []string{" "}},
+		{Cmd: []string{"ls"}, WorkDir: "data"},
+		{Cmd: []string{"ls"}, Env: []string{"NOVALUE"}},
+		{Cmd: []string{"ls"}, Env: []string{"=x"}},
+		{Cmd: []string{"ls"}, Timeout: -1},
+		{Cmd: []string{"ls"}, Timeout: 3600},
+	} {
+		if _, err := checkExec(p); err == nil {
+			t.Errorf("Expected an error for %+v", p)
+		}
+	}
+}
+
+func TestKillMarked(t *testing.T) {
+	if _, err := os.Stat("/proc/self/environ"); err != nil {
+		t.Skip("no /proc")
+	}
+	marker := fmt.Sprintf("%s=test%d", execMarker, time.Now().UnixNano())
+	other := exec.Command("sleep", "30")
+	cmd := exec.Command("sleep", "30")
+	cmd.Env = append(os.Environ(), marker)
+	if err := other.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer other.Process.Kill()
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	if out, err := exec.Command("sh", "-c", killMarked, "sh", marker).CombinedOutput(); err != nil {
+		t.Fatalf("Kill script failed: %v %s", err, out)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Marked process is still running")
+	}
+	if err := other.Process.Signal(syscall.Signal(0)); err != nil {
+		t.Errorf("Unmarked process was killed: %v", err)
+	}
+}
+
+func TestCappedBuffer(t *testing.T) {
+	b := &cappedBuffer{max: 5}
+	if n, err := b.Write([]byte("abc")); n != 3 || err != nil {
+		t.Fatalf("Unexpected write result %d, %v", n, err)
+	}
+	if n, err := b.Write([]byte("defg")); n != 4 || err != nil {
+		t.Fatalf("Overflowing writes must still succeed, got %d, %v", n, err)
+	}
+	b.Write([]byte("h"))
+	if b.buf.String() != "abcde" || !b.truncated {
+		t.Errorf("Expected the first 5 bytes and truncation, got %q %v", b.buf.String(), b.truncated)
+	}
+}
+
+func TestMockExec(t *testing.T) {
+	m := NewMockManager()
+	id, _ := m.CreateContainer(common.CreateEnvPayload{Name: "web"}, nil)
+
+	if _, err := m.Exec(common.ExecPayload{ID: id, Cmd: []string{"true"}}); err == nil {
+		t.Error("Exec should fail in a stopped container")
+	}
+	m.StartContainer(id)
+
+	// Nothing may run on the host the mock stands in for
+	marker := filepath.Join(t.TempDir(), "ran")
+	res, err := m.Exec(common.ExecPayload{ID: id, Cmd: []string{"sh", "-c", "touch " + marker + "; exit 3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("The command ran on the host")
+	}
+	if res.ExitCode != 0 || res.TimedOut || !strings.Contains(res.Stdout, "Mock exec in web: sh -c touch") {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+
+	if _, err := m.Exec(common.ExecPayload{ID: id, Cmd: []string{"ls"}, Timeout: -1}); err == nil {
+		t.Error("Expected the payload to be checked")
+	}
+}
@@ -0,0 +1,163 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+)
+
+// healthOutputMax caps the probe output sent to the client; Docker keeps
+// up to 4 KiB per probe.
+const healthOutputMax = 1024
+
+// toHealthConfig converts a payload healthcheck. Nil keeps the image's
+// check.
+func toHealthConfig(h *common.Healthcheck) (*container.HealthConfig, error) {
+	if h == nil {
+		return nil, nil
+	}
+	if len(h.Test) == 0 || strings.TrimSpace(h.Test[0]) == "" {
+		return nil, fmt.Errorf("healthcheck needs a command")
+	}
+	cfg := &container.HealthConfig{Retries: h.Retries}
+	switch first := strings.ToUpper(h.Test[0]); {
+	case len(h.Test) == 1 && first == "NONE":
+		return &container.HealthConfig{Test: []string{"NONE"}}, nil
+	case first == "CMD" || first == "CMD-SHELL":
+		if len(h.Test) < 2 {
+			return nil, fmt.Errorf("healthcheck %s needs a command", first)
+		}
+		cfg.Test = append([]string{first}, h.Test[1:]...)
+	case len(h.Test) == 1:
+		cfg.Test = []string{"CMD-SHELL", h.Test[0]}
+	default:
+		cfg.Test = append([]string{"CMD"}, h.Test...)
+	}
+	if h.Retries < 0 {
+		return nil, fmt.Errorf("healthcheck retries must not be negative")
+	}
+
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"interval", h.Interval, &cfg.Interval},
+		{"timeout", h.Timeout, &cfg.Timeout},
+		{"start period", h.StartPeriod, &cfg.StartPeriod},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid healthcheck %s %q", d.name, d.value)
+		}
+		// Docker rejects anything between zero and one millisecond
+		if v < time.Millisecond {
+			return nil, fmt.Errorf("healthcheck %s must be at least 1ms", d.name)
+		}
+		*d.dst = v
+	}
+	return cfg, nil
+}
+
+// fromHealthConfig is the inverse of toHealthConfig, used to rebuild the
+// payload of older containers.
+func fromHealthConfig(cfg *container.HealthConfig) *common.Healthcheck {
+	if cfg == nil || len(cfg.Test) == 0 {
+		return nil
+	}
+	h := &common.Healthcheck{Test: cfg.Test, Retries: cfg.Retries}
+	if cfg.Test[0] == "CMD-SHELL" && len(cfg.Test) == 2 {
+		h.Test = cfg.Test[1:]
+	}
+	for _, d := range []struct {
+		src time.Duration
+		dst *string
+	}{
+		{cfg.Interval, &h.Interval},
+		{cfg.Timeout, &h.Timeout},
+		{cfg.StartPeriod, &h.StartPeriod},
+	} {
+		if d.src > 0 {
+			*d.dst = d.src.String()
+		}
+	}
+	return h
+}
+
+// healthFromStatus reads the health state from the status text of a
+// container list, e.g. "Up 2 minutes (unhealthy)". Listing does not
+// return it otherwise and inspecting every container would be slow.
+func healthFromStatus(status string) string {
+	switch {
+	case strings.HasSuffix(status, "(health: starting)"):
+		return "starting"
+	case strings.HasSuffix(status, "(unhealthy)"):
+		return "unhealthy"
+	case strings.HasSuffix(status, "(healthy)"):
+		return "healthy"
+	}
+	return ""
+}
+
+func toHealthInfo(h *container.Health) common.HealthInfo {
+	if h == nil || h.Status == container.NoHealthcheck {
+		return common.HealthInfo{}
+	}
+	info := common.HealthInfo{Status: string(h.Status), FailingStreak: h.FailingStreak}
+	if n := len(h.Log); n > 0 {
+		last := h.Log[n-1]
+		info.LastExitCode = last.ExitCode
+		info.LastOutput = strings.TrimSpace(last.Output)
+		if len(info.LastOutput) > healthOutputMax {
+			info.LastOutput = info.LastOutput[len(info.LastOutput)-healthOutputMax:]
+		}
+		if !last.End.IsZero() {
+			info.LastCheck = last.End.Unix()
+		}
+	}
+	return info
+}
+
+// GetHealth reports the health of an environment. Without a healthcheck
+// the status is empty.
+func (m *RealManager) GetHealth(id string) (common.HealthInfo, error) {
+	inspect, err := m.cli.ContainerInspect(context.Background(), id)
+	if err != nil {
+		return common.HealthInfo{}, err
+	}
+	if inspect.State == nil {
+		return common.HealthInfo{}, nil
+	}
+	return toHealthInfo(inspect.State.Health), nil
+}
+
+// mockHealth is the health a started mock container reports: healthy if
+// its payload has a check. The caller holds the lock.
+func (m *MockManager) mockHealth(id string) string {
+	if h := m.payloads[id].Health; h != nil && !strings.EqualFold(h.Test[0], "NONE") {
+		return "healthy"
+	}
+	return ""
+}
+
+func (m *MockManager) GetHealth(id string) (common.HealthInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.containers[id]
+	if !ok {
+		return common.HealthInfo{}, fmt.Errorf("container not found")
+	}
+	info := common.HealthInfo{Status: c.Health}
+	if c.Health == "healthy" {
+		info.LastOutput = "mock probe ok"
+		info.LastCheck = time.Now().Unix()
+	}
+	return info, nil
+}
@@ -0,0 +1,137 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestToHealthConfig(t *testing.T) {
+	cfg, err := toHealthConfig(&common.Healthcheck{Test: []string{"curl -f http://localhost/"}, Interval: "30s", Retries: 3, StartPeriod: "1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cfg.Test, "|") != "CMD-SHELL|curl -f http://localhost/" || cfg.Interval != 30*time.Second || cfg.StartPeriod != time.Minute || cfg.Retries != 3 {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+
+	cfg, _ = toHealthConfig(&common.Healthcheck{Test: []string{"pg_isready", "-U", "postgres"}})
+	if strings.Join(cfg.Test, "|") != "CMD|pg_isready|-U|postgres" {
+		t.Errorf("Unexpected exec form: %v", cfg.Test)
+	}
+	cfg, _ = toHealthConfig(&common.Healthcheck{Test: []string{"none"}})
+	if strings.Join(cfg.Test, "|") != "NONE" {
+		t.Errorf("Unexpected disabled form: %v", cfg.Test)
+	}
+	if cfg, _ := toHealthConfig(nil); cfg != nil {
+		t.Error("Nil should keep the image's check")
+	}
+
+	for _, h := range []common.Healthcheck{
+		{},
+		{Test: []string{"CMD"}},
+		{Test: []string{"true"}, Interval: "soon"},
+		{Test: []string{"true"}, Timeout: "1us"},
+		{Test: []string{"true"}, Retries: -1},
+	} {
+		if _, err := toHealthConfig(&h); err == nil {
+			t.Errorf("Expected an error for %+v", h)
+		}
+	}
+}
+
+func TestFromHealthConfig(t *testing.T) {
+	h := &common.Healthcheck{Test: []string{"mc-health"}, Interval: "30s", Timeout: "10s", Retries: 3, StartPeriod: "5m0s"}
+	cfg, _ := toHealthConfig(h)
+	back := fromHealthConfig(cfg)
+	if strings.Join(back.Test, "|") != "mc-health" || back.Interval != "30s" || back.Timeout != "10s" || back.StartPeriod != "5m0s" || back.Retries != 3 {
+		t.Errorf("Round trip changed the check: %+v", back)
+	}
+}
+
+func TestHealthFromStatus(t *testing.T) {
+	tests := map[string]string{
+		"Up 2 minutes (healthy)":          "healthy",
+		"Up 5 seconds (health: starting)": "starting",
+		"Up 1 hour (unhealthy)":           "unhealthy",
+		"Up 3 days":                       "",
+		"Exited (0) 2 hours ago":          "",
+	}
+	for status, want := range tests {
+		if got := healthFromStatus(status); got != want {
+			t.Errorf("%q: got %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestToHealthInfo(t *testing.T) {
+	end := time.Unix(1700000000, 0)
+	info := toHealthInfo(&container.Health{
+		Status:        container.Unhealthy,
+		FailingStreak: 4,
+		Log: []*container.HealthcheckResult{
+			{ExitCode: 0, Output: "ok"},
+			{ExitCode: 1, Output: "connection refused\n", End: end},
+		},
+	})
+	if info.Status != "unhealthy" || info.FailingStreak != 4 || info.LastExitCode != 1 || info.LastOutput != "connection refused" || info.LastCheck != end.Unix() {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+	if info := toHealthInfo(&container.Health{Status: container.NoHealthcheck}); info.Status != "" {
+		t.Errorf("Expected no status, got %+v", info)
+	}
+}
+
+func TestComposeHealthcheck(t *testing.T) {
+	cf, err := parseCompose([]byte(`
+services:
+  web:
+    image: nginx
+    healthcheck:
+      test: curl -f http://localhost/
+      interval: 10s
+  db:
+    image: postgres
+    healthcheck:
+      test: ["CMD", "pg_isready"]
+  cache:
+    image: redis
+    healthcheck:
+      disable: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"web": "CMD-SHELL|curl -f http://localhost/", "db": "CMD|pg_isready", "cache": "NONE"}
+	for svc, test := range want {
+		spec, err := cf.serviceSpec("shop", svc, 0, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(spec.config.Healthcheck.Test, "|"); got != test {
+			t.Errorf("%s: got %s, want %s", svc, got, test)
+		}
+	}
+}
+
+func TestMockHealth(t *testing.T) {
+	m := NewMockManager()
+	id, _ := m.CreateContainer(common.CreateEnvPayload{Name: "web", Health: &common.Healthcheck{Test: []string{"true"}}}, nil)
+	plain, _ := m.CreateContainer(common.CreateEnvPayload{Name: "plain"}, nil)
+	m.StartContainer(id)
+	m.StartContainer(plain)
+
+	if h, _ := m.GetHealth(id); h.Status != "healthy" {
+		t.Errorf("Expected healthy, got %+v", h)
+	}
+	if h, _ := m.GetHealth(plain); h.Status != "" {
+		t.Errorf("Expected no health, got %+v", h)
+	}
+	m.StopContainer(id)
+	if h, _ := m.GetHealth(id); h.Status != "" {
+		t.Errorf("Stopped container reports %+v", h)
+	}
+}
@@ -0,0 +1,291 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+)
+
+// shortImageID trims an image ID to the 12 characters docker images shows.
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return id
+}
+
+// sortImages puts the largest images first, since the screen is mostly
+// used to find what takes up space.
+func sortImages(list []common.ImageInfo) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Size != list[j].Size {
+			return list[i].Size > list[j].Size
+		}
+		return list[i].ID < list[j].ID
+	})
+}
+
+// toDiskUsage summarizes a df response the way docker system df does.
+func toDiskUsage(du types.DiskUsage) common.DiskUsage {
+	var res common.DiskUsage
+
+	var used int64
+	res.Images.Total = len(du.Images)
+	res.Images.Size = du.LayersSize
+	for _, img := range du.Images {
+		if img.Containers > 0 {
+			res.Images.Active++
+			// Shared layers are not freed by removing the other images
+			if img.SharedSize > 0 {
+				used += img.Size - img.SharedSize
+			} else {
+				used += img.Size
+			}
+		}
+	}
+	res.Images.Reclaimable = max(du.LayersSize-used, 0)
+
+	res.Containers.Total = len(du.Containers)
+	for _, c := range du.Containers {
+		res.Containers.Size += c.SizeRw
+		if c.State == container.StateRunning {
+			res.Containers.Active++
+		} else {
+			res.Containers.Reclaimable += c.SizeRw
+		}
+	}
+
+	res.Volumes.Total = len(du.Volumes)
+	for _, v := range du.Volumes {
+		if v.UsageData == nil || v.UsageData.Size < 0 {
+			continue
+		}
+		res.Volumes.Size += v.UsageData.Size
+		if v.UsageData.RefCount > 0 {
+			res.Volumes.Active++
+		} else {
+			res.Volumes.Reclaimable += v.UsageData.Size
+		}
+	}
+
+	res.BuildCache.Total = len(du.BuildCache)
+	for _, r := range du.BuildCache {
+		res.BuildCache.Size += r.Size
+		if r.InUse {
+			res.BuildCache.Active++
+		} else if !r.Shared {
+			res.BuildCache.Reclaimable += r.Size
+		}
+	}
+	return res
+}
+
+// ListImages reports the images on the host with the containers using
+// them. Intermediate build layers are left out, like docker images.
+func (m *RealManager) ListImages() ([]common.ImageInfo, error) {
+	ctx := context.Background()
+	images, err := m.cli.ImageList(ctx, image.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	containers, err := m.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string][]string)
+	for _, c := range containers {
+		name := c.ID[:12]
+		if len(c.Names) > 0 {
+			name = c.Names[0][1:]
+		}
+		users[c.ImageID] = append(users[c.ImageID], name)
+	}
+
+	res := make([]common.ImageInfo, 0, len(images))
+	for _, img := range images {
+		info := common.ImageInfo{
+			ID:      shortImageID(img.ID),
+			Size:    img.Size,
+			Created: img.Created,
+			UsedBy:  users[img.ID],
+		}
+		for _, t := range img.RepoTags {
+			if t != "<none>:<none>" {
+				info.Tags = append(info.Tags, t)
+			}
+		}
+		info.Dangling = len(info.Tags) == 0
+		res = append(res, info)
+	}
+	sortImages(res)
+	return res, nil
+}
+
+// RemoveImage removes an image, or untags it if ref is one of several
+// tags. Images used by a container are refused unless forced, and running
+// containers keep theirs even then.
+func (m *RealManager) RemoveImage(ref string, force bool) error {
+	_, err := m.cli.ImageRemove(context.Background(), ref, image.RemoveOptions{Force: force, PruneChildren: true})
+	return err
+}
+
+// PruneImages removes dangling images, or every unused image with all.
+func (m *RealManager) PruneImages(all bool) (common.PruneResult, error) {
+	args := filters.NewArgs()
+	if all {
+		args.Add("dangling", "false")
+	}
+	report, err := m.cli.ImagesPrune(context.Background(), args)
+	if err != nil {
+		return common.PruneResult{}, err
+	}
+	res := common.PruneResult{Reclaimed: report.SpaceReclaimed}
+	for _, d := range report.ImagesDeleted {
+		if d.Deleted != "" {
+			res.Deleted++
+		}
+	}
+	return res, nil
+}
+
+// DiskUsage reports the space used by images, containers, volumes and the
+// build cache. Like ListVolumes it can be slow on large hosts.
+func (m *RealManager) DiskUsage() (common.DiskUsage, error) {
+	du, err := m.cli.DiskUsage(context.Background(), types.DiskUsageOptions{})
+	if err != nil {
+		return common.DiskUsage{}, err
+	}
+	return toDiskUsage(du), nil
+}
+
+// mockImageSize is what every mock image weighs.
+const mockImageSize = 100 << 20
+
+// mockImageRef adds the implicit tag, so "nginx" and "nginx:latest" are
+// the same mock image.
+func mockImageRef(ref string) string {
+	if imageRepo(ref) == ref {
+		return ref + ":latest"
+	}
+	return ref
+}
+
+func mockImageID(ref string) string {
+	sum := sha256.Sum256([]byte(ref))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// mockImageUsers maps image refs to the containers using them. The caller
+// holds the lock.
+func (m *MockManager) mockImageUsers() map[string][]string {
+	users := make(map[string][]string)
+	for _, c := range m.containers {
+		if c.Image != "" {
+			ref := mockImageRef(c.Image)
+			users[ref] = append(users[ref], c.Name)
+		}
+	}
+	return users
+}
+
+func (m *MockManager) ListImages() ([]common.ImageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	users := m.mockImageUsers()
+	var res []common.ImageInfo
+	for ref, pulled := range m.images {
+		res = append(res, common.ImageInfo{
+			ID:      mockImageID(ref),
+			Tags:    []string{ref},
+			Size:    mockImageSize,
+			Created: pulled.Unix(),
+			UsedBy:  users[ref],
+		})
+	}
+	sortImages(res)
+	return res, nil
+}
+
+func (m *MockManager) RemoveImage(ref string, force bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for r := range m.images {
+		if r == mockImageRef(ref) || mockImageID(r) == ref {
+			if users := m.mockImageUsers()[r]; len(users) > 0 && !force {
+				return fmt.Errorf("image %s is in use by %s", ref, strings.Join(users, ", "))
+			}
+			delete(m.images, r)
+			return nil
+		}
+	}
+	return fmt.Errorf("no such image: %s", ref)
+}
+
+// PruneImages removes unused mock images with all; the mock has no
+// dangling ones.
+func (m *MockManager) PruneImages(all bool) (common.PruneResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res common.PruneResult
+	if !all {
+		return res, nil
+	}
+	users := m.mockImageUsers()
+	for ref := range m.images {
+		if len(users[ref]) == 0 {
+			delete(m.images, ref)
+			res.Deleted++
+			res.Reclaimed += mockImageSize
+		}
+	}
+	return res, nil
+}
+
+func (m *MockManager) DiskUsage() (common.DiskUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res common.DiskUsage
+	users := m.mockImageUsers()
+	for ref := range m.images {
+		res.Images.Total++
+		res.Images.Size += mockImageSize
+		if len(users[ref]) > 0 {
+			res.Images.Active++
+		} else {
+			res.Images.Reclaimable += mockImageSize
+		}
+	}
+	for _, c := range m.containers {
+		res.Containers.Total++
+		if c.Status == "running" {
+			res.Containers.Active++
+		}
+	}
+	res.Volumes.Total = len(m.volumes)
+	return res, nil
+}
+
+// addMockImage records a pulled image.
+func (m *MockManager) addMockImage(ref string) {
+	if ref == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ref = mockImageRef(ref)
+	if _, ok := m.images[ref]; !ok {
+		m.images[ref] = time.Now()
+	}
+}
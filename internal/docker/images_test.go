@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/volume"
+)
+
+func TestShortImageID(t *testing.T) {
+	if id := shortImageID("sha256:0123456789abcdef0123"); id != "0123456789ab" {
+		t.Errorf("Unexpected ID %q", id)
+	}
+	if id := shortImageID("abc"); id != "abc" {
+		t.Errorf("Unexpected ID %q", id)
+	}
+}
+
+func TestToDiskUsage(t *testing.T) {
+	du := types.DiskUsage{
+		LayersSize: 1000,
+		Images: []*image.Summary{
+			{Size: 600, SharedSize: 100, Containers: 1},
+			{Size: 300, SharedSize: -1, Containers: 0},
+			{Size: 200, SharedSize: 100, Containers: 0},
+		},
+		Containers: []*container.Summary{
+			{State: container.StateRunning, SizeRw: 10},
+			{State: container.StateExited, SizeRw: 30},
+		},
+		Volumes: []*volume.Volume{
+			{UsageData: &volume.UsageData{Size: 50, RefCount: 1}},
+			{UsageData: &volume.UsageData{Size: 70, RefCount: 0}},
+			{UsageData: &volume.UsageData{Size: -1, RefCount: -1}},
+		},
+		BuildCache: []*build.CacheRecord{
+			{Size: 5, InUse: true},
+			{Size: 7, Shared: true},
+			{Size: 11},
+		},
+	}
+	res := toDiskUsage(du)
+	want := common.DiskUsage{
+		Images:     common.UsageEntry{Total: 3, Active: 1, Size: 1000, Reclaimable: 500},
+		Containers: common.UsageEntry{Total: 2, Active: 1, Size: 40, Reclaimable: 30},
+		Volumes:    common.UsageEntry{Total: 3, Active: 1, Size: 120, Reclaimable: 70},
+		BuildCache: common.UsageEntry{Total: 3, Active: 1, Size: 23, Reclaimable: 11},
+	}
+	if res != want {
+		t.Errorf("Unexpected usage:\n%+v\nwant\n%+v", res, want)
+	}
+}
+
+func TestMockImages(t *testing.T) {
+	m := NewMockManager()
+	m.CreateContainer(common.CreateEnvPayload{Name: "web", Image: "nginx"}, nil)
+	m.PullImage("postgres:16", nil, nil)
+
+	list, _ := m.ListImages()
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 images, got %+v", list)
+	}
+	var nginx common.ImageInfo
+	for _, img := range list {
+		if img.Tags[0] == "nginx:latest" {
+			nginx = img
+		}
+	}
+	if len(nginx.UsedBy) != 1 || nginx.UsedBy[0] != "web" {
+		t.Errorf("Expected nginx used by web: %+v", nginx)
+	}
+
+	if err := m.RemoveImage("nginx", false); err == nil {
+		t.Error("Expected an error for an image in use")
+	}
+	du, _ := m.DiskUsage()
+	if du.Images.Total != 2 || du.Images.Active != 1 || du.Images.Reclaimable != mockImageSize {
+		t.Errorf("Unexpected usage: %+v", du.Images)
+	}
+
+	res, _ := m.PruneImages(false)
+	if res.Deleted != 0 {
+		t.Errorf("The mock has no dangling images, pruned %+v", res)
+	}
+	res, _ = m.PruneImages(true)
+	if res.Deleted != 1 || res.Reclaimed != mockImageSize {
+		t.Errorf("Expected postgres pruned, got %+v", res)
+	}
+	if err := m.RemoveImage(nginx.ID, true); err != nil {
+		t.Errorf("Forced removal by ID failed: %v", err)
+	}
+	if list, _ := m.ListImages(); len(list) != 0 {
+		t.Errorf("Expected no images left, got %+v", list)
+	}
+}
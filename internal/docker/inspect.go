@@ -0,0 +1,225 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+)
+
+var (
+	// secretWords mark a variable as secret anywhere in its name, e.g.
+	// RCON_PASSWORD or GITHUB_TOKEN.
+	secretWords = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "CREDENTIAL", "PRIVATE"}
+	// secretParts only count as a whole part of the name, so DB_PASS is
+	// masked but PASSTHROUGH and KEYBOARD are not.
+	secretParts = map[string]bool{"PASS": true, "PW": true, "PWD": true, "KEY": true, "APIKEY": true, "AUTH": true}
+	// urlPassword matches the password of credentials in a URL, e.g.
+	// DATABASE_URL=postgres://app:hunter2@db/app.
+	urlPassword = regexp.MustCompile(`(://[^/:@\s]+:)([^@/\s]+)(@)`)
+)
+
+// isSecret reports whether a variable name looks like it holds a secret.
+func isSecret(key string) bool {
+	key = strings.ToUpper(key)
+	for _, w := range secretWords {
+		if strings.Contains(key, w) {
+			return true
+		}
+	}
+	for _, p := range strings.FieldsFunc(key, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		if secretParts[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// maskEnv returns the variables sorted, with secret values and passwords
+// in URLs replaced by common.MaskedValue.
+func maskEnv(env []string) []string {
+	res := make([]string, 0, len(env))
+	for _, e := range env {
+		k, v, ok := strings.Cut(e, "=")
+		switch {
+		case !ok:
+		case isSecret(k) && v != "":
+			e = k + "=" + common.MaskedValue
+		default:
+			e = k + "=" + urlPassword.ReplaceAllString(v, "${1}"+common.MaskedValue+"${3}")
+		}
+		res = append(res, e)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// imageRepo strips the tag or digest from an image reference. A colon
+// before the last slash belongs to a registry port.
+func imageRepo(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+	return ref
+}
+
+// pickDigest returns the repo digest of the repository ref was pulled
+// from. An image tagged in several repositories has one per repository.
+func pickDigest(ref string, digests []string) string {
+	repo := imageRepo(ref)
+	for _, d := range digests {
+		if imageRepo(d) == repo {
+			return d
+		}
+	}
+	if len(digests) > 0 {
+		return digests[0]
+	}
+	return ""
+}
+
+// unixTime converts an inspect timestamp; Docker reports the zero time
+// for containers that never started or stopped.
+func unixTime(s string) int64 {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil || t.Year() <= 1 {
+		return 0
+	}
+	return t.Unix()
+}
+
+func restartPolicy(p container.RestartPolicy) string {
+	name := string(p.Name)
+	if name == "" {
+		name = "no"
+	}
+	if p.IsOnFailure() && p.MaximumRetryCount > 0 {
+		name = fmt.Sprintf("%s:%d", name, p.MaximumRetryCount)
+	}
+	return name
+}
+
+// toEnvInspect converts inspect data. The image digest needs an image
+// inspect and is filled in by the caller.
+func toEnvInspect(c container.InspectResponse) common.EnvInspect {
+	info := common.EnvInspect{Mounts: fromMountPoints(c.Mounts)}
+	if b := c.ContainerJSONBase; b != nil {
+		info.ID = b.ID
+		if len(info.ID) > 12 {
+			info.ID = info.ID[:12]
+		}
+		info.Name = strings.TrimPrefix(b.Name, "/")
+		info.ImageID = b.Image
+		info.Created = unixTime(b.Created)
+		info.RestartCount = b.RestartCount
+		if s := b.State; s != nil {
+			info.Status = string(s.Status)
+			info.ExitCode = s.ExitCode
+			info.StartedAt = unixTime(s.StartedAt)
+			info.FinishedAt = unixTime(s.FinishedAt)
+		}
+		if hc := b.HostConfig; hc != nil {
+			info.RestartPolicy = restartPolicy(hc.RestartPolicy)
+			info.Resources = fromHostConfig(hc)
+			// Stopped containers have no live port map
+			info.Ports = bindingsFromMap(hc.PortBindings)
+		}
+	}
+	if cfg := c.Config; cfg != nil {
+		info.Image = cfg.Image
+		info.Entrypoint = cfg.Entrypoint
+		info.Command = cfg.Cmd
+		info.WorkDir = cfg.WorkingDir
+		info.User = cfg.User
+		info.Env = maskEnv(cfg.Env)
+		for k, v := range cfg.Labels {
+			if k == payloadLabel {
+				continue
+			}
+			if info.Labels == nil {
+				info.Labels = make(map[string]string)
+			}
+			info.Labels[k] = v
+		}
+	}
+	if ns := c.NetworkSettings; ns != nil {
+		if ports := bindingsFromMap(ns.Ports); len(ports) > 0 {
+			info.Ports = ports
+		}
+		for name, ep := range ns.Networks {
+			n := common.NetworkEndpoint{Name: name}
+			if ep != nil {
+				n.IP = ep.IPAddress
+				for _, a := range ep.Aliases {
+					if a != info.Name && a != info.ID {
+						n.Aliases = append(n.Aliases, a)
+					}
+				}
+			}
+			info.Networks = append(info.Networks, n)
+		}
+		sort.Slice(info.Networks, func(i, j int) bool { return info.Networks[i].Name < info.Networks[j].Name })
+	}
+	sort.Slice(info.Ports, func(i, j int) bool {
+		a, b := info.Ports[i], info.Ports[j]
+		if a.ContainerPort != b.ContainerPort {
+			return a.ContainerPort < b.ContainerPort
+		}
+		return a.HostPort < b.HostPort
+	})
+	return info
+}
+
+// InspectEnv reports the full configuration and state of an environment.
+func (m *RealManager) InspectEnv(id string) (common.EnvInspect, error) {
+	ctx := context.Background()
+	c, err := m.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return common.EnvInspect{}, err
+	}
+	info := toEnvInspect(c)
+	// The digest is a nicety; a removed or dangling image still inspects
+	if img, err := m.cli.ImageInspect(ctx, info.ImageID); err == nil {
+		info.ImageDigest = pickDigest(info.Image, img.RepoDigests)
+	}
+	return info, nil
+}
+
+func (m *MockManager) InspectEnv(id string) (common.EnvInspect, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.containers[id]
+	if !ok {
+		return common.EnvInspect{}, fmt.Errorf("container not found")
+	}
+	p := m.payloads[id]
+	info := common.EnvInspect{
+		ID:            c.ID,
+		Name:          c.Name,
+		Image:         c.Image,
+		ImageID:       "sha256:mock",
+		Created:       c.Created,
+		Env:           maskEnv(mapToEnvList(p.EnvVars)),
+		Ports:         c.Ports,
+		Mounts:        c.Mounts,
+		RestartPolicy: "unless-stopped",
+		Resources:     m.limits[id],
+		Labels:        c.Labels,
+		Status:        c.Status,
+	}
+	for _, n := range p.Networks {
+		info.Networks = append(info.Networks, common.NetworkEndpoint{Name: n.Name, IP: "172.18.0.2", Aliases: n.Aliases})
+	}
+	if c.Status == "running" {
+		info.StartedAt = c.Created
+	}
+	return info, nil
+}
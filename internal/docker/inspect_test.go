@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestIsSecret(t *testing.T) {
+	for _, k := range []string{"RCON_PASSWORD", "POSTGRES_PASSWORD", "db_pass", "GITHUB_TOKEN", "AWS_SECRET_ACCESS_KEY", "API_KEY", "api-key", "SSH_PRIVATE", "BASIC_AUTH"} {
+		if !isSecret(k) {
+			t.Errorf("%s should be masked", k)
+		}
+	}
+	for _, k := range []string{"PATH", "EULA", "PASSTHROUGH", "KEYBOARD", "MEMORY", "AUTHOR"} {
+		if isSecret(k) {
+			t.Errorf("%s should not be masked", k)
+		}
+	}
+}
+
+func TestMaskEnv(t *testing.T) {
+	env := maskEnv([]string{
+		"RCON_PASSWORD=hunter2",
+		"DATABASE_URL=postgres://app:hunter2@db:5432/app",
+		"EMPTY_TOKEN=",
+		"EULA=TRUE",
+		"NOVALUE",
+	})
+	want := []string{
+		"DATABASE_URL=postgres://app:" + common.MaskedValue + "@db:5432/app",
+		"EMPTY_TOKEN=",
+		"EULA=TRUE",
+		"NOVALUE",
+		"RCON_PASSWORD=" + common.MaskedValue,
+	}
+	if strings.Join(env, "|") != strings.Join(want, "|") {
+		t.Errorf("Unexpected env:\n%v\nwant\n%v", env, want)
+	}
+}
+
+func TestPickDigest(t *testing.T) {
+	tests := map[string]string{
+		"nginx:1.25":                 "nginx",
+		"nginx":                      "nginx",
+		"localhost:5000/app:2":       "localhost:5000/app",
+		"localhost:5000/app":         "localhost:5000/app",
+		"ghcr.io/org/app@sha256:abc": "ghcr.io/org/app",
+	}
+	for ref, want := range tests {
+		if got := imageRepo(ref); got != want {
+			t.Errorf("imageRepo(%q) = %q, want %q", ref, got, want)
+		}
+	}
+
+	digests := []string{"mirror.example.com/nginx@sha256:aaa", "nginx@sha256:bbb"}
+	if d := pickDigest("nginx:latest", digests); d != "nginx@sha256:bbb" {
+		t.Errorf("Picked %q", d)
+	}
+	if d := pickDigest("other", digests); d != digests[0] {
+		t.Errorf("Expected the first digest as fallback, got %q", d)
+	}
+	if d := pickDigest("nginx", nil); d != "" {
+		t.Errorf("Expected no digest for a local image, got %q", d)
+	}
+}
+
+func TestToEnvInspect(t *testing.T) {
+	c := container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:           "0123456789abcdef",
+			Name:         "/web",
+			Image:        "sha256:img",
+			Created:      "2026-01-02T10:00:00.5Z",
+			RestartCount: 2,
+			State: &container.State{
+				Status:     container.StateExited,
+				ExitCode:   137,
+				StartedAt:  "2026-01-02T10:00:01Z",
+				FinishedAt: "2026-01-02T11:00:00Z",
+			},
+			HostConfig: &container.HostConfig{
+				RestartPolicy: container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: 3},
+				PortBindings:  nat.PortMap{"80/tcp": {{HostPort: "8080"}}},
+				Resources:     container.Resources{Memory: 1 << 30},
+			},
+		},
+		Config: &container.Config{
+			Image:      "nginx:1.25",
+			Entrypoint: []string{"/docker-entrypoint.sh"},
+			Cmd:        []string{"nginx", "-g", "daemon off;"},
+			Env:        []string{"API_TOKEN=abc", "MODE=prod"},
+			Labels:     map[string]string{"perssh.managed": "true", payloadLabel: `{"env_vars":{"API_TOKEN":"abc"}}`},
+		},
+		NetworkSettings: &container.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"shop":   {IPAddress: "172.18.0.2", Aliases: []string{"web", "0123456789ab", "frontend"}},
+				"bridge": {IPAddress: "172.17.0.2"},
+			},
+		},
+	}
+	info := toEnvInspect(c)
+	if info.ID != "0123456789ab" || info.Name != "web" || info.Image != "nginx:1.25" || info.ImageID != "sha256:img" {
+		t.Errorf("Unexpected identity: %+v", info)
+	}
+	if info.Status != "exited" || info.ExitCode != 137 || info.RestartCount != 2 || info.StartedAt == 0 || info.FinishedAt <= info.StartedAt {
+		t.Errorf("Unexpected state: %+v", info)
+	}
+	if info.RestartPolicy != "on-failure:3" || info.Resources.Memory != "1g" {
+		t.Errorf("Unexpected host config: %q %+v", info.RestartPolicy, info.Resources)
+	}
+	if len(info.Ports) != 1 || info.Ports[0].HostPort != 8080 {
+		t.Errorf("Stopped containers should report their configured ports: %+v", info.Ports)
+	}
+	if strings.Join(info.Env, "|") != "API_TOKEN="+common.MaskedValue+"|MODE=prod" {
+		t.Errorf("Unexpected env %v", info.Env)
+	}
+	if _, ok := info.Labels[payloadLabel]; ok || info.Labels["perssh.managed"] != "true" {
+		t.Errorf("The payload label must be left out: %v", info.Labels)
+	}
+	if len(info.Networks) != 2 || info.Networks[0].Name != "bridge" || info.Networks[1].IP != "172.18.0.2" || strings.Join(info.Networks[1].Aliases, ",") != "frontend" {
+		t.Errorf("Unexpected networks: %+v", info.Networks)
+	}
+	if strings.Join(info.Command, " ") != "nginx -g daemon off;" {
+		t.Errorf("Unexpected command %v", info.Command)
+	}
+}
+
+func TestMockInspectEnv(t *testing.T) {
+	m := NewMockManager()
+	id, _ := m.CreateContainer(common.CreateEnvPayload{
+		Name:    "mc",
+		Image:   "itzg/minecraft-server",
+		Ports:   []string{"25565:25565"},
+		EnvVars: map[string]string{"RCON_PASSWORD": "hunter2", "EULA": "TRUE"},
+	}, nil)
+	info, err := m.InspectEnv(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "mc" || len(info.Ports) != 1 || strings.Join(info.Env, "|") != "EULA=TRUE|RCON_PASSWORD="+common.MaskedValue {
+		t.Errorf("Unexpected inspect: %+v", info)
+	}
+	if _, err := m.InspectEnv("missing"); err == nil {
+		t.Error("Expected an error for an unknown container")
+	}
+}
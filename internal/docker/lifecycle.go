@@ -0,0 +1,170 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// validName matches the container names Docker accepts.
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// signals are the names accepted for kill, without the SIG prefix.
+var signals = map[string]int{
+	"HUP": 1, "INT": 2, "QUIT": 3, "KILL": 9, "USR1": 10, "USR2": 12,
+	"TERM": 15, "CONT": 18, "STOP": 19, "WINCH": 28,
+}
+
+// normalizeSignal turns "hup", "SIGHUP" or "1" into "SIGHUP". Other
+// numbers are passed through for Docker to check. Empty means SIGKILL, like
+// docker kill.
+func normalizeSignal(sig string) (string, error) {
+	s := strings.ToUpper(strings.TrimSpace(sig))
+	if s == "" {
+		return "SIGKILL", nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 || n > 64 {
+			return "", fmt.Errorf("invalid signal %q", sig)
+		}
+		for name, num := range signals {
+			if num == n {
+				return "SIG" + name, nil
+			}
+		}
+		return s, nil
+	}
+	s = strings.TrimPrefix(s, "SIG")
+	if _, ok := signals[s]; !ok {
+		return "", fmt.Errorf("unknown signal %q", sig)
+	}
+	return "SIG" + s, nil
+}
+
+// stopsContainer reports whether a signal's default action ends the main
+// process. Only used by the mock.
+func stopsContainer(sig string) bool {
+	switch sig {
+	case "SIGKILL", "SIGTERM", "SIGINT", "SIGQUIT":
+		return true
+	}
+	return false
+}
+
+// RestartContainer stops and starts a container. A timeout of 0 uses the
+// container's own stop timeout (10 seconds unless set at creation).
+func (m *RealManager) RestartContainer(id string, timeout int) error {
+	opts := container.StopOptions{}
+	if timeout > 0 {
+		opts.Timeout = &timeout
+	}
+	return m.cli.ContainerRestart(context.Background(), id, opts)
+}
+
+func (m *RealManager) PauseContainer(id string) error {
+	return m.cli.ContainerPause(context.Background(), id)
+}
+
+func (m *RealManager) UnpauseContainer(id string) error {
+	return m.cli.ContainerUnpause(context.Background(), id)
+}
+
+func (m *RealManager) RenameContainer(id, name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid name %q: use letters, digits, '_', '.' and '-'", name)
+	}
+	return m.cli.ContainerRename(context.Background(), id, name)
+}
+
+// KillContainer sends a signal to the main process, e.g. SIGHUP to reload
+// a configuration without restarting.
+func (m *RealManager) KillContainer(id, signal string) error {
+	sig, err := normalizeSignal(signal)
+	if err != nil {
+		return err
+	}
+	return m.cli.ContainerKill(context.Background(), id, sig)
+}
+
+func (m *MockManager) RestartContainer(id string, timeout int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.containers[id]
+	if !ok {
+		return fmt.Errorf("container not found")
+	}
+	c.Status = "running"
+	c.Health = m.mockHealth(id)
+	m.containers[id] = c
+	return nil
+}
+
+func (m *MockManager) PauseContainer(id string) error {
+	return m.setStatus(id, "running", "paused")
+}
+
+func (m *MockManager) UnpauseContainer(id string) error {
+	return m.setStatus(id, "paused", "running")
+}
+
+// setStatus moves a mock container between states, failing like Docker
+// when it is not in the expected one.
+func (m *MockManager) setStatus(id, from, to string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.containers[id]
+	if !ok {
+		return fmt.Errorf("container not found")
+	}
+	if c.Status != from {
+		return fmt.Errorf("container %s is not %s", c.Name, from)
+	}
+	c.Status = to
+	m.containers[id] = c
+	return nil
+}
+
+func (m *MockManager) RenameContainer(id, name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid name %q: use letters, digits, '_', '.' and '-'", name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.containers[id]
+	if !ok {
+		return fmt.Errorf("container not found")
+	}
+	for other, o := range m.containers {
+		if other != id && o.Name == name {
+			return fmt.Errorf("name %q is already in use", name)
+		}
+	}
+	c.Name = name
+	m.containers[id] = c
+	return nil
+}
+
+func (m *MockManager) KillContainer(id, signal string) error {
+	sig, err := normalizeSignal(signal)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.containers[id]
+	if !ok {
+		return fmt.Errorf("container not found")
+	}
+	if c.Status != "running" {
+		return fmt.Errorf("container %s is not running", c.Name)
+	}
+	if stopsContainer(sig) {
+		c.Status = "exited"
+		m.containers[id] = c
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+)
+
+func TestNormalizeSignal(t *testing.T) {
+	for in, want := range map[string]string{
+		"":        "SIGKILL",
+		"hup":     "SIGHUP",
+		"SIGHUP":  "SIGHUP",
+		" term ":  "SIGTERM",
+		"1":       "SIGHUP",
+		"9":       "SIGKILL",
+		"34":      "34",
+		"sigusr1": "SIGUSR1",
+	} {
+		got, err := normalizeSignal(in)
+		if err != nil || got != want {
+			t.Errorf("normalizeSignal(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"BOGUS", "0", "65", "SIG"} {
+		if _, err := normalizeSignal(in); err == nil {
+			t.Errorf("Expected error for %q", in)
+		}
+	}
+}
+
+func TestMockLifecycle(t *testing.T) {
+	m := NewMockManager()
+	id, _ := m.CreateContainer(common.CreateEnvPayload{Name: "mc"}, nil)
+	other, _ := m.CreateContainer(common.CreateEnvPayload{Name: "web"}, nil)
+
+	status := func() string {
+		list, _ := m.ListContainers()
+		for _, c := range list {
+			if c.ID == id {
+				return c.Status
+			}
+		}
+		return ""
+	}
+
+	if err := m.PauseContainer(id); err == nil {
+		t.Error("Expected pausing a stopped container to fail")
+	}
+	if err := m.RestartContainer(id, 5); err != nil || status() != "running" {
+		t.Fatalf("Restart failed: %v (%s)", err, status())
+	}
+	if err := m.PauseContainer(id); err != nil || status() != "paused" {
+		t.Fatalf("Pause failed: %v (%s)", err, status())
+	}
+	if err := m.UnpauseContainer(id); err != nil || status() != "running" {
+		t.Fatalf("Unpause failed: %v (%s)", err, status())
+	}
+
+	// SIGHUP reloads, SIGTERM stops
+	if err := m.KillContainer(id, "HUP"); err != nil || status() != "running" {
+		t.Errorf("HUP: %v (%s)", err, status())
+	}
+	if err := m.KillContainer(id, "SIGTERM"); err != nil || status() != "exited" {
+		t.Errorf("TERM: %v (%s)", err, status())
+	}
+	if err := m.KillContainer(id, "HUP"); err == nil {
+		t.Error("Expected signalling a stopped container to fail")
+	}
+
+	if err := m.RenameContainer(id, "web"); err == nil {
+		t.Error("Expected rename to a taken name to fail")
+	}
+	if err := m.RenameContainer(id, "-bad"); err == nil {
+		t.Error("Expected invalid name to fail")
+	}
+	if err := m.RenameContainer(id, "survival"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if err := m.RenameContainer(other, "mc"); err != nil {
+		t.Errorf("Expected old name to be free, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+)
+
+// payloadResources folds the legacy RamLimit field into the limits.
+func payloadResources(p common.CreateEnvPayload) common.Resources {
+	r := p.Resources
+	if r.Memory == "" {
+		r.Memory = p.RamLimit
+	}
+	return r
+}
+
+// toHostResources converts payload limits into the HostConfig fields and the
+// storage options used at creation.
+func toHostResources(r common.Resources) (container.Resources, map[string]string, error) {
+	var res container.Resources
+
+	if r.Memory != "" {
+		n, err := units.RAMInBytes(r.Memory)
+		if err != nil {
+			return res, nil, fmt.Errorf("invalid memory limit %q: %w", r.Memory, err)
+		}
+		res.Memory = n
+	}
+	if r.MemorySwap != "" {
+		if r.MemorySwap == "-1" {
+			res.MemorySwap = -1
+		} else {
+			n, err := units.RAMInBytes(r.MemorySwap)
+			if err != nil {
+				return res, nil, fmt.Errorf("invalid swap limit %q: %w", r.MemorySwap, err)
+			}
+			if res.Memory > 0 && n < res.Memory {
+				return res, nil, fmt.Errorf("swap limit %s must not be below the memory limit %s", r.MemorySwap, r.Memory)
+			}
+			res.MemorySwap = n
+		}
+	}
+	if r.CPUs < 0 {
+		return res, nil, fmt.Errorf("invalid CPU quota %v", r.CPUs)
+	}
+	res.NanoCPUs = int64(r.CPUs * 1e9)
+	res.CpusetCpus = r.CpusetCPUs
+	if r.PidsLimit != 0 {
+		pids := r.PidsLimit
+		res.PidsLimit = &pids
+	}
+
+	var storage map[string]string
+	if r.StorageSize != "" {
+		if _, err := units.FromHumanSize(r.StorageSize); err != nil {
+			return res, nil, fmt.Errorf("invalid storage size %q: %w", r.StorageSize, err)
+		}
+		storage = map[string]string{"size": r.StorageSize}
+	}
+	return res, storage, nil
+}
+
+// fromHostConfig reports the effective limits of a container.
+func fromHostConfig(hc *container.HostConfig) common.Resources {
+	var r common.Resources
+	if hc == nil {
+		return r
+	}
+	if hc.Memory > 0 {
+		r.Memory = formatSize(hc.Memory)
+	}
+	switch {
+	case hc.MemorySwap < 0:
+		r.MemorySwap = "-1"
+	case hc.MemorySwap > 0:
+		r.MemorySwap = formatSize(hc.MemorySwap)
+	}
+	switch {
+	case hc.NanoCPUs > 0:
+		r.CPUs = float64(hc.NanoCPUs) / 1e9
+	case hc.CPUQuota > 0 && hc.CPUPeriod > 0:
+		// Set by docker run --cpu-quota rather than by us
+		r.CPUs = float64(hc.CPUQuota) / float64(hc.CPUPeriod)
+	}
+	r.CpusetCPUs = hc.CpusetCpus
+	if hc.PidsLimit != nil && *hc.PidsLimit > 0 {
+		r.PidsLimit = *hc.PidsLimit
+	}
+	r.StorageSize = hc.StorageOpt["size"]
+	return r
+}
+
+// formatSize prints bytes in the largest unit that keeps the value exact,
+// so the result parses back to the same number.
+func formatSize(n int64) string {
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"g", units.GiB}, {"m", units.MiB}, {"k", units.KiB}} {
+		if n%u.size == 0 {
+			return strconv.FormatInt(n/u.size, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func (m *RealManager) GetLimits(id string) (common.Resources, error) {
+	inspect, err := m.cli.ContainerInspect(context.Background(), id)
+	if err != nil {
+		return common.Resources{}, err
+	}
+	return fromHostConfig(inspect.HostConfig), nil
+}
+
+// UpdateLimits changes limits in place. Only the fields set in r are sent.
+func (m *RealManager) UpdateLimits(id string, r common.Resources) error {
+	if r.StorageSize != "" {
+		return fmt.Errorf("storage size can only be set when the environment is created")
+	}
+	res, _, err := toHostResources(r)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if res.Memory > 0 && r.MemorySwap == "" {
+		// Docker rejects a memory limit above the current swap limit, so
+		// keep the amount of swap and move the total with the memory.
+		inspect, err := m.cli.ContainerInspect(ctx, id)
+		if err != nil {
+			return err
+		}
+		if hc := inspect.HostConfig; hc != nil && hc.MemorySwap > 0 && hc.Memory > 0 {
+			res.MemorySwap = hc.MemorySwap - hc.Memory + res.Memory
+		}
+	}
+
+	_, err = m.cli.ContainerUpdate(ctx, id, container.UpdateConfig{Resources: res})
+	return err
+}
+
+func (m *MockManager) GetLimits(id string) (common.Resources, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.containers[id]; !ok {
+		return common.Resources{}, fmt.Errorf("container not found")
+	}
+	return m.limits[id], nil
+}
+
+func (m *MockManager) UpdateLimits(id string, r common.Resources) error {
+	if r.StorageSize != "" {
+		return fmt.Errorf("storage size can only be set when the environment is created")
+	}
+	if _, _, err := toHostResources(r); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.containers[id]; !ok {
+		return fmt.Errorf("container not found")
+	}
+	cur := m.limits[id]
+	if r.Memory != "" {
+		cur.Memory = r.Memory
+	}
+	if r.MemorySwap != "" {
+		cur.MemorySwap = r.MemorySwap
+	}
+	if r.CPUs != 0 {
+		cur.CPUs = r.CPUs
+	}
+	if r.CpusetCPUs != "" {
+		cur.CpusetCPUs = r.CpusetCPUs
+	}
+	if r.PidsLimit > 0 {
+		cur.PidsLimit = r.PidsLimit
+	} else if r.PidsLimit < 0 {
+		cur.PidsLimit = 0
+	}
+	m.limits[id] = cur
+	return nil
+}
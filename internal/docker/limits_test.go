@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestResourcesRoundTrip(t *testing.T) {
+	in := common.Resources{
+		Memory:      "2g",
+		MemorySwap:  "3g",
+		CPUs:        1.5,
+		CpusetCPUs:  "0-1",
+		PidsLimit:   512,
+		StorageSize: "10G",
+	}
+	res, storage, err := toHostResources(in)
+	if err != nil {
+		t.Fatalf("toHostResources failed: %v", err)
+	}
+	if res.Memory != 2<<30 || res.MemorySwap != 3<<30 || res.NanoCPUs != 1500000000 {
+		t.Errorf("Unexpected resources: %+v", res)
+	}
+	if res.PidsLimit == nil || *res.PidsLimit != 512 {
+		t.Errorf("Unexpected pids limit: %v", res.PidsLimit)
+	}
+
+	out := fromHostConfig(&container.HostConfig{Resources: res, StorageOpt: storage})
+	if out != in {
+		t.Errorf("Roundtrip mismatch: %+v vs %+v", out, in)
+	}
+}
+
+func TestResourcesInvalid(t *testing.T) {
+	for _, r := range []common.Resources{
+		{Memory: "lots"},
+		{Memory: "2g", MemorySwap: "1g"},
+		{CPUs: -1},
+		{StorageSize: "big"},
+	} {
+		if _, _, err := toHostResources(r); err == nil {
+			t.Errorf("Expected error for %+v", r)
+		}
+	}
+}
+
+func TestPayloadResourcesUsesRamLimit(t *testing.T) {
+	r := payloadResources(common.CreateEnvPayload{RamLimit: "2G"})
+	if r.Memory != "2G" {
+		t.Errorf("Expected RamLimit to become the memory limit, got %q", r.Memory)
+	}
+	r = payloadResources(common.CreateEnvPayload{RamLimit: "2G", Resources: common.Resources{Memory: "4g"}})
+	if r.Memory != "4g" {
+		t.Errorf("Expected explicit memory limit to win, got %q", r.Memory)
+	}
+}
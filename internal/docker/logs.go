@@ -0,0 +1,176 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	timetypes "github.com/docker/docker/api/types/time"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	logsDefaultTail = 100
+	maxLogTail      = 10000 // Keeps a reply to a few MB; page back for more
+
+	mockLogLines    = 500
+	mockLogInterval = 10 * time.Second
+)
+
+// parseLogTime reads a since/until value the way the Docker CLI does: an
+// RFC 3339 time, a Unix timestamp or a duration before now such as "10m".
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	ts, err := timetypes.GetTimestamp(value, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	sec, nsec, err := timetypes.ParseTimestamps(ts, 0)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+// logsOptions checks a query and turns it into options for the engine,
+// along with the number of lines asked for. Relative times are resolved
+// against now here, so they mean the agent's clock rather than the engine's.
+func logsOptions(q common.LogsQuery, now time.Time) (container.LogsOptions, int, error) {
+	tail := q.Tail
+	if tail == 0 {
+		tail = logsDefaultTail
+	}
+	if tail < 0 || tail > maxLogTail {
+		return container.LogsOptions{}, 0, fmt.Errorf("tail must be between 1 and %d lines", maxLogTail)
+	}
+
+	// Timestamps are always fetched so the reply can say where it starts
+	opts := container.LogsOptions{
+		ShowStdout: q.Stdout || !q.Stderr,
+		ShowStderr: q.Stderr || !q.Stdout,
+		Timestamps: true,
+		Tail:       strconv.Itoa(tail),
+	}
+	var since, until time.Time
+	if q.Since != "" {
+		t, err := parseLogTime(q.Since, now)
+		if err != nil {
+			return container.LogsOptions{}, 0, fmt.Errorf("invalid since %q: %w", q.Since, err)
+		}
+		since = t
+		opts.Since = fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+	}
+	if q.Until != "" {
+		t, err := parseLogTime(q.Until, now)
+		if err != nil {
+			return container.LogsOptions{}, 0, fmt.Errorf("invalid until %q: %w", q.Until, err)
+		}
+		until = t
+		opts.Until = fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		return container.LogsOptions{}, 0, fmt.Errorf("until is before since")
+	}
+	return opts, tail, nil
+}
+
+// toLogsResult builds the reply from timestamped log text holding at most
+// tail lines, dropping the timestamps unless they were asked for.
+func toLogsResult(text string, timestamps bool, tail int) common.LogsResult {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	res := common.LogsResult{AtStart: len(lines) < tail}
+	if len(lines) > 0 {
+		res.Oldest, _, _ = strings.Cut(lines[0], " ")
+	}
+	if timestamps {
+		res.Logs = text
+		return res
+	}
+	var b strings.Builder
+	for _, l := range lines {
+		if _, msg, ok := strings.Cut(l, " "); ok {
+			l = msg
+		}
+		b.WriteString(l)
+	}
+	res.Logs = b.String()
+	return res
+}
+
+func (m *RealManager) GetLogs(q common.LogsQuery) (common.LogsResult, error) {
+	opts, tail, err := logsOptions(q, time.Now())
+	if err != nil {
+		return common.LogsResult{}, err
+	}
+	ctx := context.Background()
+	inspect, err := m.cli.ContainerInspect(ctx, q.ID)
+	if err != nil {
+		return common.LogsResult{}, err
+	}
+	out, err := m.cli.ContainerLogs(ctx, q.ID, opts)
+	if err != nil {
+		return common.LogsResult{}, err
+	}
+	defer out.Close()
+
+	// Without a TTY the engine frames each stream; keep them interleaved
+	var buf bytes.Buffer
+	if inspect.Config != nil && inspect.Config.Tty {
+		_, err = io.Copy(&buf, out)
+	} else {
+		_, err = stdcopy.StdCopy(&buf, &buf, out)
+	}
+	if err != nil {
+		return common.LogsResult{}, err
+	}
+	return toLogsResult(buf.String(), q.Timestamps, tail), nil
+}
+
+// GetLogs serves a fixed history of lines leading up to the creation of
+// the container, every tenth one on stderr, so queries and paging can be
+// tried without an engine.
+func (m *MockManager) GetLogs(q common.LogsQuery) (common.LogsResult, error) {
+	opts, tail, err := logsOptions(q, time.Now())
+	if err != nil {
+		return common.LogsResult{}, err
+	}
+	m.mu.Lock()
+	c, ok := m.containers[q.ID]
+	m.mu.Unlock()
+	if !ok {
+		return common.LogsResult{}, fmt.Errorf("container not found")
+	}
+	since, _ := parseLogTime(opts.Since, time.Now())
+	until, _ := parseLogTime(opts.Until, time.Now())
+
+	first := time.Unix(c.Created, 0).Add(-mockLogInterval * (mockLogLines - 1)).UTC()
+	var lines []string
+	for i := range mockLogLines {
+		t := first.Add(mockLogInterval * time.Duration(i))
+		stderr := i%10 == 9
+		switch {
+		case opts.Since != "" && t.Before(since), opts.Until != "" && t.After(until):
+			continue
+		case stderr && !opts.ShowStderr, !stderr && !opts.ShowStdout:
+			continue
+		}
+		stream := "stdout"
+		if stderr {
+			stream = "stderr"
+		}
+		lines = append(lines, fmt.Sprintf("%s Mock %s line %d of %s\n", t.Format(time.RFC3339Nano), stream, i+1, c.Name))
+	}
+	if len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	return toLogsResult(strings.Join(lines, ""), q.Timestamps, tail), nil
+}
@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func TestLogsOptions(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	opts, tail, err := logsOptions(common.LogsQuery{ID: "x"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tail != logsDefaultTail || opts.Tail != "100" || !opts.ShowStdout || !opts.ShowStderr || !opts.Timestamps {
+		t.Errorf("Unexpected defaults: %d %+v", tail, opts)
+	}
+
+	opts, _, _ = logsOptions(common.LogsQuery{ID: "x", Stderr: true, Since: "10m", Until: "2025-01-02T03:00:00Z"}, now)
+	if opts.ShowStdout || !opts.ShowStderr {
+		t.Errorf("Expected stderr only: %+v", opts)
+	}
+	if opts.Since != "1735786445.000000000" || opts.Until != "1735786800.000000000" {
+		t.Errorf("Unexpected times %q %q", opts.Since, opts.Until)
+	}
+
+	for _, q := range []common.LogsQuery{
+		{Tail: -1},
+		{Tail: maxLogTail + 1},
+		{Since: "yesterday"},
+		{Until: "5x"},
+		{Since: "2025-01-02T03:00:00Z", Until: "2025-01-02T02:00:00Z"},
+	} {
+		if _, _, err := logsOptions(q, now); err == nil {
+			t.Errorf("Expected an error for %+v", q)
+		}
+	}
+}
+
+func TestToLogsResult(t *testing.T) {
+	var framed bytes.Buffer
+	stdcopy.NewStdWriter(&framed, stdcopy.Stdout).Write([]byte("2025-01-02T03:04:05.1Z first\n"))
+	stdcopy.NewStdWriter(&framed, stdcopy.Stderr).Write([]byte("2025-01-02T03:04:06.2Z second\n"))
+	var text bytes.Buffer
+	if _, err := stdcopy.StdCopy(&text, &text, &framed); err != nil {
+		t.Fatal(err)
+	}
+
+	res := toLogsResult(text.String(), false, 2)
+	if res.Logs != "first\nsecond\n" || res.Oldest != "2025-01-02T03:04:05.1Z" || res.AtStart {
+		t.Errorf("Unexpected result %+v", res)
+	}
+	res = toLogsResult(text.String(), true, 3)
+	if !strings.HasPrefix(res.Logs, "2025-01-02T03:04:05.1Z first") || !res.AtStart {
+		t.Errorf("Expected timestamps and the start of the logs: %+v", res)
+	}
+	if res := toLogsResult("", false, 100); res.Logs != "" || res.Oldest != "" || !res.AtStart {
+		t.Errorf("Unexpected result for no logs: %+v", res)
+	}
+}
+
+func TestMockLogsPaging(t *testing.T) {
+	m := NewMockManager()
+	id, _ := m.CreateContainer(common.CreateEnvPayload{Name: "web"}, nil)
+
+	if _, err := m.GetLogs(common.LogsQuery{ID: "missing"}); err == nil {
+		t.Error("Expected an error for a missing container")
+	}
+
+	res, err := m.GetLogs(common.LogsQuery{ID: id, Tail: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(res.Logs, "\n"); n != 200 || res.AtStart {
+		t.Fatalf("Expected 200 lines, got %d, %+v", n, res.AtStart)
+	}
+	if !strings.HasSuffix(res.Logs, "line 500 of web\n") {
+		t.Errorf("Expected the newest line last: %q", res.Logs[len(res.Logs)-40:])
+	}
+
+	// Page back until the start, as the viewer does
+	total := 200
+	for !res.AtStart {
+		oldest, err := time.Parse(time.RFC3339Nano, res.Oldest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		until := oldest.Add(-time.Nanosecond).Format(time.RFC3339Nano)
+		res, err = m.GetLogs(common.LogsQuery{ID: id, Tail: 200, Until: until})
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += strings.Count(res.Logs, "\n")
+	}
+	if total != mockLogLines || !strings.HasPrefix(res.Logs, "Mock stdout line 1 of web") {
+		t.Errorf("Expected all %d lines once, got %d", mockLogLines, total)
+	}
+
+	res, _ = m.GetLogs(common.LogsQuery{ID: id, Stderr: true, Tail: maxLogTail})
+	if n := strings.Count(res.Logs, "\n"); n != mockLogLines/10 || strings.Contains(res.Logs, "stdout") {
+		t.Errorf("Expected only the %d stderr lines, got %d", mockLogLines/10, n)
+	}
+}
@@ -0,0 +1,350 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+// builtinNetworks exist on every host. Containers end up on bridge by
+// default, or podman under Podman; none of them support aliases.
+var builtinNetworks = map[string]bool{"bridge": true, "host": true, "none": true, "podman": true}
+
+// validAlias matches DNS names usable as network aliases.
+var validAlias = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9.-]*$`)
+
+// checkNetworks validates the networks of a payload. Only user-defined
+// networks can be joined; an empty list keeps the default bridge.
+func checkNetworks(list []common.NetworkAttachment) error {
+	seen := make(map[string]bool)
+	for _, n := range list {
+		if n.Name == "" {
+			return fmt.Errorf("network name is required")
+		}
+		if builtinNetworks[n.Name] {
+			return fmt.Errorf("network %s is built in, leave networks empty to use the default bridge", n.Name)
+		}
+		if seen[n.Name] {
+			return fmt.Errorf("network %s is listed twice", n.Name)
+		}
+		seen[n.Name] = true
+		for _, a := range n.Aliases {
+			if !validAlias.MatchString(a) {
+				return fmt.Errorf("invalid alias %q for network %s", a, n.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func endpointFor(n common.NetworkAttachment) *network.EndpointSettings {
+	return &network.EndpointSettings{Aliases: n.Aliases}
+}
+
+// networkingFor joins the first network at creation; the API only takes
+// several endpoints from version 1.44 on. The rest go through
+// connectNetworks once the container exists.
+func networkingFor(list []common.NetworkAttachment, host *container.HostConfig) *network.NetworkingConfig {
+	if len(list) == 0 {
+		return &network.NetworkingConfig{}
+	}
+	host.NetworkMode = container.NetworkMode(list[0].Name)
+	return &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+		list[0].Name: endpointFor(list[0]),
+	}}
+}
+
+func (m *RealManager) connectNetworks(ctx context.Context, id string, list []common.NetworkAttachment) error {
+	for _, n := range list {
+		if err := m.cli.NetworkConnect(ctx, n.Name, id, endpointFor(n)); err != nil {
+			return fmt.Errorf("failed to join network %s: %w", n.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkNetworksExist fails for networks that have not been created yet.
+// Unlike volumes they are not created on demand, so a typo does not
+// silently isolate an environment.
+func (m *RealManager) checkNetworksExist(ctx context.Context, list []common.NetworkAttachment) error {
+	for _, n := range list {
+		if _, err := m.cli.NetworkInspect(ctx, n.Name, network.InspectOptions{}); cerrdefs.IsNotFound(err) {
+			return fmt.Errorf("network %s does not exist", n.Name)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summaryNetworks lists the networks of a listed container by name.
+func summaryNetworks(s *container.NetworkSettingsSummary) []string {
+	if s == nil {
+		return nil
+	}
+	var names []string
+	for name := range s.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// inspectNetworks recovers the user-defined networks of a container. The
+// container's own name and short ID, which Docker adds as aliases on
+// older versions, are dropped.
+func inspectNetworks(c container.InspectResponse) []common.NetworkAttachment {
+	if c.NetworkSettings == nil {
+		return nil
+	}
+	own := map[string]bool{strings.TrimPrefix(c.Name, "/"): true}
+	if c.ContainerJSONBase != nil && len(c.ID) >= 12 {
+		own[c.ID[:12]] = true
+	}
+	var res []common.NetworkAttachment
+	for name, ep := range c.NetworkSettings.Networks {
+		if builtinNetworks[name] {
+			continue
+		}
+		n := common.NetworkAttachment{Name: name}
+		if ep != nil {
+			for _, a := range ep.Aliases {
+				if !own[a] {
+					n.Aliases = append(n.Aliases, a)
+				}
+			}
+		}
+		res = append(res, n)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+func checkCreateNetwork(p common.CreateNetworkPayload) error {
+	if !validName.MatchString(p.Name) {
+		return fmt.Errorf("invalid network name %q", p.Name)
+	}
+	if builtinNetworks[p.Name] {
+		return fmt.Errorf("network %s is built in", p.Name)
+	}
+	if p.Subnet != "" {
+		if _, _, err := net.ParseCIDR(p.Subnet); err != nil {
+			return fmt.Errorf("invalid subnet %q", p.Subnet)
+		}
+	}
+	return nil
+}
+
+func sortNetworks(list []common.NetworkInfo) {
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+}
+
+// networkUsers maps network names to the containers attached to them.
+// Network inspect only lists running endpoints; the container list keeps
+// the networks of stopped containers too.
+func (m *RealManager) networkUsers(ctx context.Context) (map[string][]string, error) {
+	containers, err := m.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+	users := make(map[string][]string)
+	for _, c := range containers {
+		name := c.ID[:12]
+		if len(c.Names) > 0 {
+			name = c.Names[0][1:]
+		}
+		for _, n := range summaryNetworks(c.NetworkSettings) {
+			users[n] = append(users[n], name)
+		}
+	}
+	return users, nil
+}
+
+// ListNetworks reports every network with the containers attached to it.
+func (m *RealManager) ListNetworks() ([]common.NetworkInfo, error) {
+	ctx := context.Background()
+	networks, err := m.cli.NetworkList(ctx, network.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	users, err := m.networkUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []common.NetworkInfo
+	for _, n := range networks {
+		info := common.NetworkInfo{
+			ID:       n.ID[:12],
+			Name:     n.Name,
+			Driver:   n.Driver,
+			Internal: n.Internal,
+			Builtin:  builtinNetworks[n.Name],
+			Managed:  n.Labels["perssh.managed"] == "true",
+			UsedBy:   users[n.Name],
+		}
+		if len(n.IPAM.Config) > 0 {
+			info.Subnet = n.IPAM.Config[0].Subnet
+		}
+		res = append(res, info)
+	}
+	sortNetworks(res)
+	return res, nil
+}
+
+// CreateNetwork creates a user-defined network and returns its ID.
+func (m *RealManager) CreateNetwork(p common.CreateNetworkPayload) (string, error) {
+	if err := checkCreateNetwork(p); err != nil {
+		return "", err
+	}
+	opts := network.CreateOptions{
+		Driver:   p.Driver,
+		Internal: p.Internal,
+		Labels:   map[string]string{"perssh.managed": "true"},
+	}
+	if opts.Driver == "" {
+		opts.Driver = "bridge"
+	}
+	if p.Subnet != "" {
+		opts.IPAM = &network.IPAM{Config: []network.IPAMConfig{{Subnet: p.Subnet}}}
+	}
+	resp, err := m.cli.NetworkCreate(context.Background(), p.Name, opts)
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// RemoveNetwork removes a network no container, running or stopped, is
+// attached to.
+func (m *RealManager) RemoveNetwork(name string) error {
+	if builtinNetworks[name] {
+		return fmt.Errorf("network %s is built in", name)
+	}
+	ctx := context.Background()
+	n, err := m.cli.NetworkInspect(ctx, name, network.InspectOptions{})
+	if err != nil {
+		return err
+	}
+	users, err := m.networkUsers(ctx)
+	if err != nil {
+		return err
+	}
+	if names := users[n.Name]; len(names) > 0 {
+		sort.Strings(names)
+		return fmt.Errorf("network %s is used by %s", name, strings.Join(names, ", "))
+	}
+	return m.cli.NetworkRemove(ctx, n.ID)
+}
+
+// mockNetworks checks that the networks of a payload exist. The caller
+// holds the lock.
+func (m *MockManager) mockNetworks(list []common.NetworkAttachment) ([]string, error) {
+	if err := checkNetworks(list); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, n := range list {
+		if _, ok := m.networks[n.Name]; !ok {
+			return nil, fmt.Errorf("network %s does not exist", n.Name)
+		}
+		names = append(names, n.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m *MockManager) ListNetworks() ([]common.NetworkInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := make(map[string][]string)
+	for _, c := range m.containers {
+		networks := c.Networks
+		if len(networks) == 0 {
+			networks = []string{"bridge"}
+		}
+		for _, n := range networks {
+			users[n] = append(users[n], c.Name)
+		}
+	}
+
+	var res []common.NetworkInfo
+	for name := range builtinNetworks {
+		if name == "podman" {
+			// The mock plays Docker
+			continue
+		}
+		driver := name
+		if name == "none" {
+			driver = "null"
+		}
+		res = append(res, common.NetworkInfo{ID: name, Name: name, Driver: driver, Builtin: true, UsedBy: users[name]})
+	}
+	for _, n := range m.networks {
+		n.UsedBy = users[n.Name]
+		res = append(res, n)
+	}
+	sortNetworks(res)
+	for i := range res {
+		sort.Strings(res[i].UsedBy)
+	}
+	return res, nil
+}
+
+func (m *MockManager) CreateNetwork(p common.CreateNetworkPayload) (string, error) {
+	if err := checkCreateNetwork(p); err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.networks[p.Name]; ok {
+		return "", fmt.Errorf("network with name %s already exists", p.Name)
+	}
+	if p.Driver == "" {
+		p.Driver = "bridge"
+	}
+	id := "net-" + p.Name
+	m.networks[p.Name] = common.NetworkInfo{
+		ID:       id,
+		Name:     p.Name,
+		Driver:   p.Driver,
+		Subnet:   p.Subnet,
+		Internal: p.Internal,
+		Managed:  true,
+	}
+	return id, nil
+}
+
+func (m *MockManager) RemoveNetwork(name string) error {
+	if builtinNetworks[name] {
+		return fmt.Errorf("network %s is built in", name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.networks[name]; !ok {
+		return fmt.Errorf("network %s not found", name)
+	}
+	var users []string
+	for _, c := range m.containers {
+		for _, n := range c.Networks {
+			if n == name {
+				users = append(users, c.Name)
+			}
+		}
+	}
+	if len(users) > 0 {
+		sort.Strings(users)
+		return fmt.Errorf("network %s is used by %s", name, strings.Join(users, ", "))
+	}
+	delete(m.networks, name)
+	return nil
+}
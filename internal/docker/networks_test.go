@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+func TestCheckNetworks(t *testing.T) {
+	valid := []common.NetworkAttachment{{Name: "shop", Aliases: []string{"db", "postgres.local"}}, {Name: "public"}}
+	if err := checkNetworks(valid); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	invalid := map[string][]common.NetworkAttachment{
+		"builtin":   {{Name: "bridge"}},
+		"duplicate": {{Name: "shop"}, {Name: "shop"}},
+		"alias":     {{Name: "shop", Aliases: []string{"-db"}}},
+		"empty":     {{Aliases: []string{"db"}}},
+	}
+	for name, list := range invalid {
+		if err := checkNetworks(list); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestInspectNetworks(t *testing.T) {
+	c := container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "0123456789abcdef", Name: "/web"},
+		NetworkSettings: &container.NetworkSettings{Networks: map[string]*network.EndpointSettings{
+			"bridge": {},
+			"shop":   {Aliases: []string{"web", "0123456789ab", "www"}},
+		}},
+	}
+	got := inspectNetworks(c)
+	if len(got) != 1 || got[0].Name != "shop" || strings.Join(got[0].Aliases, ",") != "www" {
+		t.Errorf("Unexpected networks: %+v", got)
+	}
+}
+
+func TestMockNetworks(t *testing.T) {
+	m := NewMockManager()
+	payload := common.CreateEnvPayload{Name: "db", Networks: []common.NetworkAttachment{{Name: "shop", Aliases: []string{"postgres"}}}}
+	if _, err := m.CreateContainer(payload, nil); err == nil {
+		t.Error("Expected an error for a missing network")
+	}
+
+	if _, err := m.CreateNetwork(common.CreateNetworkPayload{Name: "shop", Subnet: "10.10.0.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.CreateNetwork(common.CreateNetworkPayload{Name: "other", Subnet: "10.10.0.0"}); err == nil {
+		t.Error("Expected an error for an invalid subnet")
+	}
+	if _, err := m.CreateContainer(payload, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	list, _ := m.ListNetworks()
+	var shop *common.NetworkInfo
+	for i := range list {
+		if list[i].Name == "shop" {
+			shop = &list[i]
+		}
+	}
+	if shop == nil || !shop.Managed || len(shop.UsedBy) != 1 || shop.UsedBy[0] != "db" {
+		t.Errorf("Unexpected networks: %+v", list)
+	}
+
+	if err := m.RemoveNetwork("shop"); err == nil || !strings.Contains(err.Error(), "db") {
+		t.Errorf("Expected an in-use error, got %v", err)
+	}
+	if err := m.RemoveNetwork("bridge"); err == nil {
+		t.Error("Expected an error for a built in network")
+	}
+}
@@ -0,0 +1,172 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+// parsePorts turns docker-style specs ("25565:25565", "127.0.0.1:8080:80",
+// "19132:19132/udp", "7000-7010:7000-7010") into exposed ports and bindings.
+func parsePorts(specs []string) (nat.PortSet, nat.PortMap, error) {
+	exposed, bindings, err := nat.ParsePortSpecs(specs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid port mapping: %w", err)
+	}
+	return exposed, bindings, nil
+}
+
+// portProbe reports an error if proto/ip:port cannot be bound on the host.
+type portProbe func(proto, ip string, port uint16) error
+
+// probeListen checks a host port by binding it briefly. The agent runs on
+// the Docker host, so this catches services outside Docker as well. Only
+// EADDRINUSE counts: a non-root agent may not bind ports below 1024 that
+// Docker can still publish, so other errors leave the port to Docker.
+func probeListen(proto, ip string, port uint16) error {
+	addr := net.JoinHostPort(ip, strconv.Itoa(int(port)))
+	if proto == "udp" {
+		c, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return inUse(err)
+		}
+		return c.Close()
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return inUse(err)
+	}
+	return ln.Close()
+}
+
+// inUse keeps err only if it means the address is taken.
+func inUse(err error) error {
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return err
+	}
+	return nil
+}
+
+// publishedPorts collects the host ports claimed by existing containers,
+// keyed by container name. Stopped containers keep their bindings in the
+// host config and would fail to start if those ports were taken.
+func (m *RealManager) publishedPorts(ctx context.Context) (map[string][]common.PortBinding, error) {
+	list, err := m.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string][]common.PortBinding)
+	for _, c := range list {
+		name := c.ID[:12]
+		if len(c.Names) > 0 {
+			name = c.Names[0][1:]
+		}
+		if c.State == "running" {
+			for _, p := range c.Ports {
+				if p.PublicPort != 0 {
+					used[name] = append(used[name], common.PortBinding{
+						HostIP: p.IP, HostPort: p.PublicPort, ContainerPort: p.PrivatePort, Protocol: p.Type,
+					})
+				}
+			}
+			continue
+		}
+
+		inspect, err := m.cli.ContainerInspect(ctx, c.ID)
+		if err != nil || inspect.HostConfig == nil {
+			continue
+		}
+		used[name] = append(used[name], bindingsFromMap(inspect.HostConfig.PortBindings)...)
+	}
+	return used, nil
+}
+
+// bindingsFromMap flattens a port map. Host port ranges are expanded and
+// unpublished or random (empty host port) entries are skipped.
+func bindingsFromMap(pm nat.PortMap) []common.PortBinding {
+	var res []common.PortBinding
+	for port, binds := range pm {
+		for _, b := range binds {
+			if b.HostPort == "" {
+				continue
+			}
+			start, end, err := nat.ParsePortRange(b.HostPort)
+			if err != nil {
+				continue
+			}
+			for hp := start; hp <= end; hp++ {
+				res = append(res, common.PortBinding{
+					HostIP:        b.HostIP,
+					HostPort:      uint16(hp),
+					ContainerPort: uint16(port.Int()),
+					Protocol:      port.Proto(),
+				})
+			}
+		}
+	}
+	return res
+}
+
+// checkPortConflicts fails if a requested host port is already published by
+// a container or cannot be bound. A host range mapped to a single container
+// port only needs one free port, since Docker picks from the range.
+func checkPortConflicts(bindings nat.PortMap, used map[string][]common.PortBinding, probe portProbe) error {
+	for port, binds := range bindings {
+		proto := port.Proto()
+		for _, b := range binds {
+			if b.HostPort == "" {
+				continue // Random host port
+			}
+			start, end, err := nat.ParsePortRange(b.HostPort)
+			if err != nil {
+				return fmt.Errorf("invalid host port %q: %w", b.HostPort, err)
+			}
+
+			// "7000-7010:7000-7010" is split per port by ParsePortSpecs,
+			// so a range left here always means "any one of these".
+			var lastErr error
+			for hp := start; hp <= end; hp++ {
+				if lastErr = portFree(proto, b.HostIP, uint16(hp), used, probe); lastErr == nil {
+					break
+				}
+			}
+			if lastErr != nil {
+				return lastErr
+			}
+		}
+	}
+	return nil
+}
+
+func portFree(proto, ip string, port uint16, used map[string][]common.PortBinding, probe portProbe) error {
+	for name, ports := range used {
+		for _, p := range ports {
+			if p.HostPort == port && p.Protocol == proto && ipsOverlap(p.HostIP, ip) {
+				return fmt.Errorf("host port %d/%s is already published by container %s", port, proto, name)
+			}
+		}
+	}
+	if probe != nil {
+		if err := probe(proto, ip, port); err != nil {
+			return fmt.Errorf("host port %d/%s is in use: %w", port, proto, err)
+		}
+	}
+	return nil
+}
+
+// ipsOverlap treats an empty or unspecified address as every interface.
+func ipsOverlap(a, b string) bool {
+	wild := func(ip string) bool {
+		parsed := net.ParseIP(ip)
+		return ip == "" || (parsed != nil && parsed.IsUnspecified())
+	}
+	return a == b || wild(a) || wild(b) || net.ParseIP(a).Equal(net.ParseIP(b))
+}
@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+)
+
+func TestParsePorts(t *testing.T) {
+	exposed, bindings, err := parsePorts([]string{"25565:25565", "127.0.0.1:8080:80", "19132:19132/udp", "7000-7001:7000-7001"})
+	if err != nil {
+		t.Fatalf("parsePorts failed: %v", err)
+	}
+	if len(exposed) != 5 {
+		t.Errorf("Expected 5 exposed ports, got %d", len(exposed))
+	}
+
+	got := make(map[string]bool)
+	for _, b := range bindingsFromMap(bindings) {
+		got[fmt.Sprintf("%s:%d:%d/%s", b.HostIP, b.HostPort, b.ContainerPort, b.Protocol)] = true
+	}
+	for _, want := range []string{":25565:25565/tcp", "127.0.0.1:8080:80/tcp", ":19132:19132/udp", ":7000:7000/tcp", ":7001:7001/tcp"} {
+		if !got[want] {
+			t.Errorf("Missing binding %s in %v", want, got)
+		}
+	}
+
+	if _, _, err := parsePorts([]string{"25565:abc"}); err == nil {
+		t.Error("Expected error for invalid spec")
+	}
+}
+
+func TestCheckPortConflicts(t *testing.T) {
+	used := map[string][]common.PortBinding{
+		"mc": {{HostIP: "0.0.0.0", HostPort: 25565, ContainerPort: 25565, Protocol: "tcp"}},
+	}
+
+	_, bindings, _ := parsePorts([]string{"127.0.0.1:25565:25565"})
+	err := checkPortConflicts(bindings, used, nil)
+	if err == nil || !strings.Contains(err.Error(), "mc") {
+		t.Errorf("Expected conflict with mc, got %v", err)
+	}
+
+	// Same number, other protocol
+	_, bindings, _ = parsePorts([]string{"25565:25565/udp"})
+	if err := checkPortConflicts(bindings, used, nil); err != nil {
+		t.Errorf("Unexpected conflict: %v", err)
+	}
+
+	// A host range needs only one free port
+	_, bindings, _ = parsePorts([]string{"25565-25566:80"})
+	if err := checkPortConflicts(bindings, used, nil); err != nil {
+		t.Errorf("Unexpected conflict for range: %v", err)
+	}
+}
+
+func TestCheckPortConflictsListening(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	_, bindings, _ := parsePorts([]string{fmt.Sprintf("127.0.0.1:%d:80", port)})
+	if err := checkPortConflicts(bindings, nil, probeListen); err == nil {
+		t.Error("Expected conflict with listening socket")
+	}
+
+	// Errors other than EADDRINUSE, like EACCES for low ports, are left to Docker
+	if err := probeListen("tcp", "192.0.2.1", uint16(port)); err != nil {
+		t.Errorf("Unexpected conflict for an address that cannot be bound: %v", err)
+	}
+}
+
+func TestMockCreateReportsPorts(t *testing.T) {
+	m := NewMockManager()
+	if _, err := m.CreateContainer(common.CreateEnvPayload{Name: "mc", Ports: []string{"25565:25565"}}, nil); err != nil {
+		t.Fatalf("CreateContainer failed: %v", err)
+	}
+	list, _ := m.ListContainers()
+	if len(list) != 1 || len(list[0].Ports) != 1 || list[0].Ports[0].HostPort != 25565 {
+		t.Fatalf("Unexpected ports: %+v", list)
+	}
+
+	if _, err := m.CreateContainer(common.CreateEnvPayload{Name: "mc2", Ports: []string{"25565:25565"}}, nil); err == nil {
+		t.Error("Expected second environment on the same port to fail")
+	}
+}
@@ -0,0 +1,155 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// PullHandler receives pull progress. It is called from the pulling
+// goroutine and must not block for long.
+type PullHandler func(common.PullProgress)
+
+// PullError is returned when the image of a new environment could not be
+// pulled, so callers can tell it apart from a failed create.
+type PullError struct {
+	Image string
+	Err   error
+}
+
+func (e *PullError) Error() string {
+	return fmt.Sprintf("failed to pull %s: %v", e.Image, e.Err)
+}
+
+func (e *PullError) Unwrap() error { return e.Err }
+
+// pullThrottle is the minimum gap between two progress events of the same
+// layer and status. Docker reports every few KB, far more than a terminal
+// or the SSH link needs.
+const pullThrottle = 250 * time.Millisecond
+
+// throttle forwards a progress event unless the same layer reported the
+// same status too recently. Status changes always go through.
+type throttle struct {
+	last map[string]time.Time
+	seen map[string]string
+	now  func() time.Time
+}
+
+func newThrottle() *throttle {
+	return &throttle{last: make(map[string]time.Time), seen: make(map[string]string), now: time.Now}
+}
+
+func (t *throttle) allow(p common.PullProgress) bool {
+	if p.Error != "" || p.Done || p.Layer == "" {
+		return true
+	}
+	now := t.now()
+	if t.seen[p.Layer] == p.Status && now.Sub(t.last[p.Layer]) < pullThrottle {
+		return false
+	}
+	t.seen[p.Layer] = p.Status
+	t.last[p.Layer] = now
+	return true
+}
+
+// readPullStream decodes docker's JSON message stream into progress events.
+func readPullStream(ref string, r io.Reader, progress PullHandler) error {
+	dec := json.NewDecoder(r)
+	th := newThrottle()
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+
+		p := common.PullProgress{Image: ref, Layer: msg.ID, Status: msg.Status}
+		if msg.Progress != nil {
+			p.Current = msg.Progress.Current
+			p.Total = msg.Progress.Total
+		}
+		if progress != nil && th.allow(p) {
+			progress(p)
+		}
+	}
+}
+
+// PullImage pulls ref, reporting progress as it goes. If the registry is
+// unreachable but the image is already on the host, the error is reported
+// through progress and the local copy is used. auth may be nil.
+func (m *RealManager) PullImage(ref string, auth *common.RegistryAuth, progress PullHandler) error {
+	ctx := context.Background()
+
+	err := m.pull(ctx, ref, auth, progress)
+	if err != nil {
+		if _, inspectErr := m.cli.ImageInspect(ctx, ref); inspectErr == nil {
+			if progress != nil {
+				progress(common.PullProgress{Image: ref, Status: "Using local image", Error: err.Error(), Done: true})
+			}
+			return nil
+		}
+		if progress != nil {
+			progress(common.PullProgress{Image: ref, Error: err.Error(), Done: true})
+		}
+		return &PullError{Image: ref, Err: err}
+	}
+
+	if progress != nil {
+		progress(common.PullProgress{Image: ref, Status: "Pull complete", Done: true})
+	}
+	return nil
+}
+
+func (m *RealManager) pull(ctx context.Context, ref string, auth *common.RegistryAuth, progress PullHandler) error {
+	opts := image.PullOptions{}
+	if auth != nil {
+		encoded, err := encodeAuth(*auth)
+		if err != nil {
+			return err
+		}
+		opts.RegistryAuth = encoded
+	}
+
+	out, err := m.cli.ImagePull(ctx, ref, opts)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return readPullStream(ref, out, progress)
+}
+
+// encodeAuth builds the X-Registry-Auth header value.
+func encodeAuth(auth common.RegistryAuth) (string, error) {
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		ServerAddress: auth.ServerAddress,
+	})
+}
+
+func (m *MockManager) PullImage(ref string, auth *common.RegistryAuth, progress PullHandler) error {
+	m.addMockImage(ref)
+	if progress != nil {
+		for _, step := range []common.PullProgress{
+			{Image: ref, Layer: "mock0001", Status: "Downloading", Current: 512, Total: 1024},
+			{Image: ref, Layer: "mock0001", Status: "Pull complete"},
+			{Image: ref, Status: "Pull complete", Done: true},
+		} {
+			progress(step)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/registry"
+)
+
+func TestReadPullStream(t *testing.T) {
+	stream := `{"status":"Pulling from itzg/minecraft-server","id":"latest"}
+{"status":"Downloading","progressDetail":{"current":1024,"total":4096},"id":"a1b2"}
+{"status":"Downloading","progressDetail":{"current":2048,"total":4096},"id":"a1b2"}
+{"status":"Extracting","progressDetail":{"current":4096,"total":4096},"id":"a1b2"}
+{"status":"Pull complete","id":"a1b2"}
+`
+	var events []common.PullProgress
+	if err := readPullStream("mc", strings.NewReader(stream), func(p common.PullProgress) {
+		events = append(events, p)
+	}); err != nil {
+		t.Fatalf("readPullStream failed: %v", err)
+	}
+
+	// The second Downloading event arrives within the throttle window
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d: %+v", len(events), events)
+	}
+	if e := events[1]; e.Layer != "a1b2" || e.Status != "Downloading" || e.Current != 1024 || e.Total != 4096 {
+		t.Errorf("Unexpected download event: %+v", e)
+	}
+	if events[2].Status != "Extracting" {
+		t.Errorf("Status change should not be throttled: %+v", events[2])
+	}
+}
+
+func TestReadPullStreamError(t *testing.T) {
+	stream := `{"status":"Pulling from private/app","id":"latest"}
+{"errorDetail":{"message":"pull access denied"},"error":"pull access denied"}
+`
+	err := readPullStream("private/app", strings.NewReader(stream), nil)
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("Expected pull error, got %v", err)
+	}
+}
+
+func TestThrottleAllowsAfterWindow(t *testing.T) {
+	now := time.Unix(0, 0)
+	th := newThrottle()
+	th.now = func() time.Time { return now }
+
+	p := common.PullProgress{Layer: "a1b2", Status: "Downloading"}
+	if !th.allow(p) || th.allow(p) {
+		t.Fatal("Expected only the first event inside the window")
+	}
+	now = now.Add(pullThrottle)
+	if !th.allow(p) {
+		t.Error("Expected event after the window")
+	}
+}
+
+func TestEncodeAuth(t *testing.T) {
+	encoded, err := encodeAuth(common.RegistryAuth{ServerAddress: "ghcr.io", Username: "ci", Password: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := registry.DecodeAuthConfig(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Username != "ci" || decoded.Password != "s3cret" || decoded.ServerAddress != "ghcr.io" {
+		t.Errorf("Unexpected auth config: %+v", decoded)
+	}
+}
@@ -0,0 +1,262 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+)
+
+// payloadLabel holds the CreateEnvPayload an environment was created from,
+// so it can be edited and recreated later.
+const payloadLabel = "perssh.payload"
+
+// startGrace is how long a recreated container must stay up before the
+// old one is removed.
+var startGrace = 3 * time.Second
+
+// encodePayload stores a payload for the label. Credentials are dropped
+// and mounts are stored resolved, so a recreated environment keeps its
+// volumes even if it was renamed since.
+func encodePayload(p common.CreateEnvPayload, mounts []common.Mount) (string, error) {
+	p.Auth = nil
+	p.Mounts = mounts
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// skipPorts wraps a probe to treat ports in own as free.
+func skipPorts(probe portProbe, own []common.PortBinding) portProbe {
+	return func(proto, ip string, port uint16) error {
+		for _, p := range own {
+			if p.HostPort == port && p.Protocol == proto && ipsOverlap(p.HostIP, ip) {
+				return nil
+			}
+		}
+		return probe(proto, ip, port)
+	}
+}
+
+// payloadFromInspect rebuilds a payload for containers created before the
+// payload label existed, or by something other than PerSSH. Variables
+// that come from the image are left out.
+func payloadFromInspect(c container.InspectResponse, imageEnv []string) common.CreateEnvPayload {
+	p := common.CreateEnvPayload{
+		Name:     strings.TrimPrefix(c.Name, "/"),
+		Type:     common.EnvTypeStandard,
+		Mounts:   fromMountPoints(c.Mounts),
+		Networks: inspectNetworks(c),
+	}
+	if c.Config != nil {
+		p.Image = c.Config.Image
+		p.Health = fromHealthConfig(c.Config.Healthcheck)
+		if t := c.Config.Labels["perssh.type"]; t != "" {
+			p.Type = common.EnvironmentType(t)
+		}
+		inherited := make(map[string]bool, len(imageEnv))
+		for _, e := range imageEnv {
+			inherited[e] = true
+		}
+		for _, e := range c.Config.Env {
+			if inherited[e] {
+				continue
+			}
+			if k, v, ok := strings.Cut(e, "="); ok {
+				if p.EnvVars == nil {
+					p.EnvVars = make(map[string]string)
+				}
+				p.EnvVars[k] = v
+			}
+		}
+	}
+	if c.ContainerJSONBase != nil && c.HostConfig != nil {
+		p.Resources = fromHostConfig(c.HostConfig)
+		for port, binds := range c.HostConfig.PortBindings {
+			for _, b := range binds {
+				spec := b.HostPort + ":" + port.Port() + "/" + port.Proto()
+				if b.HostIP != "" {
+					spec = b.HostIP + ":" + spec
+				}
+				p.Ports = append(p.Ports, spec)
+			}
+		}
+	}
+	return p
+}
+
+// GetEnvConfig returns the payload an environment was created from, with
+// the limits it has now, since UPDATE_LIMITS doesn't touch the label.
+func (m *RealManager) GetEnvConfig(id string) (common.CreateEnvPayload, error) {
+	ctx := context.Background()
+	inspect, err := m.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return common.CreateEnvPayload{}, err
+	}
+	if raw := inspect.Config.Labels[payloadLabel]; raw != "" {
+		var p common.CreateEnvPayload
+		if err := json.Unmarshal([]byte(raw), &p); err != nil {
+			return p, fmt.Errorf("invalid %s label: %w", payloadLabel, err)
+		}
+		// The stored name is stale after a rename
+		p.Name = strings.TrimPrefix(inspect.Name, "/")
+		p.Resources, p.RamLimit = fromHostConfig(inspect.HostConfig), ""
+		return p, nil
+	}
+
+	var imageEnv []string
+	if img, err := m.cli.ImageInspect(ctx, inspect.Image); err == nil && img.Config != nil {
+		imageEnv = img.Config.Env
+	}
+	return payloadFromInspect(inspect, imageEnv), nil
+}
+
+// RecreateContainer replaces an environment with one built from payload,
+// keeping its name and volumes. The new image is pulled while the old
+// container still runs. If the new container does not start, or stops
+// within startGrace, it is removed and the old one is restored. Services
+// of a compose stack are refused, as the copy would lose the stack labels.
+func (m *RealManager) RecreateContainer(id string, payload common.CreateEnvPayload, progress PullHandler) (string, error) {
+	ctx := context.Background()
+	old, err := m.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if old.Config != nil && old.Config.Labels[stackLabel] != "" {
+		return "", stackServiceError(old.Config.Labels[stackLabel])
+	}
+	name := strings.TrimPrefix(old.Name, "/")
+	payload.Name = name
+
+	spec, err := m.prepareCreate(ctx, payload, name)
+	if err != nil {
+		return "", err
+	}
+	if err := m.PullImage(payload.Image, payload.Auth, progress); err != nil {
+		return "", err
+	}
+
+	wasRunning := old.State != nil && (old.State.Running || old.State.Restarting)
+	if wasRunning {
+		if err := m.cli.ContainerStop(ctx, old.ID, container.StopOptions{}); err != nil {
+			return "", err
+		}
+	}
+	// Free the name; the old container stays as the rollback target
+	backup := fmt.Sprintf("%s-old-%d", name, time.Now().Unix())
+	if err := m.cli.ContainerRename(ctx, old.ID, backup); err != nil {
+		return "", m.rollback(ctx, old.ID, "", "", wasRunning, err)
+	}
+
+	newID, err := m.create(ctx, payload, spec)
+	if err != nil {
+		return "", m.rollback(ctx, old.ID, "", name, wasRunning, err)
+	}
+	if err := m.cli.ContainerStart(ctx, newID, container.StartOptions{}); err != nil {
+		return "", m.rollback(ctx, old.ID, newID, name, wasRunning, err)
+	}
+	if err := m.waitStarted(ctx, newID); err != nil {
+		return "", m.rollback(ctx, old.ID, newID, name, wasRunning, err)
+	}
+
+	// Volumes are shared with the new container, so only the container goes
+	if err := m.cli.ContainerRemove(ctx, old.ID, container.RemoveOptions{Force: true}); err != nil {
+		return newID, fmt.Errorf("environment updated, but the old container %s was not removed: %w", backup, err)
+	}
+	return newID, nil
+}
+
+// waitStarted fails if the container is not running after startGrace.
+// With the unless-stopped policy a crashing container shows as restarting.
+func (m *RealManager) waitStarted(ctx context.Context, id string) error {
+	time.Sleep(startGrace)
+	inspect, err := m.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return err
+	}
+	if s := inspect.State; s == nil || !s.Running || s.Restarting {
+		code := 0
+		if s != nil {
+			code = s.ExitCode
+		}
+		return fmt.Errorf("new container did not stay up (exit code %d)", code)
+	}
+	return nil
+}
+
+// rollback removes the new container, if any, and gives the old one back
+// its name and state. cause is returned wrapped.
+func (m *RealManager) rollback(ctx context.Context, oldID, newID, name string, start bool, cause error) error {
+	var errs []string
+	if newID != "" {
+		if err := m.cli.ContainerRemove(ctx, newID, container.RemoveOptions{Force: true}); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if name != "" {
+		if err := m.cli.ContainerRename(ctx, oldID, name); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if start {
+		if err := m.cli.ContainerStart(ctx, oldID, container.StartOptions{}); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("update failed: %w; rollback failed: %s", cause, strings.Join(errs, "; "))
+	}
+	return fmt.Errorf("update failed, previous container restored: %w", cause)
+}
+
+func (m *MockManager) GetEnvConfig(id string) (common.CreateEnvPayload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.containers[id]
+	if !ok {
+		return common.CreateEnvPayload{}, fmt.Errorf("container not found")
+	}
+	p := m.payloads[id]
+	p.Name = c.Name
+	p.Resources, p.RamLimit = m.limits[id], ""
+	return p, nil
+}
+
+func (m *MockManager) RecreateContainer(id string, payload common.CreateEnvPayload, progress PullHandler) (string, error) {
+	m.mu.Lock()
+	old, ok := m.containers[id]
+	if !ok {
+		m.mu.Unlock()
+		return "", fmt.Errorf("container not found")
+	}
+	if old.Stack != "" {
+		m.mu.Unlock()
+		return "", stackServiceError(old.Stack)
+	}
+	// Take the old container out so its name and ports are free
+	delete(m.containers, id)
+	m.mu.Unlock()
+
+	payload.Name = old.Name
+	newID, err := m.CreateContainer(payload, progress)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.containers[id] = old
+		return "", fmt.Errorf("update failed, previous container restored: %w", err)
+	}
+	c := m.containers[newID]
+	c.Status = "running"
+	c.Health = m.mockHealth(newID)
+	m.containers[newID] = c
+	delete(m.limits, id)
+	delete(m.payloads, id)
+	return newID, nil
+}
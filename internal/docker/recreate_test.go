@@ -0,0 +1,157 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestEncodePayloadDropsAuth(t *testing.T) {
+	p := common.CreateEnvPayload{
+		Name:   "web",
+		Image:  "registry.example.com/web:1",
+		Mounts: []common.Mount{{Target: "/data"}},
+		Auth:   &common.RegistryAuth{Username: "bob", Password: "hunter2"},
+	}
+	mounts, _ := resolveMounts(p.Name, p.Mounts)
+	s, err := encodePayload(p, mounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(s, "hunter2") || strings.Contains(s, "bob") {
+		t.Errorf("Credentials leaked into label: %s", s)
+	}
+
+	var back common.CreateEnvPayload
+	json.Unmarshal([]byte(s), &back)
+	if back.Image != p.Image || len(back.Mounts) != 1 || back.Mounts[0].Source != "perssh-web-data" {
+		t.Errorf("Unexpected stored payload: %+v", back)
+	}
+}
+
+func TestPayloadFromInspect(t *testing.T) {
+	c := container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			Name: "/legacy",
+			HostConfig: &container.HostConfig{
+				PortBindings: nat.PortMap{"80/tcp": {{HostIP: "127.0.0.1", HostPort: "8080"}}},
+				Resources:    container.Resources{Memory: 1 << 30},
+			},
+		},
+		Config: &container.Config{
+			Image:  "nginx:1.25",
+			Env:    []string{"PATH=/usr/bin", "MODE=prod"},
+			Labels: map[string]string{"perssh.type": "STANDARD"},
+		},
+	}
+	p := payloadFromInspect(c, []string{"PATH=/usr/bin"})
+	if p.Name != "legacy" || p.Image != "nginx:1.25" || p.Type != common.EnvTypeStandard {
+		t.Errorf("Unexpected payload: %+v", p)
+	}
+	if len(p.EnvVars) != 1 || p.EnvVars["MODE"] != "prod" {
+		t.Errorf("Expected only MODE, got %v", p.EnvVars)
+	}
+	if len(p.Ports) != 1 || p.Ports[0] != "127.0.0.1:8080:80/tcp" {
+		t.Errorf("Unexpected ports %v", p.Ports)
+	}
+	if p.Resources.Memory != "1g" {
+		t.Errorf("Expected 1g memory, got %q", p.Resources.Memory)
+	}
+
+	// The rebuilt spec must parse again
+	if _, _, err := parsePorts(p.Ports); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSkipPorts(t *testing.T) {
+	busy := func(proto, ip string, port uint16) error { return errBusy }
+	probe := skipPorts(busy, []common.PortBinding{{HostIP: "0.0.0.0", HostPort: 25565, Protocol: "tcp"}})
+	if err := probe("tcp", "", 25565); err != nil {
+		t.Errorf("Own port reported busy: %v", err)
+	}
+	if err := probe("udp", "", 25565); err == nil {
+		t.Error("Expected other protocol to be probed")
+	}
+}
+
+var errBusy = errors.New("address already in use")
+
+func TestMockRecreateKeepsNameAndVolumes(t *testing.T) {
+	m := NewMockManager()
+	id, _ := m.CreateContainer(common.CreateEnvPayload{
+		Name:   "mc",
+		Image:  "itzg/minecraft-server",
+		Ports:  []string{"25565:25565"},
+		Mounts: []common.Mount{{Target: "/data"}},
+	}, nil)
+	m.RenameContainer(id, "survival")
+
+	p, err := m.GetEnvConfig(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "survival" || p.Mounts[0].Source != "perssh-mc-data" {
+		t.Fatalf("Unexpected config: %+v", p)
+	}
+
+	// Same port as before must not conflict with the container it replaces
+	p.Image = "itzg/minecraft-server:java21"
+	p.EnvVars = map[string]string{"DIFFICULTY": "hard"}
+	newID, err := m.RecreateContainer(id, p, nil)
+	if err != nil {
+		t.Fatalf("Recreate failed: %v", err)
+	}
+
+	list, _ := m.ListContainers()
+	if len(list) != 1 {
+		t.Fatalf("Expected the old container to be gone, got %+v", list)
+	}
+	c := list[0]
+	if c.ID != newID || c.Name != "survival" || c.Image != p.Image || c.Status != "running" {
+		t.Errorf("Unexpected container %+v", c)
+	}
+	if len(c.Mounts) != 1 || c.Mounts[0].Source != "perssh-mc-data" {
+		t.Errorf("Volume not kept: %+v", c.Mounts)
+	}
+}
+
+func TestMockRecreateKeepsUpdatedLimits(t *testing.T) {
+	m := NewMockManager()
+	id, _ := m.CreateContainer(common.CreateEnvPayload{Name: "mc", RamLimit: "2g"}, nil)
+	if err := m.UpdateLimits(id, common.Resources{Memory: "4g", CPUs: 1.5}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := m.GetEnvConfig(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newID, err := m.RecreateContainer(id, p, nil)
+	if err != nil {
+		t.Fatalf("Recreate failed: %v", err)
+	}
+	if r, _ := m.GetLimits(newID); r.Memory != "4g" || r.CPUs != 1.5 {
+		t.Errorf("Limits not carried over: %+v", r)
+	}
+}
+
+func TestMockRecreateRestoresOnFailure(t *testing.T) {
+	m := NewMockManager()
+	id, _ := m.CreateContainer(common.CreateEnvPayload{Name: "web", Image: "nginx"}, nil)
+
+	p, _ := m.GetEnvConfig(id)
+	p.Ports = []string{"bogus"}
+	if _, err := m.RecreateContainer(id, p, nil); err == nil {
+		t.Fatal("Expected invalid ports to fail")
+	}
+	list, _ := m.ListContainers()
+	if len(list) != 1 || list[0].ID != id {
+		t.Errorf("Expected the old container back, got %+v", list)
+	}
+}
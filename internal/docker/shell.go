@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/creack/pty"
+	"github.com/docker/docker/api/types/container"
+)
+
+// TermSize is a terminal size in character cells.
+type TermSize struct {
+	Rows uint
+	Cols uint
+}
+
+// DefaultShell prefers bash and falls back to sh for minimal images.
+var DefaultShell = []string{"/bin/sh", "-c", "if command -v bash >/dev/null 2>&1; then exec bash; else exec sh; fi"}
+
+// Shell runs an interactive shell in the container with a TTY. It blocks
+// until the shell exits. Sizes received on resize are applied to the TTY.
+func (m *RealManager) Shell(id string, stdin io.Reader, stdout io.Writer, resize <-chan TermSize) error {
+	ctx := context.Background()
+
+	created, err := m.cli.ContainerExecCreate(ctx, id, container.ExecOptions{
+		Tty:          true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          []string{"TERM=xterm-256color"},
+		Cmd:          DefaultShell,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.cli.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{Tty: true})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	go func() {
+		for size := range resize {
+			m.cli.ContainerExecResize(ctx, created.ID, container.ResizeOptions{Height: size.Rows, Width: size.Cols})
+		}
+	}()
+
+	go func() {
+		io.Copy(resp.Conn, stdin)
+		resp.CloseWrite()
+	}()
+
+	// With a TTY the output is a raw stream, no multiplexing headers
+	_, err = io.Copy(stdout, resp.Reader)
+	return err
+}
+
+// Shell spawns a local shell on a pty so the interactive path can be
+// exercised without Docker. Only an explicitly requested mock does this;
+// one standing in for a missing engine never starts host processes.
+func (m *MockManager) Shell(id string, stdin io.Reader, stdout io.Writer, resize <-chan TermSize) error {
+	if m.fallback != "" {
+		return errors.New(m.fallback)
+	}
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.Command(shell)
+	cmd.Env = append(os.Environ(), "PERSSH_MOCK_CONTAINER="+id)
+
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Rows: 24, Cols: 80})
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+
+	go func() {
+		for size := range resize {
+			pty.Setsize(ptmx, &pty.Winsize{Rows: uint16(size.Rows), Cols: uint16(size.Cols)})
+		}
+	}()
+
+	go io.Copy(ptmx, stdin)
+
+	// Reading the master fails with EIO once the shell exits
+	io.Copy(stdout, ptmx)
+	return cmd.Wait()
+}
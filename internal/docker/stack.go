@@ -0,0 +1,477 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// authFor picks the login matching the registry of image.
+func authFor(auths []common.RegistryAuth, image string) *common.RegistryAuth {
+	host := common.RegistryHost(image)
+	for i := range auths {
+		if auths[i].ServerAddress == host {
+			return &auths[i]
+		}
+	}
+	return nil
+}
+
+// stackServiceError is returned for single-container changes to a stack
+// service, which would drop it out of its stack.
+func stackServiceError(stack string) error {
+	return fmt.Errorf("container belongs to stack %s; change its compose file and redeploy the stack instead", stack)
+}
+
+// stackFilter selects the containers, networks or volumes of a stack.
+func stackFilter(stack string) filters.Args {
+	return filters.NewArgs(filters.Arg("label", stackLabel+"="+stack))
+}
+
+// stackContainers returns the containers of a stack by service name.
+func (m *RealManager) stackContainers(ctx context.Context, stack string) (map[string]container.Summary, error) {
+	list, err := m.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: stackFilter(stack)})
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]container.Summary, len(list))
+	for _, c := range list {
+		res[c.Labels[serviceLabel]] = c
+	}
+	return res, nil
+}
+
+// DeployStack creates a stack or brings an existing one in line with the
+// compose file. Services are created in depends_on order; a service is
+// only recreated if its definition or image changed.
+func (m *RealManager) DeployStack(name string, data []byte, auths []common.RegistryAuth, progress PullHandler) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid stack name %q", name)
+	}
+	cf, err := parseCompose(data)
+	if err != nil {
+		return err
+	}
+	order, err := cf.order()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	existing, err := m.stackContainers(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := m.checkStackPorts(ctx, cf, existing); err != nil {
+		return err
+	}
+
+	imageIDs := make(map[string]string)
+	for _, img := range cf.images() {
+		if err := m.PullImage(img, authFor(auths, img), progress); err != nil {
+			return err
+		}
+		inspect, err := m.cli.ImageInspect(ctx, img)
+		if err != nil {
+			return err
+		}
+		imageIDs[img] = inspect.ID
+	}
+
+	if err := m.ensureStackNetworks(ctx, name, cf, order); err != nil {
+		return err
+	}
+	if err := m.ensureStackVolumes(ctx, name, cf); err != nil {
+		return err
+	}
+
+	for i, svc := range order {
+		spec, err := cf.serviceSpec(name, svc, i, imageIDs[cf.Services[svc].Image])
+		if err != nil {
+			return fmt.Errorf("service %s: %w", svc, err)
+		}
+		if c, ok := existing[svc]; ok {
+			delete(existing, svc)
+			if c.Labels[hashLabel] == spec.hash {
+				if c.State != "running" {
+					if err := m.cli.ContainerStart(ctx, c.ID, container.StartOptions{}); err != nil {
+						return fmt.Errorf("service %s: %w", svc, err)
+					}
+				}
+				continue
+			}
+			if err := m.cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
+				return fmt.Errorf("service %s: %w", svc, err)
+			}
+		}
+		if err := m.createService(ctx, svc, spec); err != nil {
+			return fmt.Errorf("service %s: %w", svc, err)
+		}
+	}
+
+	// Services dropped from the file
+	for _, c := range existing {
+		if err := m.cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createService creates and starts one service container. It joins its
+// first network at creation and the others afterwards, with the service
+// name as alias so services reach each other by name.
+func (m *RealManager) createService(ctx context.Context, svc string, spec serviceSpec) error {
+	endpoint := func() *network.EndpointSettings {
+		return &network.EndpointSettings{Aliases: []string{svc}}
+	}
+	netConfig := &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+		spec.networks[0]: endpoint(),
+	}}
+	spec.host.NetworkMode = container.NetworkMode(spec.networks[0])
+
+	resp, err := m.cli.ContainerCreate(ctx, spec.config, spec.host, netConfig, nil, spec.name)
+	if err != nil {
+		return err
+	}
+	for _, n := range spec.networks[1:] {
+		if err := m.cli.NetworkConnect(ctx, n, resp.ID, endpoint()); err != nil {
+			// Free the name for the next deploy
+			m.cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+			return err
+		}
+	}
+	return m.cli.ContainerStart(ctx, resp.ID, container.StartOptions{})
+}
+
+// checkStackPorts fails if a service's host port is taken by something
+// other than the stack's own containers.
+func (m *RealManager) checkStackPorts(ctx context.Context, cf *composeFile, existing map[string]container.Summary) error {
+	used, err := m.publishedPorts(ctx)
+	if err != nil {
+		return err
+	}
+	var own []common.PortBinding
+	for _, c := range existing {
+		for _, n := range c.Names {
+			name := n[1:]
+			own = append(own, used[name]...)
+			delete(used, name)
+		}
+	}
+	probe := skipPorts(probeListen, own)
+	for svc, s := range cf.Services {
+		_, bindings, _ := parsePorts(s.Ports)
+		if err := checkPortConflicts(bindings, used, probe); err != nil {
+			return fmt.Errorf("service %s: %w", svc, err)
+		}
+	}
+	return nil
+}
+
+// ensureStackNetworks creates the networks the services use. External
+// networks must already exist.
+func (m *RealManager) ensureStackNetworks(ctx context.Context, stack string, cf *composeFile, order []string) error {
+	wanted := make(map[string]*composeResource)
+	for _, svc := range order {
+		s := cf.Services[svc]
+		if len(s.Networks) == 0 {
+			wanted[stack+"_default"] = cf.Networks["default"]
+		}
+		for _, n := range s.Networks {
+			wanted[resourceName(stack, n, cf.Networks[n])] = cf.Networks[n]
+		}
+	}
+
+	for name, r := range wanted {
+		if _, err := m.cli.NetworkInspect(ctx, name, network.InspectOptions{}); err == nil {
+			continue
+		} else if !cerrdefs.IsNotFound(err) {
+			return err
+		}
+		if r != nil && r.External {
+			return fmt.Errorf("external network %s does not exist", name)
+		}
+		opts := network.CreateOptions{Labels: map[string]string{"perssh.managed": "true", stackLabel: stack}}
+		if r != nil {
+			opts.Driver = r.Driver
+		}
+		if _, err := m.cli.NetworkCreate(ctx, name, opts); err != nil {
+			return fmt.Errorf("failed to create network %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ensureStackVolumes creates the declared volumes. External volumes must
+// already exist.
+func (m *RealManager) ensureStackVolumes(ctx context.Context, stack string, cf *composeFile) error {
+	for key, r := range cf.Volumes {
+		name := resourceName(stack, key, r)
+		if _, err := m.cli.VolumeInspect(ctx, name); err == nil {
+			continue
+		} else if !cerrdefs.IsNotFound(err) {
+			return err
+		}
+		if r != nil && r.External {
+			return fmt.Errorf("external volume %s does not exist", name)
+		}
+		opts := volume.CreateOptions{
+			Name:   name,
+			Labels: map[string]string{"perssh.managed": "true", stackLabel: stack},
+		}
+		if r != nil {
+			opts.Driver = r.Driver
+		}
+		if _, err := m.cli.VolumeCreate(ctx, opts); err != nil {
+			return fmt.Errorf("failed to create volume %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// orderedStack returns the stack's containers in start order.
+func (m *RealManager) orderedStack(ctx context.Context, stack string) ([]container.Summary, error) {
+	list, err := m.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: stackFilter(stack)})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("stack %s not found", stack)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, _ := strconv.Atoi(list[i].Labels[orderLabel])
+		b, _ := strconv.Atoi(list[j].Labels[orderLabel])
+		return a < b
+	})
+	return list, nil
+}
+
+// StartStack starts the stack's containers, dependencies first.
+func (m *RealManager) StartStack(name string) error {
+	ctx := context.Background()
+	list, err := m.orderedStack(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, c := range list {
+		if c.State == "running" {
+			continue
+		}
+		if err := m.cli.ContainerStart(ctx, c.ID, container.StartOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StopStack stops the stack's containers, dependents first.
+func (m *RealManager) StopStack(name string) error {
+	ctx := context.Background()
+	list, err := m.orderedStack(ctx, name)
+	if err != nil {
+		return err
+	}
+	for i := len(list) - 1; i >= 0; i-- {
+		if err := m.cli.ContainerStop(ctx, list[i].ID, container.StopOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveStack removes the stack's containers and networks, and with
+// removeVolumes the volumes it created. External resources are left alone.
+func (m *RealManager) RemoveStack(name string, removeVolumes bool) error {
+	ctx := context.Background()
+	list, err := m.orderedStack(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, c := range list {
+		if err := m.cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
+			return err
+		}
+	}
+
+	networks, err := m.cli.NetworkList(ctx, network.ListOptions{Filters: stackFilter(name)})
+	if err != nil {
+		return err
+	}
+	for _, n := range networks {
+		if err := m.cli.NetworkRemove(ctx, n.ID); err != nil && !cerrdefs.IsNotFound(err) {
+			return fmt.Errorf("stack removed, but network %s was not: %w", n.Name, err)
+		}
+	}
+
+	if !removeVolumes {
+		return nil
+	}
+	vols, err := m.cli.VolumeList(ctx, volume.ListOptions{Filters: stackFilter(name)})
+	if err != nil {
+		return err
+	}
+	for _, v := range vols.Volumes {
+		if err := m.cli.VolumeRemove(ctx, v.Name, false); err != nil && !cerrdefs.IsConflict(err) && !cerrdefs.IsNotFound(err) {
+			return fmt.Errorf("stack removed, but volume %s was not: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *MockManager) DeployStack(name string, data []byte, auths []common.RegistryAuth, progress PullHandler) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid stack name %q", name)
+	}
+	cf, err := parseCompose(data)
+	if err != nil {
+		return err
+	}
+	order, err := cf.order()
+	if err != nil {
+		return err
+	}
+	for _, img := range cf.images() {
+		if err := m.PullImage(img, authFor(auths, img), progress); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing := make(map[string]string) // Service to ID
+	used := make(map[string][]common.PortBinding)
+	for id, c := range m.containers {
+		if c.Stack == name {
+			existing[c.Service] = id
+		} else {
+			used[c.Name] = c.Ports
+		}
+	}
+	for svc, s := range cf.Services {
+		_, bindings, _ := parsePorts(s.Ports)
+		if err := checkPortConflicts(bindings, used, nil); err != nil {
+			return fmt.Errorf("service %s: %w", svc, err)
+		}
+	}
+
+	for key, r := range cf.Volumes {
+		v := resourceName(name, key, r)
+		if _, ok := m.volumes[v]; !ok {
+			owner := name
+			if r != nil && r.External {
+				owner = ""
+			}
+			m.volumes[v] = owner
+		}
+	}
+	for i, svc := range order {
+		spec, err := cf.serviceSpec(name, svc, i, cf.Services[svc].Image)
+		if err != nil {
+			return fmt.Errorf("service %s: %w", svc, err)
+		}
+		if id, ok := existing[svc]; ok {
+			delete(existing, svc)
+			if c := m.containers[id]; c.Labels[hashLabel] == spec.hash {
+				c.Status = "running"
+				m.containers[id] = c
+				continue
+			}
+			delete(m.containers, id)
+		}
+
+		id := fmt.Sprintf("mock-%d", time.Now().UnixNano())
+		m.containers[id] = common.ContainerInfo{
+			ID:       id,
+			Name:     spec.name,
+			Image:    spec.config.Image,
+			Status:   "running",
+			Created:  time.Now().Unix(),
+			Labels:   spec.config.Labels,
+			Ports:    bindingsFromMap(spec.host.PortBindings),
+			Mounts:   fromDockerMounts(spec.host.Mounts),
+			Stack:    name,
+			Service:  svc,
+			Networks: spec.networks,
+		}
+	}
+	for _, id := range existing {
+		delete(m.containers, id)
+	}
+	return nil
+}
+
+// mockStack returns the IDs of a mock stack in start order.
+func (m *MockManager) mockStack(name string) ([]string, error) {
+	var ids []string
+	for id, c := range m.containers {
+		if c.Stack == name {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("stack %s not found", name)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.Atoi(m.containers[ids[i]].Labels[orderLabel])
+		b, _ := strconv.Atoi(m.containers[ids[j]].Labels[orderLabel])
+		return a < b
+	})
+	return ids, nil
+}
+
+func (m *MockManager) StartStack(name string) error {
+	return m.setStackStatus(name, "running")
+}
+
+func (m *MockManager) StopStack(name string) error {
+	return m.setStackStatus(name, "exited")
+}
+
+func (m *MockManager) setStackStatus(name, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids, err := m.mockStack(name)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		c := m.containers[id]
+		c.Status = status
+		m.containers[id] = c
+	}
+	return nil
+}
+
+func (m *MockManager) RemoveStack(name string, removeVolumes bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids, err := m.mockStack(name)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		c := m.containers[id]
+		delete(m.containers, id)
+		if removeVolumes {
+			for _, mt := range c.Mounts {
+				// Only volumes the stack created carry its prefix
+				if !mt.IsBind() && strings.HasPrefix(mt.Source, name+"_") {
+					delete(m.volumes, mt.Source)
+				}
+			}
+		}
+	}
+	return nil
+}
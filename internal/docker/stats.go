@@ -0,0 +1,159 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// ContainerStats samples resource usage. With an empty id every running
+// container is sampled; containers are queried in parallel since each
+// sample takes about a second to collect.
+func (m *RealManager) ContainerStats(id string) ([]common.ContainerStats, error) {
+	ctx := context.Background()
+
+	ids := []string{id}
+	if id == "" {
+		list, err := m.cli.ContainerList(ctx, container.ListOptions{
+			Filters: filters.NewArgs(filters.Arg("status", "running")),
+		})
+		if err != nil {
+			return nil, err
+		}
+		ids = ids[:0]
+		for _, c := range list {
+			ids = append(ids, c.ID)
+		}
+	}
+
+	res := make([]common.ContainerStats, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, cid := range ids {
+		wg.Add(1)
+		go func(i int, cid string) {
+			defer wg.Done()
+			res[i], errs[i] = m.sample(ctx, cid)
+		}(i, cid)
+	}
+	wg.Wait()
+
+	// A container stopping mid-poll is not an error for the whole list
+	out := res[:0]
+	for i, s := range res {
+		if errs[i] != nil {
+			if id != "" {
+				return nil, errs[i]
+			}
+			continue
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (m *RealManager) sample(ctx context.Context, id string) (common.ContainerStats, error) {
+	// stream=false waits for a second sample so precpu_stats is filled in
+	resp, err := m.cli.ContainerStats(ctx, id, false)
+	if err != nil {
+		return common.ContainerStats{}, err
+	}
+	defer resp.Body.Close()
+
+	var raw container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return common.ContainerStats{}, err
+	}
+	s := convertStats(&raw)
+	s.ID = shortID(id)
+	return s, nil
+}
+
+// convertStats follows the docker CLI's calculations.
+func convertStats(v *container.StatsResponse) common.ContainerStats {
+	s := common.ContainerStats{
+		MemUsage: memUsage(v.MemoryStats),
+		MemLimit: v.MemoryStats.Limit,
+		PIDs:     v.PidsStats.Current,
+	}
+
+	cpuDelta := float64(v.CPUStats.CPUUsage.TotalUsage) - float64(v.PreCPUStats.CPUUsage.TotalUsage)
+	sysDelta := float64(v.CPUStats.SystemUsage) - float64(v.PreCPUStats.SystemUsage)
+	online := float64(v.CPUStats.OnlineCPUs)
+	if online == 0 {
+		online = float64(len(v.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && sysDelta > 0 {
+		s.CPUPercent = cpuDelta / sysDelta * online * 100
+	}
+
+	for _, n := range v.Networks {
+		s.NetRx += n.RxBytes
+		s.NetTx += n.TxBytes
+	}
+	for _, e := range v.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			s.BlockRead += e.Value
+		case "write":
+			s.BlockWrite += e.Value
+		}
+	}
+	return s
+}
+
+// memUsage subtracts page cache, which the kernel reclaims under pressure.
+// The key differs between cgroup v1 and v2.
+func memUsage(m container.MemoryStats) uint64 {
+	cache, ok := m.Stats["total_inactive_file"]
+	if !ok {
+		cache = m.Stats["inactive_file"]
+	}
+	if cache < m.Usage {
+		return m.Usage - cache
+	}
+	return m.Usage
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// ContainerStats makes up plausible, slowly changing numbers for running
+// mock containers.
+func (m *MockManager) ContainerStats(id string) ([]common.ContainerStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := float64(time.Now().UnixNano()) / 1e9
+	var res []common.ContainerStats
+	for cid, c := range m.containers {
+		if (id != "" && cid != id) || c.Status != "running" {
+			continue
+		}
+		phase := float64(c.Created % 7)
+		up := uint64(t - float64(c.Created))
+		res = append(res, common.ContainerStats{
+			ID:         cid,
+			CPUPercent: 25 + 20*math.Sin(t/10+phase),
+			MemUsage:   uint64(512+256*math.Sin(t/30+phase)) << 20,
+			MemLimit:   2 << 30,
+			NetRx:      up * 4096,
+			NetTx:      up * 1024,
+			BlockRead:  up * 512,
+			BlockWrite: up * 2048,
+			PIDs:       20 + uint64(c.Created%10),
+		})
+	}
+	return res, nil
+}
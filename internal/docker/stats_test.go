@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestConvertStats(t *testing.T) {
+	var v container.StatsResponse
+	v.CPUStats.CPUUsage.TotalUsage = 3_000_000
+	v.CPUStats.SystemUsage = 20_000_000
+	v.CPUStats.OnlineCPUs = 4
+	v.PreCPUStats.CPUUsage.TotalUsage = 1_000_000
+	v.PreCPUStats.SystemUsage = 10_000_000
+	v.MemoryStats = container.MemoryStats{Usage: 600 << 20, Limit: 2 << 30, Stats: map[string]uint64{"inactive_file": 100 << 20}}
+	v.PidsStats.Current = 12
+	v.Networks = map[string]container.NetworkStats{
+		"eth0": {RxBytes: 1000, TxBytes: 200},
+		"eth1": {RxBytes: 24, TxBytes: 56},
+	}
+	v.BlkioStats.IoServiceBytesRecursive = []container.BlkioStatEntry{
+		{Op: "read", Value: 4096},
+		{Op: "Write", Value: 8192},
+		{Op: "Total", Value: 12288},
+	}
+
+	s := convertStats(&v)
+	// 2ms of 10ms system time across 4 CPUs
+	if s.CPUPercent != 80 {
+		t.Errorf("Expected 80%% CPU, got %v", s.CPUPercent)
+	}
+	if s.MemUsage != 500<<20 || s.MemLimit != 2<<30 {
+		t.Errorf("Unexpected memory %d/%d", s.MemUsage, s.MemLimit)
+	}
+	if s.NetRx != 1024 || s.NetTx != 256 {
+		t.Errorf("Unexpected network %d/%d", s.NetRx, s.NetTx)
+	}
+	if s.BlockRead != 4096 || s.BlockWrite != 8192 {
+		t.Errorf("Unexpected block IO %d/%d", s.BlockRead, s.BlockWrite)
+	}
+	if s.PIDs != 12 {
+		t.Errorf("Expected 12 PIDs, got %d", s.PIDs)
+	}
+}
+
+func TestConvertStatsFirstSample(t *testing.T) {
+	// Without a previous sample there is no delta to compare against
+	var v container.StatsResponse
+	v.CPUStats.CPUUsage.TotalUsage = 3_000_000
+	if s := convertStats(&v); s.CPUPercent != 0 {
+		t.Errorf("Expected 0%% CPU, got %v", s.CPUPercent)
+	}
+}
+
+func TestMemUsage(t *testing.T) {
+	m := container.MemoryStats{Usage: 300, Stats: map[string]uint64{"total_inactive_file": 100, "inactive_file": 50}}
+	if got := memUsage(m); got != 200 {
+		t.Errorf("Expected 200, got %d", got)
+	}
+	// Cache larger than usage is reported as is
+	m = container.MemoryStats{Usage: 50, Stats: map[string]uint64{"inactive_file": 100}}
+	if got := memUsage(m); got != 50 {
+		t.Errorf("Expected 50, got %d", got)
+	}
+}
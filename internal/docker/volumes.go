@@ -0,0 +1,214 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// resolveMounts validates the payload mounts and names default volumes
+// after the environment.
+func resolveMounts(env string, mounts []common.Mount) ([]common.Mount, error) {
+	seen := make(map[string]bool)
+	res := make([]common.Mount, 0, len(mounts))
+	for _, m := range mounts {
+		if !path.IsAbs(m.Target) {
+			return nil, fmt.Errorf("mount target %q must be an absolute path", m.Target)
+		}
+		if seen[m.Target] {
+			return nil, fmt.Errorf("duplicate mount target %q", m.Target)
+		}
+		seen[m.Target] = true
+
+		if m.Source == "" {
+			if env == "" {
+				return nil, fmt.Errorf("mount %s needs a source or an environment name", m.Target)
+			}
+			m.Source = common.DefaultVolumeName(env, m.Target)
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+func toDockerMounts(mounts []common.Mount) []mount.Mount {
+	var res []mount.Mount
+	for _, m := range mounts {
+		t := mount.TypeVolume
+		if m.IsBind() {
+			t = mount.TypeBind
+		}
+		res = append(res, mount.Mount{Type: t, Source: m.Source, Target: m.Target, ReadOnly: m.ReadOnly})
+	}
+	return res
+}
+
+func fromMountPoints(points []container.MountPoint) []common.Mount {
+	var res []common.Mount
+	for _, p := range points {
+		m := common.Mount{Target: p.Destination, ReadOnly: !p.RW}
+		switch p.Type {
+		case mount.TypeVolume:
+			m.Source = p.Name
+		case mount.TypeBind:
+			m.Source = p.Source
+		default:
+			continue
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+// fromDockerMounts is the inverse of toDockerMounts. Anonymous volumes
+// have no name to report and are skipped.
+func fromDockerMounts(mounts []mount.Mount) []common.Mount {
+	var res []common.Mount
+	for _, m := range mounts {
+		if m.Source == "" {
+			continue
+		}
+		res = append(res, common.Mount{Source: m.Source, Target: m.Target, ReadOnly: m.ReadOnly})
+	}
+	return res
+}
+
+// ensureVolumes creates missing named volumes with our labels so they show
+// up as managed. Existing volumes are used as they are.
+func (m *RealManager) ensureVolumes(ctx context.Context, env string, mounts []common.Mount) error {
+	for _, mt := range mounts {
+		if mt.IsBind() {
+			continue
+		}
+		if _, err := m.cli.VolumeInspect(ctx, mt.Source); err == nil {
+			continue
+		} else if !cerrdefs.IsNotFound(err) {
+			return err
+		}
+		_, err := m.cli.VolumeCreate(ctx, volume.CreateOptions{
+			Name:   mt.Source,
+			Labels: map[string]string{"perssh.managed": "true", "perssh.env": env},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create volume %s: %w", mt.Source, err)
+		}
+	}
+	return nil
+}
+
+// ListVolumes reports every volume with its size and the containers that
+// mount it. Sizes come from the df endpoint, which can be slow on hosts
+// with many volumes.
+func (m *RealManager) ListVolumes() ([]common.VolumeInfo, error) {
+	ctx := context.Background()
+	du, err := m.cli.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+	if err != nil {
+		return nil, err
+	}
+	containers, err := m.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string][]string)
+	for _, c := range containers {
+		name := c.ID[:12]
+		if len(c.Names) > 0 {
+			name = c.Names[0][1:]
+		}
+		for _, p := range c.Mounts {
+			if p.Type == mount.TypeVolume {
+				users[p.Name] = append(users[p.Name], name)
+			}
+		}
+	}
+
+	var res []common.VolumeInfo
+	for _, v := range du.Volumes {
+		info := common.VolumeInfo{
+			Name:    v.Name,
+			Driver:  v.Driver,
+			Size:    -1,
+			Managed: v.Labels["perssh.managed"] == "true",
+			UsedBy:  users[v.Name],
+		}
+		if v.UsageData != nil {
+			info.Size = v.UsageData.Size
+		}
+		res = append(res, info)
+	}
+	sortVolumes(res)
+	return res, nil
+}
+
+// ownedVolume reports whether a volume with labels was created by
+// ensureVolumes for one of envs, the names an environment went by.
+func ownedVolume(labels map[string]string, envs ...string) bool {
+	if labels["perssh.managed"] != "true" {
+		return false
+	}
+	for _, env := range envs {
+		if env != "" && labels["perssh.env"] == env {
+			return true
+		}
+	}
+	return false
+}
+
+// removeVolumes deletes the named volumes created for an environment after
+// its container is gone. Volumes that existed before, such as external
+// ones only referenced by name, and volumes still mounted by another
+// container are left alone.
+func (m *RealManager) removeVolumes(ctx context.Context, mounts []common.Mount, envs ...string) error {
+	for _, mt := range mounts {
+		if mt.IsBind() {
+			continue
+		}
+		v, err := m.cli.VolumeInspect(ctx, mt.Source)
+		if cerrdefs.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("environment removed, but volume %s was not: %w", mt.Source, err)
+		}
+		if !ownedVolume(v.Labels, envs...) {
+			continue
+		}
+		if err := m.cli.VolumeRemove(ctx, mt.Source, false); err != nil && !cerrdefs.IsConflict(err) && !cerrdefs.IsNotFound(err) {
+			return fmt.Errorf("environment removed, but volume %s was not: %w", mt.Source, err)
+		}
+	}
+	return nil
+}
+
+func sortVolumes(list []common.VolumeInfo) {
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+}
+
+func (m *MockManager) ListVolumes() ([]common.VolumeInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := make(map[string][]string)
+	for _, c := range m.containers {
+		for _, mt := range c.Mounts {
+			if !mt.IsBind() {
+				users[mt.Source] = append(users[mt.Source], c.Name)
+			}
+		}
+	}
+
+	var res []common.VolumeInfo
+	for name, owner := range m.volumes {
+		res = append(res, common.VolumeInfo{Name: name, Driver: "local", Managed: owner != "", UsedBy: users[name]})
+	}
+	sortVolumes(res)
+	return res, nil
+}
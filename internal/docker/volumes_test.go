@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+)
+
+func TestResolveMounts(t *testing.T) {
+	mounts, err := resolveMounts("survival", []common.Mount{
+		{Target: "/data"},
+		{Source: "/srv/mods", Target: "/mods", ReadOnly: true},
+		{Source: "shared-cache", Target: "/cache"},
+	})
+	if err != nil {
+		t.Fatalf("resolveMounts failed: %v", err)
+	}
+	if mounts[0].Source != "perssh-survival-data" || mounts[0].IsBind() {
+		t.Errorf("Unexpected default volume: %+v", mounts[0])
+	}
+	if !mounts[1].IsBind() || !mounts[1].ReadOnly {
+		t.Errorf("Expected read-only bind mount: %+v", mounts[1])
+	}
+	if mounts[2].IsBind() {
+		t.Errorf("Expected named volume: %+v", mounts[2])
+	}
+
+	for _, bad := range [][]common.Mount{
+		{{Target: "data"}},
+		{{Target: "/data"}, {Source: "x", Target: "/data"}},
+	} {
+		if _, err := resolveMounts("survival", bad); err == nil {
+			t.Errorf("Expected error for %+v", bad)
+		}
+	}
+}
+
+func TestMockRemoveKeepsSharedVolumes(t *testing.T) {
+	m := NewMockManager()
+	a, _ := m.CreateContainer(common.CreateEnvPayload{Name: "a", Mounts: []common.Mount{{Target: "/data"}, {Source: "shared", Target: "/shared"}}}, nil)
+	m.CreateContainer(common.CreateEnvPayload{Name: "b", Mounts: []common.Mount{{Source: "shared", Target: "/shared"}}}, nil)
+
+	vols, _ := m.ListVolumes()
+	if len(vols) != 2 || vols[1].Name != "shared" || len(vols[1].UsedBy) != 2 {
+		t.Fatalf("Unexpected volumes: %+v", vols)
+	}
+
+	if err := m.RemoveContainer(a, true); err != nil {
+		t.Fatal(err)
+	}
+	vols, _ = m.ListVolumes()
+	if len(vols) != 1 || vols[0].Name != "shared" {
+		t.Errorf("Expected only the shared volume to remain, got %+v", vols)
+	}
+}
+
+func TestOwnedVolume(t *testing.T) {
+	labels := map[string]string{"perssh.managed": "true", "perssh.env": "mc"}
+	if !ownedVolume(labels, "survival", "mc") {
+		t.Error("Expected a volume created under the old name to be owned")
+	}
+	if ownedVolume(labels, "web") || ownedVolume(labels, "") {
+		t.Error("Expected a volume of another environment not to be owned")
+	}
+	if ownedVolume(map[string]string{"perssh.env": "mc"}, "mc") || ownedVolume(nil, "mc") {
+		t.Error("Expected unmanaged volumes not to be owned")
+	}
+}
+
+func TestMockRemoveKeepsExternalVolumes(t *testing.T) {
+	m := NewMockManager()
+	// Created outside PerSSH, then mounted by name
+	m.volumes["world"] = ""
+	id, _ := m.CreateContainer(common.CreateEnvPayload{Name: "mc", Mounts: []common.Mount{{Target: "/data"}, {Source: "world", Target: "/world"}}}, nil)
+	m.RenameContainer(id, "survival")
+
+	if err := m.RemoveContainer(id, true); err != nil {
+		t.Fatal(err)
+	}
+	vols, _ := m.ListVolumes()
+	if len(vols) != 1 || vols[0].Name != "world" || vols[0].Managed {
+		t.Errorf("Expected only the external volume to remain, got %+v", vols)
+	}
+}
@@ -44,6 +44,16 @@ func (m *MinecraftModule) GetDefaults() common.CreateEnvPayload {
 		Type: common.EnvTypeMinecraft,
 		Image: "itzg/minecraft-server", // Popular image
 		Ports: []string{"25565:25565"},
+		// World, configs and mods live here; keep them across recreation
+		Mounts: []common.Mount{{Target: "/data"}},
+		// mc-health ships with the image and pings the server list
+		Health: &common.Healthcheck{
+			Test:        []string{"mc-health"},
+			Interval:    "30s",
+			Timeout:     "10s",
+			Retries:     3,
+			StartPeriod: "5m", // World generation and modpack installs take a while
+		},
 		Minecraft: common.MinecraftConfig{
 			EULA:       true,
 			ServerType: "VANILLA",
@@ -60,8 +70,22 @@ func (m *MinecraftModule) ParseLogs(logs string) map[string]interface{} {
 	}
 }
 
+// ComposeModule deploys a multi-container stack from a compose file. The
+// services are defined by the file; the payload only carries the type.
+type ComposeModule struct{}
+
+func (m *ComposeModule) Name() string { return "Compose Stack" }
+func (m *ComposeModule) Type() common.EnvironmentType { return common.EnvTypeCompose }
+func (m *ComposeModule) GetDefaults() common.CreateEnvPayload {
+	return common.CreateEnvPayload{Type: common.EnvTypeCompose}
+}
+func (m *ComposeModule) ParseLogs(logs string) map[string]interface{} {
+	return nil
+}
+
 // Registry to hold modules
 var Registry = []Module{
 	&StandardModule{},
 	&MinecraftModule{},
+	&ComposeModule{},
 }
@@ -24,6 +24,12 @@ func TestRegistry(t *testing.T) {
 			if defaults.Image != "itzg/minecraft-server" {
 				t.Errorf("Minecraft default image incorrect: %s", defaults.Image)
 			}
+			if len(defaults.Mounts) != 1 || defaults.Mounts[0].Target != "/data" {
+				t.Errorf("Minecraft should persist /data, got %+v", defaults.Mounts)
+			}
+			if defaults.Health == nil || len(defaults.Health.Test) == 0 {
+				t.Error("Minecraft should define a healthcheck")
+			}
 		}
 	}
 }
@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRejects(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "x * * * *", "@fortnightly"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2025, 1, 15, 10, 30, 20, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 4 * * *", time.Date(2025, 1, 16, 4, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2025, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"30 2 1,15 * *", time.Date(2025, 2, 1, 2, 30, 0, 0, time.UTC)},
+		{"0 12 * feb *", time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)},
+		{"10/20 11 * * *", time.Date(2025, 1, 15, 11, 10, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches (the 20th or a Friday)
+		{"0 0 20 * fri", time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	s, _ := Parse("0 0 30 2 *")
+	if got := s.Next(from); !got.IsZero() {
+		t.Errorf("Feb 30 should never run, got %v", got)
+	}
+}
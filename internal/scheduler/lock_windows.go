@@ -0,0 +1,21 @@
+//go:build windows
+
+package scheduler
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// lockFile only creates path on Windows. The agent runs on Linux hosts;
+// this keeps the package building everywhere.
+func lockFile(path string, wait bool) (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return func() { f.Close() }, nil
+}
@@ -0,0 +1,197 @@
+// Package scheduler runs jobs on cron schedules in the agent. Jobs live in
+// a file on the host; one agent at a time runs them, and runs missed while
+// none was running are caught up when the next one starts.
+package scheduler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+)
+
+const (
+	tickInterval = 15 * time.Second
+	retryLock    = time.Minute // How often waiting agents try to take over
+
+	// missedAfter is how late a run may be before it counts as missed
+	// rather than merely delayed by the tick.
+	missedAfter = time.Minute
+)
+
+// Runner performs a job and returns a short description of the result.
+type Runner func(job common.Job) (string, error)
+
+// Scheduler runs the jobs of a store.
+type Scheduler struct {
+	store *Store
+	run   Runner
+	now   func() time.Time
+
+	mu      sync.Mutex
+	running map[string]bool // Job IDs; a job never overlaps itself
+	wg      sync.WaitGroup  // Running jobs, manual runs included
+}
+
+func New(store *Store, run Runner) *Scheduler {
+	return &Scheduler{store: store, run: run, now: time.Now, running: make(map[string]bool)}
+}
+
+// Store returns the store the scheduler runs.
+func (s *Scheduler) Store() *Store {
+	return s.store
+}
+
+// Serve runs due jobs until stop is closed. Only one agent on the host
+// does so at a time; the others wait and take over when it exits.
+func (s *Scheduler) Serve(stop <-chan struct{}) {
+	lock := filepath.Join(filepath.Dir(s.store.path), "scheduler.lock")
+	for {
+		unlock, err := lockFile(lock, false)
+		if err == nil {
+			defer unlock()
+			break
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(retryLock):
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Scheduler running jobs from %s\n", s.store.path)
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		s.Tick()
+		select {
+		case <-stop:
+			s.wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick starts every job that is due and not still running. A job that
+// came due while no agent was running runs once, unless it skips missed
+// runs.
+func (s *Scheduler) Tick() {
+	now := s.now()
+	type dueJob struct {
+		job     common.Job
+		trigger string
+	}
+	var due []dueJob
+
+	err := s.store.update(func(jobs []common.Job) ([]common.Job, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		changed := false
+		for i := range jobs {
+			j := &jobs[i]
+			if j.Disabled {
+				continue
+			}
+			sched, err := Parse(j.Schedule)
+			if err != nil {
+				// Edited by hand; Save would have refused it
+				continue
+			}
+			next := sched.Next(now).Unix()
+			if j.NextRun == 0 {
+				j.NextRun = next
+				changed = true
+				continue
+			}
+			if j.NextRun > now.Unix() {
+				continue
+			}
+			trigger := common.TriggerSchedule
+			if now.Sub(time.Unix(j.NextRun, 0)) > missedAfter {
+				trigger = common.TriggerCatchUp
+			}
+			j.NextRun = next
+			changed = true
+			if (trigger == common.TriggerCatchUp && j.SkipMissed) || s.running[j.ID] {
+				continue
+			}
+			s.running[j.ID] = true
+			due = append(due, dueJob{*j, trigger})
+		}
+		if !changed {
+			// Most ticks; don't rewrite the file
+			return nil, nil
+		}
+		return jobs, nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Scheduler error: %v\n", err)
+		return
+	}
+
+	for _, d := range due {
+		s.wg.Add(1)
+		go func(d dueJob) {
+			defer s.wg.Done()
+			s.execute(d.job, d.trigger)
+		}(d)
+	}
+}
+
+// Wait blocks until the running jobs have finished.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// RunJob runs the job with id now, outside its schedule, and returns the
+// result.
+func (s *Scheduler) RunJob(id string) (common.JobRun, error) {
+	job, err := s.store.Get(id)
+	if err != nil {
+		return common.JobRun{}, err
+	}
+	s.mu.Lock()
+	if s.running[id] {
+		s.mu.Unlock()
+		return common.JobRun{}, fmt.Errorf("job %s is already running", job.Name)
+	}
+	s.running[id] = true
+	s.wg.Add(1)
+	s.mu.Unlock()
+	defer s.wg.Done()
+	return s.execute(job, common.TriggerManual), nil
+}
+
+// execute runs a job marked as running and records the result.
+func (s *Scheduler) execute(job common.Job, trigger string) common.JobRun {
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.ID)
+		s.mu.Unlock()
+	}()
+
+	start := s.now()
+	out, err := s.run(job)
+	run := common.JobRun{
+		Start:    start.Unix(),
+		Duration: s.now().Sub(start).Milliseconds(),
+		Trigger:  trigger,
+		Success:  err == nil,
+		Output:   out,
+	}
+	if err != nil {
+		run.Output = err.Error()
+	}
+	if len(run.Output) > outputLimit {
+		run.Output = run.Output[:outputLimit] + "..."
+	}
+	fmt.Fprintf(os.Stderr, "Job %s (%s): success=%v %s\n", job.Name, trigger, run.Success, run.Output)
+	if err := s.store.record(job.ID, run); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to record run of job %s: %v\n", job.Name, err)
+	}
+	return run
+}
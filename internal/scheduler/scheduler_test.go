@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+)
+
+// recorder is a Runner that remembers which jobs ran.
+type recorder struct {
+	mu   sync.Mutex
+	runs []string
+}
+
+func (r *recorder) run(job common.Job) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, job.Name)
+	if job.Command == "fail" {
+		return "", errors.New("failed")
+	}
+	return "done", nil
+}
+
+func TestTick(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "jobs.json"))
+	r := &recorder{}
+	s := New(store, r.run)
+	start := time.Date(2025, 1, 15, 10, 30, 0, 0, time.Local)
+
+	due, _ := store.Save(common.Job{Name: "due", Schedule: "31 10 * * *", Action: common.JobExec, Env: "mc", Command: "fail"}, start)
+	store.Save(common.Job{Name: "later", Schedule: "0 12 * * *", Action: common.JobPruneImages}, start)
+	store.Save(common.Job{Name: "off", Schedule: "31 10 * * *", Action: common.JobPruneImages, Disabled: true}, start)
+
+	s.now = func() time.Time { return start.Add(70 * time.Second) }
+	s.Tick()
+	s.Wait()
+	if len(r.runs) != 1 || r.runs[0] != "due" {
+		t.Fatalf("Expected only the due job to run, got %v", r.runs)
+	}
+	job, _ := store.Get(due.ID)
+	if len(job.History) != 1 || job.History[0].Success || job.History[0].Output != "failed" ||
+		job.History[0].Trigger != common.TriggerSchedule {
+		t.Errorf("Unexpected history %+v", job.History)
+	}
+	if job.NextRun != time.Date(2025, 1, 16, 10, 31, 0, 0, time.Local).Unix() {
+		t.Errorf("Next run not advanced: %v", time.Unix(job.NextRun, 0))
+	}
+
+	// A second tick in the same minute runs nothing
+	s.Tick()
+	s.Wait()
+	if len(r.runs) != 1 {
+		t.Errorf("Expected no new runs, got %v", r.runs)
+	}
+}
+
+func TestTickCatchesUp(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "jobs.json"))
+	r := &recorder{}
+	s := New(store, r.run)
+	start := time.Date(2025, 1, 15, 10, 30, 0, 0, time.Local)
+
+	catch, _ := store.Save(common.Job{Name: "catch", Schedule: "0 * * * *", Action: common.JobPruneImages}, start)
+	store.Save(common.Job{Name: "skip", Schedule: "0 * * * *", Action: common.JobPruneImages, SkipMissed: true}, start)
+
+	// No agent ran for a day; the job runs once, not 24 times
+	s.now = func() time.Time { return start.Add(24 * time.Hour) }
+	s.Tick()
+	s.Wait()
+	if len(r.runs) != 1 || r.runs[0] != "catch" {
+		t.Fatalf("Expected one catch-up run, got %v", r.runs)
+	}
+	job, _ := store.Get(catch.ID)
+	if job.History[0].Trigger != common.TriggerCatchUp || !job.History[0].Success {
+		t.Errorf("Unexpected run %+v", job.History[0])
+	}
+	jobs, _ := store.List()
+	for _, j := range jobs {
+		if j.NextRun != time.Date(2025, 1, 16, 11, 0, 0, 0, time.Local).Unix() {
+			t.Errorf("%s: next run %v", j.Name, time.Unix(j.NextRun, 0))
+		}
+	}
+}
+
+func TestRunJob(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "jobs.json"))
+	block := make(chan struct{})
+	started := make(chan struct{})
+	s := New(store, func(common.Job) (string, error) {
+		close(started)
+		<-block
+		return "restarted", nil
+	})
+	job, _ := store.Save(common.Job{Name: "restart", Schedule: "@daily", Action: common.JobRestart, Env: "mc"}, time.Now())
+
+	done := make(chan common.JobRun)
+	go func() {
+		run, _ := s.RunJob(job.ID)
+		done <- run
+	}()
+	<-started
+	if _, err := s.RunJob(job.ID); err == nil {
+		t.Error("Expected error running a job twice at once")
+	}
+	// An agent shutting down waits for manual runs too
+	waited := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Error("Wait returned while the job was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(block)
+	<-waited
+	if run := <-done; !run.Success || run.Output != "restarted" || run.Trigger != common.TriggerManual {
+		t.Errorf("Unexpected run %+v", run)
+	}
+	if _, err := s.RunJob("gone"); err == nil {
+		t.Error("Expected error for an unknown job")
+	}
+}
@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+)
+
+const (
+	historySize = 20   // Runs kept per job
+	outputLimit = 1000 // Bytes of output kept per run
+)
+
+// DefaultPath is where the agent keeps its jobs: ~/.perssh/jobs.json, next
+// to the backups.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".perssh", "jobs.json")
+}
+
+// Store keeps jobs in a JSON file. Every agent on the host may change it,
+// so each change holds a lock on the file and rereads it.
+type Store struct {
+	path string
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// List returns the stored jobs in the order they were created.
+func (s *Store) List() ([]common.Job, error) {
+	var res []common.Job
+	err := s.update(func(jobs []common.Job) ([]common.Job, error) {
+		res = jobs
+		return nil, nil
+	})
+	return res, err
+}
+
+// Get returns the job with id.
+func (s *Store) Get(id string) (common.Job, error) {
+	jobs, err := s.List()
+	if err != nil {
+		return common.Job{}, err
+	}
+	for _, j := range jobs {
+		if j.ID == id {
+			return j, nil
+		}
+	}
+	return common.Job{}, fmt.Errorf("no such job: %s", id)
+}
+
+// Save adds job, or changes the stored job with its ID. Only the fields a
+// user edits are taken; the next run is recomputed when the schedule
+// changes or the job is enabled again.
+func (s *Store) Save(job common.Job, now time.Time) (common.Job, error) {
+	sched, err := Validate(job)
+	if err != nil {
+		return common.Job{}, err
+	}
+
+	var saved common.Job
+	err = s.update(func(jobs []common.Job) ([]common.Job, error) {
+		if job.ID == "" {
+			job.ID = newJobID()
+			job.NextRun = sched.Next(now).Unix()
+			job.History = nil
+			saved = job
+			return append(jobs, job), nil
+		}
+		for i := range jobs {
+			j := &jobs[i]
+			if j.ID != job.ID {
+				continue
+			}
+			if j.Schedule != job.Schedule || (j.Disabled && !job.Disabled) || j.NextRun == 0 {
+				j.NextRun = sched.Next(now).Unix()
+			}
+			j.Name, j.Schedule, j.Action = job.Name, job.Schedule, job.Action
+			j.Env, j.Command, j.All = job.Env, job.Command, job.All
+			j.Disabled, j.SkipMissed = job.Disabled, job.SkipMissed
+			saved = *j
+			return jobs, nil
+		}
+		return nil, fmt.Errorf("no such job: %s", job.ID)
+	})
+	return saved, err
+}
+
+// Delete removes the job with id.
+func (s *Store) Delete(id string) error {
+	return s.update(func(jobs []common.Job) ([]common.Job, error) {
+		for i, j := range jobs {
+			if j.ID == id {
+				return append(jobs[:i], jobs[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("no such job: %s", id)
+	})
+}
+
+// record adds a run to the history of the job with id. Jobs deleted while
+// running are left deleted.
+func (s *Store) record(id string, run common.JobRun) error {
+	return s.update(func(jobs []common.Job) ([]common.Job, error) {
+		for i := range jobs {
+			if jobs[i].ID == id {
+				h := append([]common.JobRun{run}, jobs[i].History...)
+				if len(h) > historySize {
+					h = h[:historySize]
+				}
+				jobs[i].History = h
+				return jobs, nil
+			}
+		}
+		return nil, nil
+	})
+}
+
+// update calls fn with the stored jobs while holding the lock, and writes
+// what it returns unless that is nil.
+func (s *Store) update(fn func([]common.Job) ([]common.Job, error)) error {
+	unlock, err := lockFile(s.path+".lock", true)
+	if err != nil {
+		return fmt.Errorf("failed to lock jobs: %w", err)
+	}
+	defer unlock()
+
+	var jobs []common.Job
+	b, err := os.ReadFile(s.path)
+	if err == nil {
+		if err := json.Unmarshal(b, &jobs); err != nil {
+			return fmt.Errorf("failed to read %s: %w", s.path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	jobs, err = fn(jobs)
+	if err != nil || jobs == nil {
+		return err
+	}
+
+	// Write a copy and rename it, so a crash never leaves half a file
+	b, err = json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Validate checks a job before it is saved and returns its schedule.
+func Validate(job common.Job) (Schedule, error) {
+	if strings.TrimSpace(job.Name) == "" {
+		return Schedule{}, fmt.Errorf("job name cannot be empty")
+	}
+	sched, err := Parse(job.Schedule)
+	if err != nil {
+		return Schedule{}, err
+	}
+	if sched.Next(time.Now()).IsZero() {
+		return Schedule{}, fmt.Errorf("schedule %q never runs", job.Schedule)
+	}
+	switch job.Action {
+	case common.JobPruneImages:
+		return sched, nil
+	case common.JobRestart, common.JobBackup:
+	case common.JobExec, common.JobInput:
+		if strings.TrimSpace(job.Command) == "" {
+			return Schedule{}, fmt.Errorf("%s jobs need a command", job.Action)
+		}
+	default:
+		return Schedule{}, fmt.Errorf("unknown action %q", job.Action)
+	}
+	if job.Env == "" {
+		return Schedule{}, fmt.Errorf("%s jobs need an environment", job.Action)
+	}
+	return sched, nil
+}
+
+func newJobID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
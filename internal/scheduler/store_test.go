@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+)
+
+func TestValidate(t *testing.T) {
+	ok := []common.Job{
+		{Name: "nightly", Schedule: "0 4 * * *", Action: common.JobRestart, Env: "mc"},
+		{Name: "say", Schedule: "@hourly", Action: common.JobInput, Env: "mc", Command: "say hi"},
+		{Name: "prune", Schedule: "@weekly", Action: common.JobPruneImages},
+	}
+	for _, j := range ok {
+		if _, err := Validate(j); err != nil {
+			t.Errorf("%s: %v", j.Name, err)
+		}
+	}
+	bad := []common.Job{
+		{Schedule: "@daily", Action: common.JobRestart, Env: "mc"},
+		{Name: "x", Schedule: "bad", Action: common.JobRestart, Env: "mc"},
+		{Name: "x", Schedule: "@daily", Action: common.JobRestart},
+		{Name: "x", Schedule: "@daily", Action: common.JobExec, Env: "mc"},
+		{Name: "x", Schedule: "@daily", Action: "reboot", Env: "mc"},
+		{Name: "x", Schedule: "0 0 31 2 *", Action: common.JobBackup, Env: "mc"},
+	}
+	for _, j := range bad {
+		if _, err := Validate(j); err == nil {
+			t.Errorf("%+v: expected error", j)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "perssh", "jobs.json"))
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.Local)
+
+	jobs, err := s.List()
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("Expected no jobs, got %v, %v", jobs, err)
+	}
+
+	job, err := s.Save(common.Job{Name: "nightly", Schedule: "0 4 * * *", Action: common.JobBackup, Env: "mc"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == "" || job.NextRun != time.Date(2025, 1, 16, 4, 0, 0, 0, time.Local).Unix() {
+		t.Errorf("Unexpected job %+v", job)
+	}
+	if err := s.record(job.ID, common.JobRun{Success: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Edits keep the history; a new schedule moves the next run
+	job.Schedule = "0 5 * * *"
+	job.History = nil
+	job, err = s.Save(job, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(job.History) != 1 || job.NextRun != time.Date(2025, 1, 16, 5, 0, 0, 0, time.Local).Unix() {
+		t.Errorf("Unexpected job after edit %+v", job)
+	}
+	if _, err := s.Save(common.Job{ID: "gone", Name: "x", Schedule: "@daily", Action: common.JobPruneImages}, now); err == nil {
+		t.Error("Expected error saving an unknown job")
+	}
+
+	for i := 0; i < historySize+5; i++ {
+		s.record(job.ID, common.JobRun{Start: int64(i)})
+	}
+	got, err := s.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.History) != historySize || got.History[0].Start != historySize+4 {
+		t.Errorf("Expected the newest %d runs, got %d starting at %d", historySize, len(got.History), got.History[0].Start)
+	}
+
+	if err := s.Delete(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(job.ID); err == nil {
+		t.Error("Expected error deleting twice")
+	}
+	if jobs, _ := s.List(); len(jobs) != 0 {
+		t.Errorf("Expected no jobs, got %+v", jobs)
+	}
+}
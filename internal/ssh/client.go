@@ -2,9 +2,14 @@ package ssh
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/sftp"
@@ -17,9 +22,34 @@ type RemoteInterface interface {
 	Connect() error
 	Close()
 	DeployAgent(localBinaryPath string) error
-	StartAgent() error
+	StartAgent(args ...string) error
 	SendRequest(req common.Request) error
 	GetStdout() io.Reader
+	// Dial opens a connection from the remote host (direct-tcpip).
+	Dial(network, addr string) (net.Conn, error)
+	// Listen accepts connections on the remote host (tcpip-forward).
+	Listen(network, addr string) (net.Listener, error)
+	// OpenShell starts an interactive TTY shell inside a container.
+	OpenShell(containerID string, cols, rows int, stdin io.Reader, stdout io.Writer) (Shell, error)
+	// Download and Upload copy files over SFTP.
+	Download(remotePath, localPath string) error
+	Upload(localPath, remotePath string) error
+}
+
+// JumpHost is an intermediate SSH server (bastion) the target is reached through.
+// Every hop authenticates and verifies its host key on its own.
+type JumpHost struct {
+	Host            string
+	User            string
+	Port            int
+	Auth            []ssh.AuthMethod
+	HostKeyCallback ssh.HostKeyCallback
+	// HostKeyAlgorithms are preferred, e.g. the types known for the hop.
+	HostKeyAlgorithms []string
+}
+
+func (j JumpHost) addr() string {
+	return net.JoinHostPort(j.Host, strconv.Itoa(j.Port))
 }
 
 type Client struct {
@@ -32,9 +62,76 @@ type Client struct {
 	Stdin    io.WriteCloser
 	Stdout   io.Reader
 	SFTP     *sftp.Client
+
+	// HostKeyCallback verifies the target host. Nil accepts any key.
+	HostKeyCallback ssh.HostKeyCallback
+	// HostKeyAlgorithms are preferred, e.g. the types known for the host.
+	HostKeyAlgorithms []string
+	// JumpHosts are dialed in order before the target (like ssh -J).
+	JumpHosts []JumpHost
+
+	hops      []*ssh.Client
+	sftpMu    sync.Mutex
+	agentArgs []string // Passed to shell sessions too, so they find the same engine
 }
 
 func NewClient(host, user string, port int, password string, keyPath string) (*Client, error) {
+	return &Client{
+		Host: host,
+		User: user,
+		Port: port,
+		Auth: authMethods(password, keyPath),
+	}, nil
+}
+
+// NewJumpHost builds a hop with its own credentials.
+func NewJumpHost(host, user string, port int, password string, keyPath string) JumpHost {
+	return JumpHost{
+		Host: host,
+		User: user,
+		Port: port,
+		Auth: authMethods(password, keyPath),
+	}
+}
+
+// ParseJumpSpec parses a comma separated ProxyJump list such as
+// "admin@bastion:2222,gw.internal". Missing ports default to 22 and
+// missing users are left empty for the caller to fill in.
+func ParseJumpSpec(spec string) ([]JumpHost, error) {
+	var hops []JumpHost
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		hop := JumpHost{Port: 22}
+		if i := strings.LastIndex(part, "@"); i >= 0 {
+			hop.User = part[:i]
+			part = part[i+1:]
+		}
+
+		host, portStr, err := net.SplitHostPort(part)
+		if err != nil {
+			// No port given
+			host = strings.Trim(part, "[]")
+		} else {
+			port, err := strconv.Atoi(portStr)
+			if err != nil || port <= 0 || port > 65535 {
+				return nil, fmt.Errorf("invalid port in jump host %q", part)
+			}
+			hop.Port = port
+		}
+		if host == "" {
+			return nil, fmt.Errorf("missing host in jump host %q", part)
+		}
+		hop.Host = host
+		hops = append(hops, hop)
+	}
+	return hops, nil
+}
+
+func authMethods(password string, keyPath string) []ssh.AuthMethod {
 	var authMethods []ssh.AuthMethod
 
 	if keyPath != "" {
@@ -59,31 +156,95 @@ func NewClient(host, user string, port int, password string, keyPath string) (*C
 		}))
 	}
 
-	return &Client{
-		Host: host,
-		User: user,
-		Port: port,
-		Auth: authMethods,
-	}, nil
+	return authMethods
+}
+
+func clientConfig(user string, auth []ssh.AuthMethod, hostKey ssh.HostKeyCallback, algos []string) *ssh.ClientConfig {
+	if hostKey == nil {
+		hostKey = ssh.InsecureIgnoreHostKey() // For prototype simplicity. Production should verify.
+	}
+	return &ssh.ClientConfig{
+		User:              user,
+		Auth:              auth,
+		HostKeyCallback:   hostKey,
+		HostKeyAlgorithms: algos,
+		Timeout:           10 * time.Second,
+	}
 }
 
 func (c *Client) Connect() error {
-	config := &ssh.ClientConfig{
-		User:            c.User,
-		Auth:            c.Auth,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // For prototype simplicity. Production should verify.
-		Timeout:         10 * time.Second,
+	// Walk the jump chain; each hop opens a direct-tcpip channel to the next.
+	var via *ssh.Client
+	for _, hop := range c.JumpHosts {
+		user := hop.User
+		if user == "" {
+			user = c.User
+		}
+		auth := hop.Auth
+		if len(auth) == 0 {
+			auth = c.Auth
+		}
+
+		client, err := dialVia(via, hop.addr(), clientConfig(user, auth, hop.HostKeyCallback, hop.HostKeyAlgorithms))
+		if err != nil {
+			c.closeHops()
+			return fmt.Errorf("jump host %s: %w", hop.addr(), err)
+		}
+		c.hops = append(c.hops, client)
+		via = client
 	}
 
-	addr := fmt.Sprintf("%s:%d", c.Host, c.Port)
-	client, err := ssh.Dial("tcp", addr, config)
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	client, err := dialVia(via, addr, clientConfig(c.User, c.Auth, c.HostKeyCallback, c.HostKeyAlgorithms))
 	if err != nil {
+		c.closeHops()
 		return err
 	}
 	c.Client = client
 	return nil
 }
 
+// ErrAuthFailed is wrapped by the error of a handshake the server refused
+// the credentials of. Retrying with the same ones can't succeed and may
+// lock the account.
+var ErrAuthFailed = errors.New("authentication failed")
+
+// authFailed marks err if it is a refused login. x/crypto only reports
+// that in the message.
+func authFailed(err error) error {
+	if err != nil && strings.Contains(err.Error(), "unable to authenticate") {
+		return fmt.Errorf("%w: %w", ErrAuthFailed, err)
+	}
+	return err
+}
+
+// dialVia runs the SSH handshake with addr, tunnelled through via when set.
+func dialVia(via *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if via == nil {
+		client, err := ssh.Dial("tcp", addr, config)
+		return client, authFailed(err)
+	}
+
+	conn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	ncc, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, authFailed(err)
+	}
+	return ssh.NewClient(ncc, chans, reqs), nil
+}
+
+// closeHops tears the jump chain down from the far end inwards.
+func (c *Client) closeHops() {
+	for i := len(c.hops) - 1; i >= 0; i-- {
+		c.hops[i].Close()
+	}
+	c.hops = nil
+}
+
 func (c *Client) Close() {
 	if c.Session != nil {
 		c.Session.Close()
@@ -94,6 +255,7 @@ func (c *Client) Close() {
 	if c.Client != nil {
 		c.Client.Close()
 	}
+	c.closeHops()
 }
 
 // DeployAgent uploads the perssh-server binary if needed.
@@ -127,13 +289,15 @@ func (c *Client) DeployAgent(localBinaryPath string) error {
 	return sftpClient.Chmod(remotePath, 0755)
 }
 
-// StartAgent runs the agent and pipes IO.
-func (c *Client) StartAgent() error {
+// StartAgent runs the agent with args and pipes IO. Args go through the
+// remote shell unquoted, so callers only pass fixed flags.
+func (c *Client) StartAgent(args ...string) error {
 	session, err := c.Client.NewSession()
 	if err != nil {
 		return err
 	}
 	c.Session = session
+	c.agentArgs = args
 
 	stdin, err := session.StdinPipe()
 	if err != nil {
@@ -149,7 +313,7 @@ func (c *Client) StartAgent() error {
 
 	// Run agent. We assume it's in the home dir or path we deployed to.
 	// We run it directly.
-	if err := session.Start("./perssh-server"); err != nil {
+	if err := session.Start(strings.Join(append([]string{"./perssh-server"}, args...), " ")); err != nil {
 		return err
 	}
 
@@ -171,3 +335,20 @@ func (c *Client) SendRequest(req common.Request) error {
 func (c *Client) GetStdout() io.Reader {
 	return c.Stdout
 }
+
+// Dial opens a TCP connection from the remote host, used for port forwards.
+func (c *Client) Dial(network, addr string) (net.Conn, error) {
+	if c.Client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	return c.Client.Dial(network, addr)
+}
+
+// Listen binds addr on the remote host, used for reverse forwards.
+// Binding non-loopback addresses requires GatewayPorts on the server.
+func (c *Client) Listen(network, addr string) (net.Listener, error) {
+	if c.Client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	return c.Client.Listen(network, addr)
+}
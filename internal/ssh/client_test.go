@@ -0,0 +1,113 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseJumpSpec(t *testing.T) {
+	hops, err := ParseJumpSpec("admin@bastion:2222, gw.internal ,ops@[fd00::1]:22")
+	if err != nil {
+		t.Fatalf("ParseJumpSpec failed: %v", err)
+	}
+	if len(hops) != 3 {
+		t.Fatalf("Expected 3 hops, got %d", len(hops))
+	}
+
+	if hops[0].User != "admin" || hops[0].Host != "bastion" || hops[0].Port != 2222 {
+		t.Errorf("Unexpected first hop: %+v", hops[0])
+	}
+	if hops[1].User != "" || hops[1].Host != "gw.internal" || hops[1].Port != 22 {
+		t.Errorf("Unexpected second hop: %+v", hops[1])
+	}
+	if hops[2].Host != "fd00::1" || hops[2].addr() != "[fd00::1]:22" {
+		t.Errorf("Unexpected IPv6 hop: %+v", hops[2])
+	}
+}
+
+func TestParseJumpSpecInvalid(t *testing.T) {
+	for _, spec := range []string{"bastion:abc", "user@:22", "host:70000"} {
+		if _, err := ParseJumpSpec(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestDialMarksAuthFailures(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &ssh.ServerConfig{PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+		return nil, errors.New("denied")
+	}}
+	server.AddHostKey(signer)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				ssh.NewServerConn(c, server)
+				c.Close()
+			}()
+		}
+	}()
+
+	config := clientConfig("user", []ssh.AuthMethod{ssh.Password("wrong")}, nil, nil)
+	if _, err := dialVia(nil, addr, config); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Expected an auth failure, got %v", err)
+	}
+	ln.Close()
+	if _, err := dialVia(nil, addr, config); err == nil || errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Expected a plain network error, got %v", err)
+	}
+}
+
+func TestOpenShellRejectsUnsafeIDs(t *testing.T) {
+	c := &Client{}
+	for _, id := range []string{"", "abc'; rm -rf ~; '", "-rf", "a b", "$(id)"} {
+		if _, err := c.OpenShell(id, 80, 24, nil, nil); err == nil || !strings.Contains(err.Error(), "invalid container ID") {
+			t.Errorf("Expected %q to be rejected, got %v", id, err)
+		}
+	}
+	if _, err := c.OpenShell("mc-1.data_2", 80, 24, nil, nil); err == nil || strings.Contains(err.Error(), "invalid") {
+		t.Errorf("Expected a valid ID to get past the check, got %v", err)
+	}
+}
+
+func TestLocalTransfer(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "backup.tar.gz")
+	os.WriteFile(src, []byte("data"), 0600)
+
+	c := NewLocalMockClient()
+	dst := filepath.Join(dir, "copy.tar.gz")
+	if err := c.Download(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(dst); string(b) != "data" {
+		t.Errorf("Unexpected content %q", b)
+	}
+	if _, err := os.Stat(dst + ".part"); !os.IsNotExist(err) {
+		t.Error("Temporary file left behind")
+	}
+	if err := c.Upload(src, dst); err == nil {
+		t.Error("Expected error overwriting an existing file")
+	}
+}
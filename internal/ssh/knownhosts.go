@@ -0,0 +1,102 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// DefaultKnownHostsPath is OpenSSH's ~/.ssh/known_hosts, so hosts trusted
+// by either tool are trusted by both.
+func DefaultKnownHostsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
+
+// ErrHostKeyChanged is wrapped by the error of a host whose key does not
+// match known_hosts. It may be an attack, so it is never retried.
+var ErrHostKeyChanged = errors.New("host key changed")
+
+// knownHostsMu serializes reading and appending to known_hosts files.
+var knownHostsMu sync.Mutex
+
+// KnownHostKeyAlgorithms returns the host key algorithms matching the keys
+// path lists for host, to ask the server for a key that can be checked.
+// It returns nil for hosts that aren't listed.
+func KnownHostKeyAlgorithms(path, host string, port int) []string {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	check, err := knownhosts.New(path)
+	if err != nil {
+		return nil
+	}
+	// A key no entry can match makes the error list the known ones
+	probe, _ := ssh.NewPublicKey(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)))
+	var keyErr *knownhosts.KeyError
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	if !errors.As(check(addr, &net.TCPAddr{}, probe), &keyErr) {
+		return nil
+	}
+	var algos []string
+	for _, k := range keyErr.Want {
+		if t := k.Key.Type(); t == ssh.KeyAlgoRSA {
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, t)
+		} else {
+			algos = append(algos, t)
+		}
+	}
+	return algos
+}
+
+// KnownHosts verifies host keys against the known_hosts file at path. A
+// host that isn't listed is trusted on first use and added, like
+// StrictHostKeyChecking=accept-new; a listed host with another key is
+// rejected.
+func KnownHosts(path string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsMu.Lock()
+		defer knownHostsMu.Unlock()
+
+		// Reread every time, so a key added for an earlier hop counts
+		check, err := knownhosts.New(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err == nil {
+			err = check(hostname, remote, key)
+			var keyErr *knownhosts.KeyError
+			switch {
+			case err == nil:
+				return nil
+			case errors.As(err, &keyErr) && len(keyErr.Want) > 0:
+				line := keyErr.Want[0]
+				return fmt.Errorf("%w: the key of %s does not match %s:%d; if the host was reinstalled, remove that line", ErrHostKeyChanged, hostname, line.Filename, line.Line)
+			case !errors.As(err, &keyErr):
+				// Revoked keys and the like
+				return err
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+		return err
+	}
+}
@@ -0,0 +1,66 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func hostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKnownHostsTrustsOnFirstUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ssh", "known_hosts")
+	check := KnownHosts(path)
+	bastion := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 22}
+	target := &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 2222}
+	a, b := hostKey(t), hostKey(t)
+
+	if err := check("bastion:22", bastion, a); err != nil {
+		t.Fatalf("First key should be trusted: %v", err)
+	}
+	if err := check("bastion:22", bastion, a); err != nil {
+		t.Errorf("Known key rejected: %v", err)
+	}
+	if err := check("bastion:22", bastion, b); !errors.Is(err, ErrHostKeyChanged) || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected a changed key to be rejected, got %v", err)
+	}
+
+	// Each hop has its own entry
+	if err := check("target:2222", target, b); err != nil {
+		t.Errorf("New host rejected: %v", err)
+	}
+	if err := check("target:2222", target, a); err == nil {
+		t.Error("Expected the bastion's key to be rejected for the target")
+	}
+
+	if algos := KnownHostKeyAlgorithms(path, "target", 2222); len(algos) != 1 || algos[0] != ssh.KeyAlgoED25519 {
+		t.Errorf("Unexpected algorithms for the target: %v", algos)
+	}
+	if algos := KnownHostKeyAlgorithms(path, "elsewhere", 22); algos != nil {
+		t.Errorf("Expected no algorithms for an unknown host: %v", algos)
+	}
+
+	data, _ := os.ReadFile(path)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 ||
+		!strings.HasPrefix(lines[0], "bastion ") || !strings.HasPrefix(lines[1], "[target]:2222 ") {
+		t.Errorf("Unexpected known_hosts:\n%s", data)
+	}
+}
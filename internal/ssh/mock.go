@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 
 	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/creack/pty"
 )
 
 // LocalMockClient runs the agent locally for testing purposes.
@@ -16,6 +18,8 @@ type LocalMockClient struct {
 	Stdin  io.WriteCloser
 	Stdout io.Reader
 	Cmd    *exec.Cmd
+
+	agentArgs []string
 }
 
 func NewLocalMockClient() *LocalMockClient {
@@ -36,7 +40,8 @@ func (c *LocalMockClient) DeployAgent(localBinaryPath string) error {
 	return nil // Already local
 }
 
-func (c *LocalMockClient) StartAgent() error {
+// agentPath locates the local perssh-server binary.
+func agentPath() string {
 	exe, _ := os.Executable()
 	binPath := filepath.Join(filepath.Dir(exe), "perssh-server")
 	
@@ -47,8 +52,14 @@ func (c *LocalMockClient) StartAgent() error {
 	if _, err := os.Stat(binPath); os.IsNotExist(err) {
 		binPath = "./perssh-server"
 	}
+	return binPath
+}
 
-	cmd := exec.Command(binPath)
+func (c *LocalMockClient) StartAgent(args ...string) error {
+	binPath := agentPath()
+
+	cmd := exec.Command(binPath, args...)
+	c.agentArgs = args
 	
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -86,3 +97,72 @@ func (c *LocalMockClient) SendRequest(req common.Request) error {
 func (c *LocalMockClient) GetStdout() io.Reader {
 	return c.Stdout
 }
+
+// Dial connects directly since the "remote" host is the local machine.
+func (c *LocalMockClient) Dial(network, addr string) (net.Conn, error) {
+	return net.Dial(network, addr)
+}
+
+// Listen binds locally since the "remote" host is the local machine.
+func (c *LocalMockClient) Listen(network, addr string) (net.Listener, error) {
+	return net.Listen(network, addr)
+}
+
+// Download copies a local file, since the "remote" host is the local machine.
+func (c *LocalMockClient) Download(remotePath, localPath string) error {
+	src, err := os.Open(remotePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return saveLocal(localPath, src)
+}
+
+// Upload copies a local file, since the "remote" host is the local machine.
+func (c *LocalMockClient) Upload(localPath, remotePath string) error {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return saveLocal(remotePath, src)
+}
+
+type ptyShell struct {
+	cmd  *exec.Cmd
+	ptmx *os.File
+	done chan struct{}
+}
+
+// OpenShell runs the local agent in shell mode on a local pty, mirroring
+// what the SSH client does on the remote host.
+func (c *LocalMockClient) OpenShell(containerID string, cols, rows int, stdin io.Reader, stdout io.Writer) (Shell, error) {
+	args := append(append([]string{}, c.agentArgs...), "-shell", containerID)
+	cmd := exec.Command(agentPath(), args...)
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start local shell: %w", err)
+	}
+
+	s := &ptyShell{cmd: cmd, ptmx: ptmx, done: make(chan struct{})}
+	go io.Copy(ptmx, stdin)
+	go func() {
+		io.Copy(stdout, ptmx)
+		close(s.done)
+	}()
+	return s, nil
+}
+
+func (s *ptyShell) Resize(cols, rows int) error {
+	return pty.Setsize(s.ptmx, &pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)})
+}
+
+func (s *ptyShell) Wait() error {
+	err := s.cmd.Wait()
+	<-s.done
+	return err
+}
+
+func (s *ptyShell) Close() error {
+	return s.ptmx.Close()
+}
@@ -0,0 +1,87 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Shell is an interactive terminal session into a container.
+type Shell interface {
+	// Resize forwards a terminal size change.
+	Resize(cols, rows int) error
+	// Wait blocks until the remote shell exits.
+	Wait() error
+	Close() error
+}
+
+// containerIDPattern matches container IDs and names, which are safe to
+// put on the remote command line unquoted.
+var containerIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+type sshShell struct {
+	session *ssh.Session
+}
+
+// OpenShell requests a pty and runs the agent in shell mode, which bridges
+// it to a docker exec TTY on the host.
+func (c *Client) OpenShell(containerID string, cols, rows int, stdin io.Reader, stdout io.Writer) (Shell, error) {
+	if !containerIDPattern.MatchString(containerID) {
+		return nil, fmt.Errorf("invalid container ID %q", containerID)
+	}
+	if c.Client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	session, err := c.Client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty("xterm-256color", rows, cols, modes); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	// Copy stdin ourselves: Session.Wait would otherwise block on it
+	in, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	session.Stdout = stdout
+	session.Stderr = stdout
+
+	cmd := append(append([]string{"./perssh-server"}, c.agentArgs...), "-shell", containerID)
+	if err := session.Start(strings.Join(cmd, " ")); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	go func() {
+		io.Copy(in, stdin)
+		in.Close()
+	}()
+
+	return &sshShell{session: session}, nil
+}
+
+func (s *sshShell) Resize(cols, rows int) error {
+	return s.session.WindowChange(rows, cols)
+}
+
+func (s *sshShell) Wait() error {
+	return s.session.Wait()
+}
+
+func (s *sshShell) Close() error {
+	return s.session.Close()
+}
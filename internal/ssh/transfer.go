@@ -0,0 +1,101 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/sftp"
+)
+
+// sftpClient returns the SFTP session of the connection, opening one if
+// the agent was not deployed over it.
+func (c *Client) sftpClient() (*sftp.Client, error) {
+	c.sftpMu.Lock()
+	defer c.sftpMu.Unlock()
+	if c.SFTP != nil {
+		return c.SFTP, nil
+	}
+	if c.Client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	s, err := sftp.NewClient(c.Client)
+	if err != nil {
+		return nil, err
+	}
+	c.SFTP = s
+	return s, nil
+}
+
+// Download copies a file from the remote host. Existing local files are
+// not overwritten.
+func (c *Client) Download(remotePath, localPath string) error {
+	s, err := c.sftpClient()
+	if err != nil {
+		return err
+	}
+	src, err := s.Open(remotePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return saveLocal(localPath, src)
+}
+
+// Upload copies a local file to the remote host under a temporary name
+// first, so the agent never sees half a file. Existing remote files are
+// not overwritten.
+func (c *Client) Upload(localPath, remotePath string) error {
+	s, err := c.sftpClient()
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if _, err := s.Stat(remotePath); err == nil {
+		return fmt.Errorf("%s already exists on the host", remotePath)
+	}
+	part := remotePath + ".part"
+	dst, err := s.Create(part)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = s.Rename(part, remotePath)
+	}
+	if err != nil {
+		s.Remove(part)
+	}
+	return err
+}
+
+// saveLocal writes r to path through a temporary file.
+func saveLocal(path string, r io.Reader) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	part := path + ".part"
+	f, err := os.Create(part)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(part, path)
+	}
+	if err != nil {
+		os.Remove(part)
+	}
+	return err
+}
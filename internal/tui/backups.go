@@ -0,0 +1,356 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// backupAction is the backups screen action awaiting confirmation.
+type backupAction int
+
+const (
+	backupNone backupAction = iota
+	backupStop              // Stop the environment for the backup
+	backupRestore
+	backupDelete
+)
+
+// backupTransferMsg reports the end of a download or upload.
+type backupTransferMsg struct {
+	done string // Shown on success
+	err  error
+}
+
+// openBackups switches to the backups screen of the environment in the
+// details screen.
+func (m *Model) openBackups() tea.Cmd {
+	m.state = stateBackups
+	m.backupsLoading = true
+	m.backupConfirm = backupNone
+	m.backupsErr = ""
+	m.backupsMsg = ""
+	m.backupInput.Blur()
+	return m.cmdListBackups()
+}
+
+// --- Backups ---
+func (m Model) updateBackups(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		if _, ok := msg.(logTickMsg); ok {
+			// Keep the details screen's log polling alive for the way back
+			return m, m.cmdPollLogsTick()
+		}
+		return m, m.pollCmd(msg)
+	}
+
+	if m.backupInput.Focused() {
+		switch key.String() {
+		case "esc":
+			m.backupInput.Blur()
+			return m, nil
+		case "enter":
+			local := strings.TrimSpace(m.backupInput.Value())
+			m.backupInput.Blur()
+			if local == "" {
+				return m, nil
+			}
+			if !strings.HasSuffix(local, ".tar.gz") {
+				m.backupsErr = "Backups are .tar.gz files"
+				return m, nil
+			}
+			if m.backupDir == "" {
+				m.backupsErr = "The backup list has not loaded yet"
+				return m, nil
+			}
+			return m, m.startTransfer("Uploading "+filepath.Base(local), m.cmdUpload(local))
+		}
+		var cmd tea.Cmd
+		m.backupInput, cmd = m.backupInput.Update(msg)
+		return m, cmd
+	}
+
+	if m.backupConfirm != backupNone {
+		return m.updateBackupConfirm(key)
+	}
+
+	switch key.String() {
+	case "esc":
+		m.state = stateEnvDetails
+		return m, nil
+	case "l":
+		m.backupsLoading = true
+		return m, m.cmdListBackups()
+	case "up":
+		if m.backupCursor > 0 {
+			m.backupCursor--
+		}
+		return m, nil
+	case "down":
+		if m.backupCursor < len(m.backups)-1 {
+			m.backupCursor++
+		}
+		return m, nil
+	}
+
+	// Everything else waits for the running action
+	if m.backupBusy != "" {
+		return m, nil
+	}
+	switch key.String() {
+	case "n":
+		name := m.envName(m.selectedEnvID)
+		m.logger.Audit("Backing up %s", name)
+		return m, m.startBackupAction("Backing up "+name, "Backed up "+name,
+			common.CmdBackupEnv, common.BackupEnvPayload{ID: m.selectedEnvID})
+	case "s":
+		m.backupConfirm = backupStop
+		m.backupsErr = ""
+	case "r":
+		if m.backupCursor < len(m.backups) {
+			m.backupConfirm = backupRestore
+			m.backupsErr = ""
+		}
+	case "x":
+		if m.backupCursor < len(m.backups) {
+			m.backupConfirm = backupDelete
+			m.backupsErr = ""
+		}
+	case "d":
+		if m.backupCursor < len(m.backups) {
+			b := m.backups[m.backupCursor]
+			return m, m.startTransfer("Downloading "+b.Name, m.cmdDownload(b.Name))
+		}
+	case "u":
+		m.backupInput.SetValue("")
+		m.backupInput.Focus()
+		m.backupsErr = ""
+		return m, textinput.Blink
+	}
+	return m, nil
+}
+
+// updateBackupConfirm handles the prompt shown before stopping the
+// environment, restoring over its data or deleting a backup.
+func (m Model) updateBackupConfirm(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	action := m.backupConfirm
+	m.backupConfirm = backupNone
+	if key.String() != "y" {
+		return m, nil
+	}
+
+	name := m.envName(m.selectedEnvID)
+	switch action {
+	case backupStop:
+		m.logger.Audit("Backing up %s while stopped", name)
+		return m, m.startBackupAction("Stopping and backing up "+name, "Backed up "+name,
+			common.CmdBackupEnv, common.BackupEnvPayload{ID: m.selectedEnvID, Stop: true})
+	case backupRestore:
+		b := m.backups[m.backupCursor]
+		m.logger.Audit("Restoring backup %s into %s", b.Name, name)
+		return m, m.startBackupAction("Restoring "+b.Name+" into "+name, "Restored "+b.Name+" into "+name,
+			common.CmdRestoreBackup, common.RestoreBackupPayload{ID: m.selectedEnvID, Name: b.Name})
+	case backupDelete:
+		b := m.backups[m.backupCursor]
+		m.logger.Audit("Deleting backup %s", b.Name)
+		return m, m.startBackupAction("Deleting "+b.Name, "Deleted "+b.Name, common.CmdDeleteBackup, b.Name)
+	}
+	return m, nil
+}
+
+// startBackupAction sends a backup, restore or delete request; done is
+// shown when it succeeds.
+func (m *Model) startBackupAction(busy, done string, t common.CommandType, payload interface{}) tea.Cmd {
+	m.backupBusy = busy
+	m.backupDone = done
+	m.backupsErr = ""
+	m.backupsMsg = ""
+	client := m.sshClient
+	return func() tea.Msg {
+		if client != nil {
+			client.SendRequest(common.Request{ID: "backup", Type: t, Payload: payload})
+		}
+		return nil
+	}
+}
+
+func (m *Model) startTransfer(busy string, cmd tea.Cmd) tea.Cmd {
+	m.backupBusy = busy
+	m.backupsErr = ""
+	m.backupsMsg = ""
+	return cmd
+}
+
+// envName is the name of a listed environment, or its ID.
+func (m Model) envName(id string) string {
+	for _, c := range m.containers {
+		if c.ID == id {
+			return c.Name
+		}
+	}
+	return id
+}
+
+func (m *Model) handleBackupsResponse(msg common.Response) {
+	m.backupsLoading = false
+	if !msg.Success {
+		m.backupsErr = msg.Error
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var list common.BackupList
+	json.Unmarshal(b, &list)
+	m.backups = list.Backups
+	m.backupDir = list.Dir
+	if m.backupCursor >= len(list.Backups) {
+		m.backupCursor = 0
+	}
+}
+
+// handleBackupActionResponse reports a backup, restore or delete and
+// refreshes the list.
+func (m *Model) handleBackupActionResponse(msg common.Response) tea.Cmd {
+	m.backupBusy = ""
+	if !msg.Success {
+		m.backupsErr = msg.Error
+	} else {
+		m.backupsMsg = m.backupDone
+	}
+	m.backupsLoading = true
+	return m.cmdListBackups()
+}
+
+func (m *Model) handleBackupTransfer(msg backupTransferMsg) tea.Cmd {
+	m.backupBusy = ""
+	if msg.err != nil {
+		m.backupsErr = msg.err.Error()
+		return nil
+	}
+	m.backupsMsg = msg.done
+	m.backupsLoading = true
+	return m.cmdListBackups()
+}
+
+func (m Model) viewBackups() string {
+	b := strings.Builder{}
+	b.WriteString(styleGreen.Render("Backups") + styleDim.Render(" of "+m.envName(m.selectedEnvID)) + "\n")
+	if m.backupDir != "" {
+		b.WriteString(styleDim.Render("Stored in "+m.backupDir+" on the host") + "\n")
+	}
+	b.WriteString("\n")
+
+	if m.backupsLoading && len(m.backups) == 0 {
+		b.WriteString(styleDim.Render("Loading backups...") + "\n")
+	} else if len(m.backups) == 0 {
+		b.WriteString(styleDim.Render("(No backups)") + "\n")
+	}
+	own := m.envName(m.selectedEnvID)
+	for i, bk := range m.backups {
+		pref := "  "
+		if i == m.backupCursor {
+			pref = styleGreen.Render("> ")
+		}
+		name := fmt.Sprintf("%-40s", bk.Name)
+		if bk.Env != own {
+			// Restorable only if the mounts match
+			name = styleDim.Render(name)
+		}
+		env := bk.Env
+		if env == "" {
+			env = styleWarn.Render("unreadable")
+		}
+		created := time.Unix(bk.Created, 0).Format("2006-01-02 15:04")
+		b.WriteString(fmt.Sprintf("%s%s %9s  %s  %-16s %s\n", pref, name, formatBytes(uint64(bk.Size)), created, env,
+			styleDim.Render(strings.Join(bk.Targets, ", "))))
+	}
+
+	switch {
+	case m.backupInput.Focused():
+		b.WriteString("\nUpload backup: " + m.backupInput.View() + "\n")
+		b.WriteString(styleDim.Render("[Enter] Upload  [Esc] Cancel"))
+	case m.backupBusy != "":
+		b.WriteString("\n" + styleDim.Render(m.backupBusy+"..."))
+	case m.backupConfirm == backupStop:
+		b.WriteString(styleErr.Render(fmt.Sprintf("\nStop %s for the backup?", own)) + "\n")
+		b.WriteString(styleDim.Render("It is started again afterwards.  [Y] Stop and Back Up  [Esc] Cancel"))
+	case m.backupConfirm == backupRestore:
+		bk := m.backups[m.backupCursor]
+		b.WriteString(styleErr.Render(fmt.Sprintf("\nReplace the data of %s with %s?", own, bk.Name)) + "\n")
+		b.WriteString(styleDim.Render("The environment is stopped meanwhile.  [Y] Restore  [Esc] Cancel"))
+	case m.backupConfirm == backupDelete:
+		b.WriteString(styleErr.Render(fmt.Sprintf("\nDelete backup %s?", m.backups[m.backupCursor].Name)) + "\n")
+		b.WriteString(styleDim.Render("[Y] Delete  [Esc] Cancel"))
+	default:
+		b.WriteString(styleDim.Render("\n[N] Back Up  [S] Stop & Back Up  [R] Restore  [D] Download  [U] Upload  [X] Delete  [L] Refresh  [Esc] Back"))
+	}
+	if m.backupsMsg != "" {
+		b.WriteString(styleGreen.Render("\n" + m.backupsMsg))
+	}
+	if m.backupsErr != "" {
+		b.WriteString(styleErr.Render("\nError: " + m.backupsErr))
+	}
+	return styleBox.Render(b.String())
+}
+
+// downloadDir is where downloaded backups go: ~/Downloads if there is
+// one, else the working directory.
+func downloadDir() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		dir := filepath.Join(home, "Downloads")
+		if st, err := os.Stat(dir); err == nil && st.IsDir() {
+			return dir
+		}
+	}
+	return "."
+}
+
+func (m Model) cmdListBackups() tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "backups", Type: common.CmdListBackups})
+		}
+		return nil
+	}
+}
+
+// cmdDownload fetches a backup over SFTP. The host is always Unix, so
+// remote paths use forward slashes.
+func (m Model) cmdDownload(name string) tea.Cmd {
+	client, remote := m.sshClient, path.Join(m.backupDir, name)
+	local := filepath.Join(downloadDir(), name)
+	m.logger.Audit("Downloading backup %s to %s", name, local)
+	return func() tea.Msg {
+		if client == nil {
+			return backupTransferMsg{err: fmt.Errorf("not connected")}
+		}
+		if err := client.Download(remote, local); err != nil {
+			return backupTransferMsg{err: err}
+		}
+		return backupTransferMsg{done: "Saved to " + local}
+	}
+}
+
+// cmdUpload puts a backup into the agent's backup directory, where it can
+// be restored like any other.
+func (m Model) cmdUpload(local string) tea.Cmd {
+	client, remote := m.sshClient, path.Join(m.backupDir, filepath.Base(local))
+	m.logger.Audit("Uploading backup %s", local)
+	return func() tea.Msg {
+		if client == nil {
+			return backupTransferMsg{err: fmt.Errorf("not connected")}
+		}
+		if err := client.Upload(local, remote); err != nil {
+			return backupTransferMsg{err: err}
+		}
+		return backupTransferMsg{done: "Uploaded " + filepath.Base(local)}
+	}
+}
@@ -0,0 +1,217 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Update Env ---
+// The environment is recreated from the payload it was created with, with
+// the image, variables and ports edited here. Volumes and limits carry over.
+
+func (m *Model) startEdit(c common.ContainerInfo) tea.Cmd {
+	m.state = stateEditEnv
+	m.editID = c.ID
+	m.editName = c.Name
+	m.editLoading = true
+	m.editErr = ""
+	m.pull = pullState{}
+	for _, in := range []*textinput.Model{&m.editImage, &m.editEnv, &m.editPorts} {
+		in.SetValue("")
+		in.Blur()
+	}
+	return m.cmdGetEnvConfig(c.ID)
+}
+
+func (m Model) updateEditEnv(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok && !m.updating {
+		switch key.String() {
+		case "esc":
+			m.state = stateDashboard
+			return m, nil
+		case "tab":
+			switch {
+			case m.editImage.Focused():
+				m.editImage.Blur()
+				m.editEnv.Focus()
+			case m.editEnv.Focused():
+				m.editEnv.Blur()
+				m.editPorts.Focus()
+			default:
+				m.editPorts.Blur()
+				m.editImage.Focus()
+			}
+			return m, textinput.Blink
+		case "enter":
+			if m.editLoading {
+				return m, nil
+			}
+			payload, err := m.editedPayload()
+			if err != nil {
+				m.editErr = err.Error()
+				return m, nil
+			}
+			m.editErr = ""
+			m.updating = true
+			m.pull = pullState{}
+			m.logger.Audit("Updating %s to %s", m.editName, payload.Image)
+			return m, tea.Batch(m.cmdUpdateEnv(m.editID, payload), m.createSpinner.Tick)
+		}
+	}
+
+	var cmd, sCmd tea.Cmd
+	switch {
+	case m.editImage.Focused():
+		m.editImage, cmd = m.editImage.Update(msg)
+	case m.editEnv.Focused():
+		m.editEnv, cmd = m.editEnv.Update(msg)
+	case m.editPorts.Focused():
+		m.editPorts, cmd = m.editPorts.Update(msg)
+	}
+	if m.updating {
+		m.createSpinner, sCmd = m.createSpinner.Update(msg)
+	}
+	return m, tea.Batch(cmd, sCmd, m.pollCmd(msg))
+}
+
+// editedPayload applies the form to the stored payload.
+func (m Model) editedPayload() (common.CreateEnvPayload, error) {
+	p := m.editBase
+	p.Image = strings.TrimSpace(m.editImage.Value())
+	if p.Image == "" {
+		return p, fmt.Errorf("image is required")
+	}
+	env, err := parseEnv(m.editEnv.Value())
+	if err != nil {
+		return p, err
+	}
+	p.EnvVars = env
+	p.Ports = nil
+	for _, spec := range strings.Split(m.editPorts.Value(), ",") {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			p.Ports = append(p.Ports, spec)
+		}
+	}
+	p.Auth = m.registryAuth(p.Image)
+	return p, nil
+}
+
+func (m *Model) handleEnvConfigResponse(msg common.Response) {
+	m.editLoading = false
+	if !msg.Success {
+		m.editErr = msg.Error
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var p common.CreateEnvPayload
+	json.Unmarshal(b, &p)
+	m.editBase = p
+	m.editImage.SetValue(p.Image)
+	m.editEnv.SetValue(formatEnv(p.EnvVars))
+	m.editPorts.SetValue(strings.Join(p.Ports, ", "))
+	m.editImage.Focus()
+}
+
+func (m *Model) handleUpdateResponse(msg common.Response) {
+	m.updating = false
+	if !msg.Success {
+		m.editErr = msg.Error
+		return
+	}
+	m.logger.Audit("Updated %s", m.editName)
+	m.state = stateDashboard
+	if m.sshClient != nil {
+		m.sshClient.SendRequest(common.Request{ID: "list", Type: common.CmdListContainers})
+	}
+}
+
+func (m Model) viewEditEnv() string {
+	b := strings.Builder{}
+	b.WriteString(styleGreen.Render("Update "+m.editName) + "\n\n")
+
+	if m.editLoading {
+		b.WriteString(styleDim.Render("Loading configuration...") + "\n")
+	} else {
+		b.WriteString("Image:     " + m.editImage.View() + "\n")
+		b.WriteString("Variables: " + m.editEnv.View() + "\n")
+		b.WriteString("Ports:     " + m.editPorts.View() + "\n")
+		if len(m.editBase.Mounts) > 0 {
+			var mounts []string
+			for _, mt := range m.editBase.Mounts {
+				mounts = append(mounts, mt.Source+":"+mt.Target)
+			}
+			b.WriteString(styleDim.Render("Kept:      "+strings.Join(mounts, ", ")) + "\n")
+		}
+	}
+
+	if m.updating {
+		b.WriteString("\n" + m.createSpinner.View() + " Recreating...\n")
+		b.WriteString(m.viewPull())
+	} else {
+		b.WriteString(styleDim.Render("\n[Tab] Next Field  [Enter] Recreate  [Esc] Back"))
+	}
+	if m.editErr != "" {
+		b.WriteString(styleErr.Render("\nError: " + m.editErr))
+	}
+	return styleBox.Render(b.String())
+}
+
+// parseEnv reads "KEY=value; KEY2=value" pairs. Semicolons separate
+// entries since values often contain commas and spaces.
+func parseEnv(s string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("expected KEY=value, got %q", entry)
+		}
+		env[strings.TrimSpace(k)] = v
+	}
+	return env, nil
+}
+
+// formatEnv renders variables in the form parseEnv accepts, sorted by key.
+func formatEnv(env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + env[k]
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (m Model) cmdGetEnvConfig(id string) tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "envconfig", Type: common.CmdGetEnvConfig, Payload: id})
+		}
+		return nil
+	}
+}
+
+func (m Model) cmdUpdateEnv(id string, payload common.CreateEnvPayload) tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{
+				ID:      "update",
+				Type:    common.CmdUpdateEnv,
+				Payload: common.UpdateEnvPayload{ID: id, Payload: payload},
+			})
+		}
+		return nil
+	}
+}
@@ -0,0 +1,181 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// execOutputLines is how much of a command's output the details screen
+// shows; the full output goes to the log file.
+const execOutputLines = 12
+
+const execHelp = "[-u user] [-w dir] [-e KEY=value] [-t seconds] command"
+
+// parseExec reads the exec prompt. Leading options work like docker exec;
+// the rest runs through sh -c so pipes and globs work.
+func parseExec(line string) (common.ExecPayload, error) {
+	var p common.ExecPayload
+	rest := strings.TrimSpace(line)
+	for strings.HasPrefix(rest, "-") {
+		flag, after := cutWord(rest)
+		if flag == "--" {
+			rest = after
+			break
+		}
+		val, after := cutWord(after)
+		if val == "" {
+			return p, fmt.Errorf("%s needs a value", flag)
+		}
+		switch flag {
+		case "-u":
+			p.User = val
+		case "-w":
+			p.WorkDir = val
+		case "-e":
+			if !strings.Contains(val, "=") {
+				return p, fmt.Errorf("invalid variable %q, expected KEY=value", val)
+			}
+			p.Env = append(p.Env, val)
+		case "-t":
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				return p, fmt.Errorf("invalid timeout %q", val)
+			}
+			p.Timeout = n
+		default:
+			return p, fmt.Errorf("unknown option %s", flag)
+		}
+		rest = after
+	}
+	if rest == "" {
+		return p, fmt.Errorf("command is required")
+	}
+	p.Cmd = []string{"sh", "-c", rest}
+	return p, nil
+}
+
+// cutWord splits off the first space separated word.
+func cutWord(s string) (word, rest string) {
+	s = strings.TrimLeft(s, " ")
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], strings.TrimLeft(s[i+1:], " ")
+	}
+	return s, ""
+}
+
+// updateExecPrompt handles keys while the exec prompt is open.
+func (m Model) updateExecPrompt(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.String() {
+	case "esc":
+		m.execInput.Blur()
+		m.execErr = ""
+		return m, nil
+	case "enter":
+		p, err := parseExec(m.execInput.Value())
+		if err != nil {
+			m.execErr = err.Error()
+			return m, nil
+		}
+		p.ID = m.selectedEnvID
+		m.execInput.Blur()
+		m.execErr = ""
+		m.execResult = nil
+		m.execRunning = true
+		m.logger.Audit("Running in %s: %s", m.selectedEnvID, m.execInput.Value())
+		return m, m.cmdExec(p)
+	}
+
+	var cmd tea.Cmd
+	m.execInput, cmd = m.execInput.Update(key)
+	return m, cmd
+}
+
+// startExecPrompt opens the prompt with the previous command selected for
+// editing.
+func (m *Model) startExecPrompt() tea.Cmd {
+	m.execErr = ""
+	m.execInput.CursorEnd()
+	m.execInput.Focus()
+	return textinput.Blink
+}
+
+func (m *Model) handleExecResponse(msg common.Response) {
+	m.execRunning = false
+	if !msg.Success {
+		m.execErr = msg.Error
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var res common.ExecResult
+	json.Unmarshal(b, &res)
+	m.execResult = &res
+	m.logger.System("Exec in %s exited with %d:\n%s%s", m.selectedEnvID, res.ExitCode, res.Stdout, res.Stderr)
+}
+
+func (m Model) viewExec() string {
+	var b strings.Builder
+	if m.execInput.Focused() {
+		b.WriteString("Exec: " + m.execInput.View() + "\n")
+		b.WriteString(styleDim.Render(execHelp))
+	} else if m.execRunning {
+		b.WriteString(styleDim.Render("Running " + m.execInput.Value() + "..."))
+	} else if r := m.execResult; r != nil {
+		status := styleGreen.Render("exit 0")
+		switch {
+		case r.TimedOut && r.KillError != "":
+			status = styleErr.Render("timed out, " + r.KillError)
+		case r.TimedOut:
+			status = styleErr.Render("timed out")
+		case r.ExitCode != 0:
+			status = styleErr.Render(fmt.Sprintf("exit %d", r.ExitCode))
+		}
+		b.WriteString(fmt.Sprintf("$ %s  [%s]", m.execInput.Value(), status))
+		lines := outputLines(r.Stdout, styleDim.Render)
+		lines = append(lines, outputLines(r.Stderr, styleErr.Render)...)
+		if len(lines) > execOutputLines {
+			more := len(lines) - execOutputLines
+			lines = lines[len(lines)-execOutputLines:]
+			b.WriteString(styleDim.Render(fmt.Sprintf("  (%d earlier lines in the log file)", more)))
+		}
+		if r.Truncated {
+			b.WriteString(styleDim.Render("  (output truncated)"))
+		}
+		for _, l := range lines {
+			b.WriteString("\n" + l)
+		}
+	}
+	if m.execErr != "" {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(styleErr.Render("Exec failed: " + m.execErr))
+	}
+	return b.String()
+}
+
+func outputLines(s string, render func(...string) string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	var res []string
+	for _, l := range strings.Split(s, "\n") {
+		res = append(res, render(strings.TrimRight(l, "\r")))
+	}
+	return res
+}
+
+func (m Model) cmdExec(p common.ExecPayload) tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "exec", Type: common.CmdExec, Payload: p})
+		}
+		return nil
+	}
+}
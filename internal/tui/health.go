@@ -0,0 +1,177 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// envStatus renders the status column. Running environments with a
+// healthcheck show its state; unhealthy ones stand out from healthy ones.
+func envStatus(c common.ContainerInfo) (string, lipgloss.Style) {
+	if c.Status != "running" {
+		return c.Status, styleDim
+	}
+	switch c.Health {
+	case "unhealthy":
+		return "running, unhealthy", styleWarn
+	case "starting":
+		return "running, starting", styleGreen
+	case "healthy":
+		return "running, healthy", styleGreen
+	}
+	return c.Status, styleGreen
+}
+
+func (m *Model) handleHealthResponse(msg common.Response) {
+	if !msg.Success {
+		m.logger.Error("Health check failed: %s", msg.Error)
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var h common.HealthInfo
+	json.Unmarshal(b, &h)
+	m.health = h
+}
+
+// viewHealth is the health line of the details screen, empty without a
+// healthcheck.
+func (m Model) viewHealth() string {
+	h := m.health
+	if h.Status == "" {
+		return ""
+	}
+	style := styleGreen
+	if h.Status == "unhealthy" {
+		style = styleWarn
+	}
+	s := "Health: " + style.Render(h.Status)
+	if h.FailingStreak > 0 {
+		s += fmt.Sprintf(" (%d failed in a row)", h.FailingStreak)
+	}
+	if h.LastCheck > 0 {
+		s += styleDim.Render(fmt.Sprintf(" | last probe %s ago, exit %d", time.Since(time.Unix(h.LastCheck, 0)).Round(time.Second), h.LastExitCode))
+	}
+	if out := lastLine(h.LastOutput); out != "" {
+		if len(out) > 60 {
+			out = out[:57] + "..."
+		}
+		s += styleDim.Render(": " + out)
+	}
+	return s
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		s = s[i+1:]
+	}
+	return s
+}
+
+// cmdGetHealth asks for the health of the environment in the details
+// screen, if it has a healthcheck.
+func (m Model) cmdGetHealth() tea.Cmd {
+	hasCheck := false
+	for _, c := range m.containers {
+		if c.ID == m.selectedEnvID {
+			hasCheck = c.Health != ""
+		}
+	}
+	if !hasCheck {
+		return nil
+	}
+	id := m.selectedEnvID
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "health", Type: common.CmdGetHealth, Payload: id})
+		}
+		return nil
+	}
+}
+
+// parseHealthcheck reads the Health field of the create form: a shell
+// command followed by optional interval=, timeout=, retries= and start=
+// words, e.g. "curl -f http://localhost/ interval=30s retries=3". "none"
+// disables the image's check.
+func parseHealthcheck(s string) (*common.Healthcheck, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	if len(fields) == 1 && strings.EqualFold(fields[0], "none") {
+		return &common.Healthcheck{Test: []string{"NONE"}}, nil
+	}
+
+	h := &common.Healthcheck{}
+	end := len(fields)
+options:
+	for end > 0 {
+		k, v, ok := strings.Cut(fields[end-1], "=")
+		if !ok {
+			break
+		}
+		switch k {
+		case "interval":
+			h.Interval = v
+		case "timeout":
+			h.Timeout = v
+		case "start":
+			h.StartPeriod = v
+		case "retries":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid retries %q", v)
+			}
+			h.Retries = n
+		default:
+			// Part of the command, e.g. "test -f /tmp/ready=1"
+			break options
+		}
+		end--
+	}
+	if end == 0 {
+		return nil, fmt.Errorf("healthcheck needs a command before its options")
+	}
+	for _, d := range []string{h.Interval, h.Timeout, h.StartPeriod} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d); err != nil {
+			return nil, fmt.Errorf("invalid duration %q", d)
+		}
+	}
+	h.Test = []string{strings.Join(fields[:end], " ")}
+	return h, nil
+}
+
+// formatHealthcheck is the inverse of parseHealthcheck, used as the
+// placeholder that shows a module's default.
+func formatHealthcheck(h *common.Healthcheck) string {
+	if h == nil || len(h.Test) == 0 {
+		return ""
+	}
+	if len(h.Test) == 1 && strings.EqualFold(h.Test[0], "NONE") {
+		return "none"
+	}
+	parts := []string{strings.Join(h.Test, " ")}
+	if h.Interval != "" {
+		parts = append(parts, "interval="+h.Interval)
+	}
+	if h.Timeout != "" {
+		parts = append(parts, "timeout="+h.Timeout)
+	}
+	if h.Retries > 0 {
+		parts = append(parts, fmt.Sprintf("retries=%d", h.Retries))
+	}
+	if h.StartPeriod != "" {
+		parts = append(parts, "start="+h.StartPeriod)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,82 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/utils"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// hopLogin identifies the account on a jump host. Each hop has its own
+// password, never the target's.
+type hopLogin struct {
+	User, Host string
+}
+
+func (h hopLogin) String() string {
+	return h.User + "@" + h.Host
+}
+
+// needHopPasswordMsg asks for the password of a jump host that has none
+// in the keyring or entered this session.
+type needHopPasswordMsg struct{ hop hopLogin }
+
+// hopPassword returns the password entered for hop this session, else
+// the one in the keyring.
+func hopPassword(entered map[hopLogin]string, hop hopLogin) (string, bool) {
+	if pass, ok := entered[hop]; ok {
+		return pass, true
+	}
+	if stored, err := utils.GetPassword(hop.Host, hop.User); err == nil && stored != "" {
+		return stored, true
+	}
+	return "", false
+}
+
+// askHopPassword opens the prompt on the login screen. A reconnect can't
+// prompt, so it gives up on the session instead.
+func (m *Model) askHopPassword(hop hopLogin) tea.Cmd {
+	if m.state != stateLogin {
+		m.backToLogin(fmt.Sprintf("No password for jump host %s", hop))
+		return nil
+	}
+	m.loggingIn = false
+	m.hopPrompt = &hop
+	m.inputHopPass.SetValue("")
+	m.inputHopPass.Focus()
+	return textinput.Blink
+}
+
+// updateHopPrompt handles keys while a jump host password is asked for.
+func (m Model) updateHopPrompt(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "esc":
+			m.loginErr = fmt.Sprintf("No password for jump host %s", m.hopPrompt)
+			m.hopPrompt = nil
+			m.inputHopPass.Blur()
+			return m, nil
+		case "enter":
+			if m.inputHopPass.Value() == "" {
+				return m, nil
+			}
+			m.hopPasswords[*m.hopPrompt] = m.inputHopPass.Value()
+			m.hopPrompt = nil
+			m.inputHopPass.Blur()
+			m.loggingIn = true
+			return m, tea.Batch(m.loginSpinner.Tick, m.cmdLogin())
+		}
+	}
+	var cmd tea.Cmd
+	m.inputHopPass, cmd = m.inputHopPass.Update(msg)
+	return m, cmd
+}
+
+// storeHopPasswords keeps the passwords entered for jump hosts once they
+// worked, like the target's.
+func (m Model) storeHopPasswords() {
+	for hop, pass := range m.hopPasswords {
+		go utils.StorePassword(hop.Host, hop.User, pass)
+	}
+}
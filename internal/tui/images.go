@@ -0,0 +1,293 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// imageAction is the images screen action awaiting confirmation.
+type imageAction int
+
+const (
+	imageNone imageAction = iota
+	imageRemove
+	imagePrune    // Dangling images only
+	imagePruneAll // Every image no container uses
+)
+
+// --- Images ---
+func (m Model) updateImages(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, m.pollCmd(msg)
+	}
+
+	if m.imagePullInput.Focused() {
+		switch key.String() {
+		case "esc":
+			m.imagePullInput.Blur()
+			return m, nil
+		case "enter":
+			ref := strings.TrimSpace(m.imagePullInput.Value())
+			m.imagePullInput.Blur()
+			if ref == "" {
+				return m, nil
+			}
+			m.imagePulling = true
+			m.pull = pullState{}
+			m.imagesErr = ""
+			m.imagesMsg = ""
+			m.logger.Audit("Pulling image %s", ref)
+			return m, m.cmdImageAction("image", common.CmdPullImage, common.PullImagePayload{Image: ref, Auth: m.registryAuth(ref)})
+		}
+		var cmd tea.Cmd
+		m.imagePullInput, cmd = m.imagePullInput.Update(msg)
+		return m, cmd
+	}
+
+	if m.imageConfirm != imageNone {
+		return m.updateImageConfirm(key)
+	}
+
+	switch key.String() {
+	case "esc":
+		m.state = stateDashboard
+		return m, nil
+	case "l":
+		m.imagesLoading = true
+		return m, tea.Batch(m.cmdListImages(), m.cmdDiskUsage())
+	case "n":
+		if m.imagePulling {
+			return m, nil
+		}
+		m.imagePullInput.SetValue("")
+		m.imagePullInput.Focus()
+		return m, textinput.Blink
+	case "x":
+		if m.imageCursor < len(m.images) {
+			m.imageConfirm = imageRemove
+			m.imagesErr = ""
+		}
+	case "p":
+		m.imageConfirm = imagePrune
+		m.imagesErr = ""
+	case "a":
+		m.imageConfirm = imagePruneAll
+		m.imagesErr = ""
+	case "up":
+		if m.imageCursor > 0 {
+			m.imageCursor--
+		}
+	case "down":
+		if m.imageCursor < len(m.images)-1 {
+			m.imageCursor++
+		}
+	}
+	return m, nil
+}
+
+// updateImageConfirm handles the prompt shown before removing or pruning.
+// Force removal is only offered for single images.
+func (m Model) updateImageConfirm(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	action := m.imageConfirm
+	m.imageConfirm = imageNone
+	switch key.String() {
+	case "y":
+	case "f":
+		if action != imageRemove {
+			return m, nil
+		}
+	default:
+		return m, nil
+	}
+
+	m.imagesMsg = ""
+	if action == imageRemove {
+		img := m.images[m.imageCursor]
+		force := key.String() == "f"
+		m.logger.Audit("Removing image %s (%s, force %v)", img.ID, imageName(img), force)
+		return m, m.cmdImageAction("image", common.CmdRemoveImage, common.RemoveImagePayload{Image: img.ID, Force: force})
+	}
+	all := action == imagePruneAll
+	m.logger.Audit("Pruning images (all unused: %v)", all)
+	return m, m.cmdImageAction("prune", common.CmdPruneImages, common.PruneImagesPayload{All: all})
+}
+
+func (m *Model) handleImagesResponse(msg common.Response) {
+	m.imagesLoading = false
+	if !msg.Success {
+		m.imagesErr = msg.Error
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var list []common.ImageInfo
+	json.Unmarshal(b, &list)
+	m.images = list
+	if m.imageCursor >= len(list) {
+		m.imageCursor = 0
+	}
+}
+
+func (m *Model) handleDiskUsageResponse(msg common.Response) {
+	if !msg.Success {
+		m.imagesErr = msg.Error
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var du common.DiskUsage
+	json.Unmarshal(b, &du)
+	m.diskUsage = &du
+}
+
+// handleImageActionResponse reports a remove, pull or prune and refreshes
+// the screen.
+func (m *Model) handleImageActionResponse(msg common.Response) tea.Cmd {
+	if m.imagePulling && msg.ID == "image" {
+		m.imagePulling = false
+		if msg.Success {
+			m.imagesMsg = "Pulled " + m.imagePullInput.Value()
+		}
+	}
+	if !msg.Success {
+		m.imagesErr = msg.Error
+		return nil
+	}
+	if msg.ID == "prune" {
+		b, _ := json.Marshal(msg.Data)
+		var res common.PruneResult
+		json.Unmarshal(b, &res)
+		m.imagesMsg = fmt.Sprintf("Removed %d images, freed %s", res.Deleted, formatBytes(res.Reclaimed))
+	}
+	m.imagesErr = ""
+	m.imagesLoading = true
+	return tea.Batch(m.cmdListImages(), m.cmdDiskUsage())
+}
+
+// imageName is the first tag, or <none> for dangling images.
+func imageName(img common.ImageInfo) string {
+	if len(img.Tags) == 0 {
+		return "<none>"
+	}
+	return img.Tags[0]
+}
+
+func (m Model) viewImages() string {
+	b := strings.Builder{}
+	b.WriteString(styleGreen.Render("Images") + "\n\n")
+
+	if du := m.diskUsage; du != nil {
+		b.WriteString(styleDim.Render(fmt.Sprintf("%-14s %6s %7s %9s  %s", "TYPE", "TOTAL", "ACTIVE", "SIZE", "RECLAIMABLE")) + "\n")
+		for _, row := range []struct {
+			name string
+			e    common.UsageEntry
+		}{
+			{"Images", du.Images},
+			{"Containers", du.Containers},
+			{"Local Volumes", du.Volumes},
+			{"Build Cache", du.BuildCache},
+		} {
+			b.WriteString(fmt.Sprintf("%-14s %6d %7d %9s  %s\n", row.name, row.e.Total, row.e.Active,
+				formatBytes(uint64(row.e.Size)), formatReclaimable(row.e)))
+		}
+		b.WriteString("\n")
+	}
+
+	if m.imagesLoading && len(m.images) == 0 {
+		b.WriteString(styleDim.Render("Loading images...") + "\n")
+	} else if len(m.images) == 0 {
+		b.WriteString(styleDim.Render("(No images)") + "\n")
+	}
+	for i, img := range m.images {
+		pref := "  "
+		if i == m.imageCursor {
+			pref = styleGreen.Render("> ")
+		}
+		name := strings.Join(img.Tags, ", ")
+		if len(name) > 40 {
+			name = name[:37] + "..."
+		}
+		if img.Dangling {
+			name = styleDim.Render(fmt.Sprintf("%-40s", "<none>"))
+		} else {
+			name = fmt.Sprintf("%-40s", name)
+		}
+		users := styleDim.Render("unused")
+		if len(img.UsedBy) > 0 {
+			users = strings.Join(img.UsedBy, ", ")
+		}
+		created := time.Unix(img.Created, 0).Format("2006-01-02")
+		b.WriteString(fmt.Sprintf("%s%-12s %s %9s  %s  %s\n", pref, img.ID, name, formatBytes(uint64(img.Size)), created, users))
+	}
+
+	switch {
+	case m.imagePullInput.Focused():
+		b.WriteString("\nPull image: " + m.imagePullInput.View() + "\n")
+		b.WriteString(styleDim.Render("[Enter] Pull  [Esc] Cancel"))
+	case m.imagePulling:
+		b.WriteString("\n" + styleDim.Render("Pulling "+m.imagePullInput.Value()+"...") + "\n" + m.viewPull())
+	case m.imageConfirm == imageRemove:
+		img := m.images[m.imageCursor]
+		b.WriteString(styleErr.Render(fmt.Sprintf("\nRemove image %s (%s)?", img.ID, imageName(img))) + "\n")
+		b.WriteString(styleDim.Render("[Y] Remove  [F] Force, also if tagged twice or used by a stopped container  [Esc] Cancel"))
+	case m.imageConfirm == imagePrune:
+		b.WriteString(styleErr.Render("\nRemove all dangling images?") + "\n")
+		b.WriteString(styleDim.Render("[Y] Prune  [Esc] Cancel"))
+	case m.imageConfirm == imagePruneAll:
+		b.WriteString(styleErr.Render("\nRemove every image no container uses?") + "\n")
+		b.WriteString(styleDim.Render("They are pulled again when an environment needs them.  [Y] Prune  [Esc] Cancel"))
+	default:
+		b.WriteString(styleDim.Render("\n[N] Pull  [X] Remove  [P] Prune Dangling  [A] Prune Unused  [L] Refresh  [Esc] Back"))
+	}
+	if m.imagesMsg != "" {
+		b.WriteString(styleGreen.Render("\n" + m.imagesMsg))
+	}
+	if m.imagesErr != "" {
+		b.WriteString(styleErr.Render("\nError: " + m.imagesErr))
+	}
+	return styleBox.Render(b.String())
+}
+
+// formatReclaimable renders a reclaimable size with its share, like
+// docker system df.
+func formatReclaimable(e common.UsageEntry) string {
+	s := formatBytes(uint64(e.Reclaimable))
+	if e.Size > 0 {
+		s += fmt.Sprintf(" (%d%%)", e.Reclaimable*100/e.Size)
+	}
+	return s
+}
+
+func (m Model) cmdListImages() tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "images", Type: common.CmdListImages})
+		}
+		return nil
+	}
+}
+
+func (m Model) cmdDiskUsage() tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "df", Type: common.CmdDiskUsage})
+		}
+		return nil
+	}
+}
+
+// cmdImageAction sends a remove, prune or pull request. The reply uses
+// id so the screen can refresh itself.
+func (m Model) cmdImageAction(id string, t common.CommandType, payload interface{}) tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: id, Type: t, Payload: payload})
+		}
+		return nil
+	}
+}
@@ -0,0 +1,147 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// toggleInspect switches the details screen between logs and the inspect
+// tab, fetching the configuration each time the tab opens.
+func (m *Model) toggleInspect() tea.Cmd {
+	m.inspectView = !m.inspectView
+	if !m.inspectView {
+		return nil
+	}
+	m.inspectLoading = true
+	if m.inspect == nil {
+		m.inspectViewport.SetContent("Loading configuration...")
+	}
+	return m.cmdInspect(m.selectedEnvID)
+}
+
+func (m *Model) handleInspectResponse(msg common.Response) {
+	m.inspectLoading = false
+	if !msg.Success {
+		m.inspect = nil
+		m.inspectViewport.SetContent(styleErr.Render("Inspect failed: " + msg.Error))
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var info common.EnvInspect
+	json.Unmarshal(b, &info)
+	m.inspect = &info
+	m.inspectViewport.SetContent(formatInspect(info))
+}
+
+// formatInspect renders the inspect tab, one field per line and one line
+// per list entry.
+func formatInspect(info common.EnvInspect) string {
+	var b strings.Builder
+	field := func(name, value string) {
+		if value != "" {
+			b.WriteString(fmt.Sprintf("%-12s%s\n", name, value))
+		}
+	}
+	section := func(name string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		b.WriteString("\n" + styleGreen.Render(name) + "\n")
+		for _, l := range lines {
+			b.WriteString("  " + l + "\n")
+		}
+	}
+
+	field("Name", fmt.Sprintf("%s (%s)", info.Name, info.ID))
+	state := info.Status
+	if info.Status == "running" && info.StartedAt > 0 {
+		state += ", up since " + formatTime(info.StartedAt)
+	} else if info.FinishedAt > 0 {
+		state += fmt.Sprintf(", exit code %d at %s", info.ExitCode, formatTime(info.FinishedAt))
+	}
+	field("State", state)
+	field("Restarts", fmt.Sprintf("%d (policy %s)", info.RestartCount, info.RestartPolicy))
+	field("Created", formatTime(info.Created))
+	field("Image", info.Image)
+	field("Digest", info.ImageDigest)
+	field("Image ID", info.ImageID)
+	field("Entrypoint", formatCommand(info.Entrypoint))
+	field("Command", formatCommand(info.Command))
+	field("Workdir", info.WorkDir)
+	field("User", info.User)
+	limits := formatLimits(info.Resources)
+	if limits == "" {
+		limits = "none"
+	}
+	field("Limits", limits)
+
+	var ports []string
+	for _, p := range info.Ports {
+		ports = append(ports, formatPorts([]common.PortBinding{p}))
+	}
+	section("Ports", ports)
+
+	var mounts []string
+	for _, mt := range info.Mounts {
+		s := mt.Source + " -> " + mt.Target
+		if mt.ReadOnly {
+			s += " (read-only)"
+		}
+		mounts = append(mounts, s)
+	}
+	section("Mounts", mounts)
+
+	var networks []string
+	for _, n := range info.Networks {
+		s := fmt.Sprintf("%-20s %s", n.Name, n.IP)
+		if len(n.Aliases) > 0 {
+			s += styleDim.Render("  aliases " + strings.Join(n.Aliases, ", "))
+		}
+		networks = append(networks, s)
+	}
+	section("Networks", networks)
+
+	section("Environment", info.Env)
+
+	var labels []string
+	for k, v := range info.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	section("Labels", labels)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// formatCommand quotes arguments with spaces, like the docker CLI.
+func formatCommand(args []string) string {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\"'") {
+			a = fmt.Sprintf("%q", a)
+		}
+		parts[i] = a
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatTime(unix int64) string {
+	if unix <= 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).Format("2006-01-02 15:04:05")
+}
+
+func (m Model) cmdInspect(id string) tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "inspect", Type: common.CmdInspectEnv, Payload: id})
+		}
+		return nil
+	}
+}
@@ -0,0 +1,438 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// jobChoice is an entry of the action field in the job form.
+type jobChoice struct {
+	action common.JobAction
+	all    bool
+	label  string
+}
+
+var jobChoices = []jobChoice{
+	{common.JobRestart, false, "Restart"},
+	{common.JobBackup, false, "Back up"},
+	{common.JobExec, false, "Run command"},
+	{common.JobInput, false, "Console input"},
+	{common.JobPruneImages, false, "Prune dangling images"},
+	{common.JobPruneImages, true, "Prune unused images"},
+}
+
+// Fields of the job form, in tab order.
+const (
+	jobFieldName = iota
+	jobFieldSchedule
+	jobFieldAction
+	jobFieldEnv
+	jobFieldCommand
+	jobFieldMissed
+	jobFieldCount
+)
+
+// openJobs switches to the jobs screen.
+func (m *Model) openJobs() tea.Cmd {
+	m.state = stateJobs
+	m.jobsLoading = true
+	m.jobEditing = false
+	m.jobHistory = false
+	m.jobConfirm = false
+	m.jobsErr = ""
+	m.jobsMsg = ""
+	return m.cmdListJobs()
+}
+
+// --- Jobs ---
+func (m Model) updateJobs(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, m.pollCmd(msg)
+	}
+	if m.jobEditing {
+		return m.updateJobForm(msg, key)
+	}
+	if m.jobConfirm {
+		m.jobConfirm = false
+		if key.String() == "y" && m.jobCursor < len(m.jobs) {
+			j := m.jobs[m.jobCursor]
+			m.logger.Audit("Deleting job %s", j.Name)
+			return m, m.cmdJobAction("job", common.CmdDeleteJob, j.ID)
+		}
+		return m, nil
+	}
+	if m.jobHistory {
+		if key.String() == "esc" || key.String() == "enter" {
+			m.jobHistory = false
+		}
+		return m, nil
+	}
+
+	switch key.String() {
+	case "esc":
+		m.state = stateDashboard
+		return m, nil
+	case "l":
+		m.jobsLoading = true
+		return m, m.cmdListJobs()
+	case "up":
+		if m.jobCursor > 0 {
+			m.jobCursor--
+		}
+		return m, nil
+	case "down":
+		if m.jobCursor < len(m.jobs)-1 {
+			m.jobCursor++
+		}
+		return m, nil
+	case "n":
+		// Start from the environment selected on the dashboard
+		job := common.Job{Schedule: "0 4 * * *", Action: common.JobRestart}
+		if c, ok := m.selectedContainer(); ok {
+			job.Env = c.Name
+		}
+		return m, m.editJob(job)
+	}
+
+	if m.jobCursor >= len(m.jobs) {
+		return m, nil
+	}
+	j := m.jobs[m.jobCursor]
+	switch key.String() {
+	case "enter":
+		m.jobHistory = true
+	case "e":
+		return m, m.editJob(j)
+	case "x":
+		m.jobConfirm = true
+		m.jobsErr = ""
+	case "t":
+		j.Disabled = !j.Disabled
+		m.logger.Audit("Setting job %s disabled=%v", j.Name, j.Disabled)
+		return m, m.cmdJobAction("job", common.CmdSaveJob, j)
+	case "r":
+		if m.jobRunning != "" {
+			return m, nil
+		}
+		m.jobRunning = j.Name
+		m.jobsErr = ""
+		m.jobsMsg = ""
+		m.logger.Audit("Running job %s", j.Name)
+		return m, m.cmdJobAction("jobrun", common.CmdRunJob, j.ID)
+	}
+	return m, nil
+}
+
+// editJob opens the form for job; a job without ID is created on save.
+func (m *Model) editJob(job common.Job) tea.Cmd {
+	m.jobEditing = true
+	m.jobEdit = job
+	m.jobsErr = ""
+	m.jobsMsg = ""
+	m.jobName.SetValue(job.Name)
+	m.jobSchedule.SetValue(job.Schedule)
+	m.jobEnv.SetValue(job.Env)
+	m.jobCommand.SetValue(job.Command)
+	m.jobChoice = 0
+	for i, c := range jobChoices {
+		if c.action == job.Action && c.all == job.All {
+			m.jobChoice = i
+		}
+	}
+	m.focusJobField(jobFieldName)
+	return textinput.Blink
+}
+
+func (m Model) updateJobForm(msg tea.Msg, key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.String() {
+	case "esc":
+		m.jobEditing = false
+		m.focusJobField(-1)
+		return m, nil
+	case "tab", "down":
+		m.focusJobField((m.jobField + 1) % jobFieldCount)
+		return m, textinput.Blink
+	case "shift+tab", "up":
+		m.focusJobField((m.jobField + jobFieldCount - 1) % jobFieldCount)
+		return m, textinput.Blink
+	case "enter":
+		job := m.jobEdit
+		job.Name = strings.TrimSpace(m.jobName.Value())
+		job.Schedule = strings.TrimSpace(m.jobSchedule.Value())
+		c := jobChoices[m.jobChoice]
+		job.Action, job.All = c.action, c.all
+		job.Env, job.Command = "", ""
+		if c.action != common.JobPruneImages {
+			job.Env = strings.TrimSpace(m.jobEnv.Value())
+		}
+		if c.action == common.JobExec || c.action == common.JobInput {
+			job.Command = m.jobCommand.Value()
+		}
+		m.jobsErr = ""
+		m.logger.Audit("Saving job %s (%s %s)", job.Name, job.Schedule, job.Action)
+		return m, m.cmdJobAction("job", common.CmdSaveJob, job)
+	case "left", "right":
+		switch m.jobField {
+		case jobFieldAction:
+			if key.String() == "right" {
+				m.jobChoice = (m.jobChoice + 1) % len(jobChoices)
+			} else {
+				m.jobChoice = (m.jobChoice + len(jobChoices) - 1) % len(jobChoices)
+			}
+			return m, nil
+		case jobFieldMissed:
+			m.jobEdit.SkipMissed = !m.jobEdit.SkipMissed
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	switch m.jobField {
+	case jobFieldName:
+		m.jobName, cmd = m.jobName.Update(msg)
+	case jobFieldSchedule:
+		m.jobSchedule, cmd = m.jobSchedule.Update(msg)
+	case jobFieldEnv:
+		m.jobEnv, cmd = m.jobEnv.Update(msg)
+	case jobFieldCommand:
+		m.jobCommand, cmd = m.jobCommand.Update(msg)
+	}
+	return m, cmd
+}
+
+// focusJobField focuses field of the job form; -1 blurs them all.
+func (m *Model) focusJobField(field int) {
+	m.jobField = field
+	inputs := map[int]*textinput.Model{
+		jobFieldName:     &m.jobName,
+		jobFieldSchedule: &m.jobSchedule,
+		jobFieldEnv:      &m.jobEnv,
+		jobFieldCommand:  &m.jobCommand,
+	}
+	for f, in := range inputs {
+		if f == field {
+			in.Focus()
+		} else {
+			in.Blur()
+		}
+	}
+}
+
+func (m *Model) handleJobsResponse(msg common.Response) {
+	m.jobsLoading = false
+	if !msg.Success {
+		m.jobsErr = msg.Error
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var list []common.Job
+	json.Unmarshal(b, &list)
+	m.jobs = list
+	if m.jobCursor >= len(list) {
+		m.jobCursor = max(len(list)-1, 0)
+	}
+}
+
+// handleJobActionResponse reports a save, toggle, delete or run and
+// refreshes the list. A failed save keeps the form open.
+func (m *Model) handleJobActionResponse(msg common.Response) tea.Cmd {
+	if msg.ID == "jobrun" {
+		name := m.jobRunning
+		m.jobRunning = ""
+		if msg.Success {
+			b, _ := json.Marshal(msg.Data)
+			var run common.JobRun
+			json.Unmarshal(b, &run)
+			if run.Success {
+				m.jobsMsg = name + ": " + firstLine(run.Output)
+			} else {
+				m.jobsErr = name + " failed: " + firstLine(run.Output)
+			}
+		}
+	}
+	if !msg.Success {
+		m.jobsErr = msg.Error
+		return nil
+	}
+	if msg.ID == "job" && m.jobEditing {
+		m.jobEditing = false
+		m.focusJobField(-1)
+		m.jobsMsg = "Saved " + strings.TrimSpace(m.jobName.Value())
+	}
+	m.jobsLoading = true
+	return m.cmdListJobs()
+}
+
+// firstLine shortens job output for the status line.
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	if len(s) > 80 {
+		s = s[:77] + "..."
+	}
+	return s
+}
+
+// jobTarget describes what a job acts on.
+func jobTarget(j common.Job) string {
+	for _, c := range jobChoices {
+		if c.action == j.Action && c.all == j.All {
+			if j.Env == "" {
+				return c.label
+			}
+			return c.label + " " + j.Env
+		}
+	}
+	return string(j.Action) + " " + j.Env
+}
+
+func (m Model) viewJobs() string {
+	if m.jobEditing {
+		return m.viewJobForm()
+	}
+	if m.jobHistory && m.jobCursor < len(m.jobs) {
+		return m.viewJobHistory(m.jobs[m.jobCursor])
+	}
+
+	b := strings.Builder{}
+	b.WriteString(styleGreen.Render("Scheduled Jobs") + "\n")
+	b.WriteString(styleDim.Render("Run by the agent on the host, in the host's time zone") + "\n\n")
+
+	if m.jobsLoading && len(m.jobs) == 0 {
+		b.WriteString(styleDim.Render("Loading jobs...") + "\n")
+	} else if len(m.jobs) == 0 {
+		b.WriteString(styleDim.Render("(No jobs)") + "\n")
+	} else {
+		b.WriteString(styleDim.Render(fmt.Sprintf("  %-18s %-14s %-30s %-16s %s", "NAME", "SCHEDULE", "ACTION", "NEXT RUN", "LAST RUN")) + "\n")
+	}
+	for i, j := range m.jobs {
+		pref := "  "
+		if i == m.jobCursor {
+			pref = styleGreen.Render("> ")
+		}
+		next := styleDim.Render(fmt.Sprintf("%-16s", "disabled"))
+		if !j.Disabled && j.NextRun > 0 {
+			next = time.Unix(j.NextRun, 0).Format("2006-01-02 15:04")
+		}
+		last := styleDim.Render("never")
+		if len(j.History) > 0 {
+			r := j.History[0]
+			when := time.Unix(r.Start, 0).Format("01-02 15:04")
+			if r.Success {
+				last = styleGreen.Render("OK") + " " + when
+			} else {
+				last = styleErr.Render("FAILED") + " " + when
+			}
+		}
+		b.WriteString(fmt.Sprintf("%s%-18s %-14s %-30s %s %s\n", pref, j.Name, j.Schedule, jobTarget(j), next, last))
+	}
+
+	switch {
+	case m.jobRunning != "":
+		b.WriteString("\n" + styleDim.Render("Running "+m.jobRunning+"..."))
+	case m.jobConfirm && m.jobCursor < len(m.jobs):
+		b.WriteString(styleErr.Render(fmt.Sprintf("\nDelete job %s?", m.jobs[m.jobCursor].Name)) + "\n")
+		b.WriteString(styleDim.Render("Its history is deleted with it.  [Y] Delete  [Esc] Cancel"))
+	default:
+		b.WriteString(styleDim.Render("\n[N] New  [E] Edit  [Enter] History  [R] Run Now  [T] Enable/Disable  [X] Delete  [L] Refresh  [Esc] Back"))
+	}
+	if m.jobsMsg != "" {
+		b.WriteString(styleGreen.Render("\n" + m.jobsMsg))
+	}
+	if m.jobsErr != "" {
+		b.WriteString(styleErr.Render("\nError: " + m.jobsErr))
+	}
+	return styleBox.Render(b.String())
+}
+
+func (m Model) viewJobHistory(j common.Job) string {
+	b := strings.Builder{}
+	b.WriteString(styleGreen.Render("Runs of "+j.Name) + styleDim.Render(" ("+jobTarget(j)+", "+j.Schedule+")") + "\n\n")
+	if len(j.History) == 0 {
+		b.WriteString(styleDim.Render("(Not run yet)") + "\n")
+	}
+	for _, r := range j.History {
+		status := styleGreen.Render(fmt.Sprintf("%-6s", "OK"))
+		if !r.Success {
+			status = styleErr.Render("FAILED")
+		}
+		dur := (time.Duration(r.Duration) * time.Millisecond).Round(time.Second / 10)
+		b.WriteString(fmt.Sprintf("%s %s %-8s %8s  %s\n", time.Unix(r.Start, 0).Format("2006-01-02 15:04:05"), status,
+			r.Trigger, dur, firstLine(r.Output)))
+	}
+	b.WriteString(styleDim.Render("\n[Esc] Back"))
+	return styleBox.Render(b.String())
+}
+
+func (m Model) viewJobForm() string {
+	b := strings.Builder{}
+	title := "New Job"
+	if m.jobEdit.ID != "" {
+		title = "Edit Job"
+	}
+	b.WriteString(styleGreen.Render(title) + "\n\n")
+
+	field := func(f int, label, value string) {
+		pref := "  "
+		if m.jobField == f {
+			pref = styleGreen.Render("> ")
+		}
+		b.WriteString(fmt.Sprintf("%s%-13s %s\n", pref, label, value))
+	}
+	missed := "Run once when the agent starts again"
+	if m.jobEdit.SkipMissed {
+		missed = "Skip"
+	}
+	choice := jobChoices[m.jobChoice]
+
+	field(jobFieldName, "Name:", m.jobName.View())
+	field(jobFieldSchedule, "Schedule:", m.jobSchedule.View())
+	b.WriteString(styleDim.Render("                minute hour day month weekday, e.g. */30 * * * * or 0 4 * * mon-fri; or @hourly, @daily, @weekly") + "\n")
+	field(jobFieldAction, "Action:", "< "+choice.label+" >")
+	if choice.action != common.JobPruneImages {
+		field(jobFieldEnv, "Environment:", m.jobEnv.View())
+	} else {
+		field(jobFieldEnv, "Environment:", styleDim.Render("(host-wide)"))
+	}
+	switch choice.action {
+	case common.JobExec:
+		field(jobFieldCommand, "Command:", m.jobCommand.View())
+	case common.JobInput:
+		field(jobFieldCommand, "Console line:", m.jobCommand.View())
+	default:
+		field(jobFieldCommand, "Command:", styleDim.Render("(not used)"))
+	}
+	field(jobFieldMissed, "Missed runs:", "< "+missed+" >")
+
+	b.WriteString(styleDim.Render("\n[Tab] Next Field  [←/→] Change  [Enter] Save  [Esc] Cancel"))
+	if m.jobsErr != "" {
+		b.WriteString(styleErr.Render("\nError: " + m.jobsErr))
+	}
+	return styleBox.Render(b.String())
+}
+
+func (m Model) cmdListJobs() tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "jobs", Type: common.CmdListJobs})
+		}
+		return nil
+	}
+}
+
+// cmdJobAction sends a save, delete or run request. The reply uses id so
+// the screen can refresh itself.
+func (m Model) cmdJobAction(id string, t common.CommandType, payload interface{}) tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: id, Type: t, Payload: payload})
+		}
+		return nil
+	}
+}
@@ -0,0 +1,170 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/modules"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// envPrompt is the one-line prompt the dashboard shows for actions that
+// need an argument.
+type envPrompt int
+
+const (
+	promptNone envPrompt = iota
+	promptRename
+	promptKill
+	promptAdopt // Picks a module instead of reading the input
+)
+
+// updateEnvKeys handles the lifecycle keys on the dashboard. It reports
+// false for keys it does not own.
+func (m Model) updateEnvKeys(key tea.KeyMsg) (Model, tea.Cmd, bool) {
+	c, ok := m.selectedContainer()
+	if !ok {
+		return m, nil, false
+	}
+
+	switch key.String() {
+	case "r":
+		timeout := m.clientConfig.Docker.StopTimeout
+		m.logger.Audit("Restarting %s (timeout %ds)", c.Name, timeout)
+		return m, m.cmdEnvAction(common.CmdRestartEnv, common.RestartEnvPayload{ID: c.ID, Timeout: timeout}), true
+	case "p":
+		switch c.Status {
+		case "running":
+			m.logger.Audit("Pausing %s", c.Name)
+			return m, m.cmdEnvAction(common.CmdPauseEnv, c.ID), true
+		case "paused":
+			m.logger.Audit("Unpausing %s", c.Name)
+			return m, m.cmdEnvAction(common.CmdUnpauseEnv, c.ID), true
+		}
+		m.dashMsg = fmt.Sprintf("%s is not running", c.Name)
+		return m, nil, true
+	case "n":
+		m.envPrompt = promptRename
+		m.promptTarget = c
+		m.promptInput.SetValue(c.Name)
+	case "k":
+		m.envPrompt = promptKill
+		m.promptTarget = c
+		m.promptInput.SetValue("SIGHUP")
+	case "a":
+		if c.Managed() {
+			m.dashMsg = fmt.Sprintf("%s is already managed", c.Name)
+			return m, nil, true
+		}
+		m.envPrompt = promptAdopt
+		m.promptTarget = c
+		m.adoptType = 0
+		return m, nil, true
+	default:
+		return m, nil, false
+	}
+	m.promptInput.CursorEnd()
+	m.promptInput.Focus()
+	return m, textinput.Blink, true
+}
+
+// updateEnvPrompt handles keys while the rename, signal or adopt prompt
+// is open.
+func (m Model) updateEnvPrompt(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.envPrompt == promptAdopt {
+		return m.updateAdoptPrompt(key)
+	}
+	switch key.String() {
+	case "esc":
+		m.closeEnvPrompt()
+		return m, nil
+	case "enter":
+		c := m.promptTarget
+		val := m.promptInput.Value()
+		var cmd tea.Cmd
+		if m.envPrompt == promptRename {
+			if val == "" || val == c.Name {
+				m.closeEnvPrompt()
+				return m, nil
+			}
+			m.logger.Audit("Renaming %s to %s", c.Name, val)
+			cmd = m.cmdEnvAction(common.CmdRenameEnv, common.RenameEnvPayload{ID: c.ID, Name: val})
+		} else {
+			m.logger.Audit("Sending %s to %s", val, c.Name)
+			// The prompt warns about unmanaged containers
+			cmd = m.cmdEnvAction(common.CmdKillEnv, common.KillEnvPayload{ID: c.ID, Signal: val, Force: !c.Managed()})
+		}
+		m.closeEnvPrompt()
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.promptInput, cmd = m.promptInput.Update(key)
+	return m, cmd
+}
+
+// adoptModules are the types a single existing container can become.
+func adoptModules() []modules.Module {
+	var res []modules.Module
+	for _, mod := range modules.Registry {
+		if mod.Type() != common.EnvTypeCompose {
+			res = append(res, mod)
+		}
+	}
+	return res
+}
+
+func (m Model) updateAdoptPrompt(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	mods := adoptModules()
+	switch key.String() {
+	case "esc":
+		m.closeEnvPrompt()
+	case "left", "up":
+		m.adoptType = (m.adoptType + len(mods) - 1) % len(mods)
+	case "right", "down", "tab":
+		m.adoptType = (m.adoptType + 1) % len(mods)
+	case "enter":
+		c := m.promptTarget
+		mod := mods[m.adoptType]
+		m.closeEnvPrompt()
+		m.logger.Audit("Adopting %s as %s", c.Name, mod.Type())
+		m.dashMsg = ""
+		return m, m.cmdEnvAction(common.CmdAdoptEnv, common.AdoptEnvPayload{ID: c.ID, Type: mod.Type()})
+	}
+	return m, nil
+}
+
+func (m *Model) closeEnvPrompt() {
+	m.envPrompt = promptNone
+	m.promptInput.Blur()
+}
+
+func (m Model) viewEnvPrompt() string {
+	if m.envPrompt == promptAdopt {
+		mod := adoptModules()[m.adoptType]
+		return fmt.Sprintf("Adopt %s as: < %s >\n", m.promptTarget.Name, styleGreen.Render(mod.Name())) +
+			styleDim.Render("The container is recreated with the same settings and volumes.  [Left/Right] Type  [Enter] Adopt  [Esc] Cancel")
+	}
+	label := "Rename %s to: "
+	warn := ""
+	if m.envPrompt == promptKill {
+		label = "Signal for %s: "
+		if !m.promptTarget.Managed() {
+			warn = styleWarn.Render("This container was not created by PerSSH.") + "\n"
+		}
+	}
+	return warn + fmt.Sprintf(label, m.promptTarget.Name) + m.promptInput.View() + "\n" +
+		styleDim.Render("[Enter] Confirm  [Esc] Cancel")
+}
+
+// cmdEnvAction sends a lifecycle request. The "action" response refreshes
+// the list or shows the error.
+func (m Model) cmdEnvAction(t common.CommandType, payload interface{}) tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "action", Type: t, Payload: payload})
+		}
+		return nil
+	}
+}
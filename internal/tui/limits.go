@@ -0,0 +1,164 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Limits are edited as "key=value" pairs, e.g. "mem=2g cpus=1.5 pids=256".
+const limitsHelp = "mem=2g swap=4g cpus=1.5 cpuset=0-1 pids=256 disk=10g"
+
+// parseLimits reads the "key=value" form used by the limit prompts.
+func parseLimits(s string) (common.Resources, error) {
+	var r common.Resources
+	for _, field := range strings.Fields(s) {
+		key, val, ok := strings.Cut(field, "=")
+		if !ok || val == "" {
+			return r, fmt.Errorf("expected key=value, got %q", field)
+		}
+		switch strings.ToLower(key) {
+		case "mem", "memory":
+			r.Memory = val
+		case "swap":
+			r.MemorySwap = val
+		case "cpus":
+			n, err := strconv.ParseFloat(val, 64)
+			if err != nil || n < 0 {
+				return r, fmt.Errorf("invalid cpus %q", val)
+			}
+			r.CPUs = n
+		case "cpuset":
+			r.CpusetCPUs = val
+		case "pids":
+			n, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return r, fmt.Errorf("invalid pids %q", val)
+			}
+			r.PidsLimit = n
+		case "disk", "storage":
+			r.StorageSize = val
+		default:
+			return r, fmt.Errorf("unknown limit %q (known: mem, swap, cpus, cpuset, pids, disk)", key)
+		}
+	}
+	return r, nil
+}
+
+// formatLimits renders limits in the form parseLimits accepts.
+func formatLimits(r common.Resources) string {
+	var parts []string
+	if r.Memory != "" {
+		parts = append(parts, "mem="+r.Memory)
+	}
+	if r.MemorySwap != "" {
+		parts = append(parts, "swap="+r.MemorySwap)
+	}
+	if r.CPUs > 0 {
+		parts = append(parts, "cpus="+strconv.FormatFloat(r.CPUs, 'f', -1, 64))
+	}
+	if r.CpusetCPUs != "" {
+		parts = append(parts, "cpuset="+r.CpusetCPUs)
+	}
+	if r.PidsLimit > 0 {
+		parts = append(parts, fmt.Sprintf("pids=%d", r.PidsLimit))
+	}
+	if r.StorageSize != "" {
+		parts = append(parts, "disk="+r.StorageSize)
+	}
+	return strings.Join(parts, " ")
+}
+
+// updateLimitsPrompt handles keys while the limits prompt on the details
+// screen is open.
+func (m Model) updateLimitsPrompt(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.String() {
+	case "esc":
+		m.limitsInput.Blur()
+		m.limitsErr = ""
+		return m, nil
+	case "enter":
+		r, err := parseLimits(m.limitsInput.Value())
+		if err != nil {
+			m.limitsErr = err.Error()
+			return m, nil
+		}
+		m.limitsInput.Blur()
+		m.limitsErr = ""
+		m.logger.Audit("Updating limits of %s: %s", m.selectedEnvID, formatLimits(r))
+		return m, m.cmdUpdateLimits(m.selectedEnvID, r)
+	}
+
+	var cmd tea.Cmd
+	m.limitsInput, cmd = m.limitsInput.Update(key)
+	return m, cmd
+}
+
+// startLimitsPrompt opens the prompt prefilled with the current limits.
+func (m *Model) startLimitsPrompt() tea.Cmd {
+	m.limitsErr = ""
+	// Storage cannot change after creation, so it is not offered here
+	current := m.limits
+	current.StorageSize = ""
+	m.limitsInput.SetValue(formatLimits(current))
+	m.limitsInput.CursorEnd()
+	m.limitsInput.Focus()
+	return textinput.Blink
+}
+
+// handleLimitsResponse stores the effective limits reported by the agent.
+func (m *Model) handleLimitsResponse(msg common.Response) {
+	if !msg.Success {
+		m.limitsErr = msg.Error
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var r common.Resources
+	if json.Unmarshal(b, &r) == nil {
+		m.limits = r
+	}
+}
+
+func (m Model) viewLimits() string {
+	var line string
+	if m.limitsInput.Focused() {
+		line = "Limits: " + m.limitsInput.View()
+	} else {
+		s := formatLimits(m.limits)
+		if s == "" {
+			s = "none"
+		}
+		line = styleDim.Render("Limits: " + s)
+	}
+	if m.limitsErr != "" {
+		line += "  " + styleErr.Render(m.limitsErr)
+	}
+	return line
+}
+
+func (m Model) cmdGetLimits(id string) tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "limits", Type: common.CmdGetLimits, Payload: id})
+		}
+		return nil
+	}
+}
+
+func (m Model) cmdUpdateLimits(id string, r common.Resources) tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{
+				ID:      "limits",
+				Type:    common.CmdUpdateLimits,
+				Payload: common.UpdateLimitsPayload{ID: id, Resources: r},
+			})
+		}
+		return nil
+	}
+}
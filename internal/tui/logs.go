@@ -0,0 +1,88 @@
+package tui
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// olderLogsPage is how many lines each step back through the history loads.
+const olderLogsPage = 200
+
+// handleLogsResponse replaces the log view with the latest lines. Polling
+// and refreshing both end up here, so any history paged in is dropped.
+func (m *Model) handleLogsResponse(msg common.Response) {
+	m.logsLoading = false
+	if !msg.Success {
+		m.logsViewport.SetContent("Error fetching logs: " + msg.Error)
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var res common.LogsResult
+	if json.Unmarshal(b, &res) != nil {
+		return
+	}
+	m.logsText = res.Logs
+	m.logsOldest = res.Oldest
+	m.logsAtStart = res.AtStart
+	m.logsPaged = false
+	m.logsViewport.SetContent(m.logsText)
+	m.logsViewport.GotoBottom()
+}
+
+// cmdGetOlderLogs asks for the page of lines before the oldest one shown.
+// It does nothing while a page is on its way or once the start is reached.
+func (m *Model) cmdGetOlderLogs() tea.Cmd {
+	if m.logsPaging || m.logsAtStart || m.logsOldest == "" {
+		return nil
+	}
+	oldest, err := time.Parse(time.RFC3339Nano, m.logsOldest)
+	if err != nil {
+		return nil
+	}
+	m.logsPaging = true
+	q := common.LogsQuery{
+		ID:    m.selectedEnvID,
+		Tail:  olderLogsPage,
+		Until: oldest.Add(-time.Nanosecond).Format(time.RFC3339Nano),
+	}
+	client := m.sshClient
+	return func() tea.Msg {
+		if client != nil {
+			client.SendRequest(common.Request{ID: "logsolder", Type: common.CmdGetLogs, Payload: q})
+		}
+		return nil
+	}
+}
+
+// handleOlderLogsResponse puts a page of history above the lines shown,
+// keeping the view on the same line. Polling stops until the next refresh
+// so the page isn't replaced again.
+func (m *Model) handleOlderLogsResponse(msg common.Response) {
+	m.logsPaging = false
+	if !msg.Success {
+		m.logger.Error("Failed to load older logs: %s", msg.Error)
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var res common.LogsResult
+	if json.Unmarshal(b, &res) != nil {
+		return
+	}
+	older := res.Logs
+	if res.AtStart {
+		older = styleDim.Render("--- Start of logs ---") + "\n" + older
+	}
+	if res.Oldest != "" {
+		m.logsOldest = res.Oldest
+	}
+	m.logsAtStart = res.AtStart
+	m.logsPaged = true
+	m.logsText = older + m.logsText
+	offset := m.logsViewport.YOffset + strings.Count(older, "\n")
+	m.logsViewport.SetContent(m.logsText)
+	m.logsViewport.SetYOffset(offset)
+}
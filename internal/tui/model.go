@@ -2,7 +2,9 @@ package tui
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,6 +15,7 @@ import (
 	"github.com/COMPANYNAMEHERE/PerSSH/internal/discovery"
 	"github.com/COMPANYNAMEHERE/PerSSH/internal/modules"
 	"github.com/COMPANYNAMEHERE/PerSSH/internal/ssh"
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/tunnel"
 	"github.com/COMPANYNAMEHERE/PerSSH/internal/utils"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -26,7 +29,11 @@ var (
 	styleGreen = lipgloss.NewStyle().Foreground(lipgloss.Color("46"))
 	styleDim   = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 	styleErr   = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	styleWarn  = lipgloss.NewStyle().Foreground(lipgloss.Color("208"))
 	styleBox   = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(1, 2)
+
+	// styleBanner marks a host whose environments are not real
+	styleBanner = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("0")).Background(lipgloss.Color("208")).Padding(0, 1).Align(lipgloss.Center)
 )
 
 type state int
@@ -37,6 +44,14 @@ const (
 	stateDashboard
 	stateEnvDetails
 	stateCreateEnv
+	stateTunnels
+	stateVolumes
+	stateRegistries
+	stateEditEnv
+	stateNetworks
+	stateImages
+	stateBackups
+	stateJobs
 )
 
 type Model struct {
@@ -49,6 +64,10 @@ type Model struct {
 
 	// Login
 	inputHost, inputUser, inputPort, inputPassword textinput.Model
+	inputJump                                      textinput.Model
+	inputHopPass                                   textinput.Model
+	hopPrompt                                      *hopLogin // Jump host whose password is asked for
+	hopPasswords                                   map[hopLogin]string
 	loginErr                                       string
 	loginSpinner                                   spinner.Model
 	loggingIn                                      bool
@@ -65,21 +84,57 @@ type Model struct {
 	telemetryErr  string
 	containers    []common.ContainerInfo
 	containerList string // Pre-rendered list for simplicity
+	dashMsg       string // Last action error shown under the list
+	stats         map[string]common.ContainerStats // Latest sample by container ID
+	removeTarget  *common.ContainerInfo // Environment awaiting remove confirmation
+	removeStack   string                // Stack awaiting remove confirmation
+	expanded      map[string]bool       // Stacks showing their services
+	showAll       bool                  // Also list containers not managed by PerSSH
+	adoptType     int                   // Index in modules.Registry, see promptAdopt
+	envPrompt     envPrompt // Rename or signal prompt, see updateEnvKeys
+	promptTarget  common.ContainerInfo
+	promptInput   textinput.Model
 
 	// Env Details
-	selectedEnvID string
-	logsViewport  viewport.Model
-	logsLoading   bool
-	consoleInput  textinput.Model
-	detailedView  bool
-	cpuHistory    []float64
-	ramHistory    []float64
-	tempHistory   []float64
+	selectedEnvID   string
+	logsViewport    viewport.Model
+	logsLoading     bool
+	logsText        string // Log history plus live console output
+	attached        bool   // Console output is pushed by the agent
+	attachPending   bool   // ATTACH_ENV sent, no reply yet
+	logsOldest      string // Time of the first line shown, to page back from
+	logsAtStart     bool   // Nothing older than the lines shown
+	logsPaging      bool   // An older page is on its way
+	logsPaged       bool   // History was paged in; polling would replace it
+	consoleInput    textinput.Model
+	limits          common.Resources // Effective limits reported by the agent
+	limitsInput     textinput.Model
+	limitsErr       string
+	detailedView    bool
+	cpuHistory      []float64
+	ramHistory      []float64
+	tempHistory     []float64
+	envCPUHistory   []float64 // Selected container, see handleStatsResponse
+	envMemHistory   []float64
+	health          common.HealthInfo // Selected container, see cmdGetHealth
+	execInput       textinput.Model
+	execRunning     bool
+	execResult      *common.ExecResult
+	execErr         string
+	inspectView     bool // Inspect tab instead of logs
+	inspectLoading  bool
+	inspect         *common.EnvInspect
+	inspectViewport viewport.Model
 
 	// Create Env
-	inputName  textinput.Model
-	inputType  int             // Index in modules.Registry
-	inputImage textinput.Model // For standard
+	inputName   textinput.Model
+	inputType   int             // Index in modules.Registry
+	inputImage  textinput.Model // For standard
+	inputLimits textinput.Model // Resource limits, see parseLimits
+	inputMounts textinput.Model // Volumes and binds, see parseMounts
+	inputNets   textinput.Model // Networks and aliases, see parseNetworks
+	inputHealth textinput.Model // Healthcheck, see parseHealthcheck
+	inputYAML   textinput.Model // Local path of a compose file
 	// Minecraft specific
 	mcEula            bool
 	mcOp              textinput.Model
@@ -91,12 +146,103 @@ type Model struct {
 
 	createErr     string
 	creating      bool
+	pull          pullState
 	createSpinner spinner.Model
 	decoder       *json.Decoder
 	DevMode       bool
 
 	// Dashboard Selection
 	cursor int
+
+	// Reconnect after the agent link drops
+	reconnecting      bool
+	reconnectAttempts int // Failed attempts since the link dropped
+
+	// Tunnels
+	tunnels         *tunnel.Manager
+	tunnelsRestored bool
+	tunnelCursor    int
+	tunnelEnv       string
+	tunnelMode      tunnel.Mode
+	tunnelBind      textinput.Model
+	tunnelTarget    textinput.Model
+	tunnelEditing   bool
+	tunnelErr       string
+
+	// Volumes
+	volumes        []common.VolumeInfo
+	volumeCursor   int
+	volumesLoading bool
+	volumesErr     string
+
+	// Networks
+	networks        []common.NetworkInfo
+	networkCursor   int
+	networksLoading bool
+	networksErr     string
+	networkCreating bool
+	networkInput    textinput.Model
+	networkRemove   string // Network awaiting remove confirmation
+
+	// Images
+	images         []common.ImageInfo
+	imageCursor    int
+	imagesLoading  bool
+	imagesErr      string
+	imagesMsg      string // Result of the last prune or pull
+	diskUsage      *common.DiskUsage
+	imageConfirm   imageAction
+	imagePullInput textinput.Model
+	imagePulling   bool
+
+	// Backups of the environment in the details screen
+	backups        []common.BackupInfo
+	backupDir      string // On the host, see common.BackupList
+	backupCursor   int
+	backupsLoading bool
+	backupsErr     string
+	backupsMsg     string
+	backupBusy     string // Running action or transfer, shown instead of the keys
+	backupDone     string // Shown when the running action succeeds
+	backupConfirm  backupAction
+	backupInput    textinput.Model // Local path of a backup to upload
+
+	// Scheduled jobs, see jobs.go
+	jobs        []common.Job
+	jobCursor   int
+	jobsLoading bool
+	jobsErr     string
+	jobsMsg     string
+	jobRunning  string // Name of the job run with R until it returns
+	jobConfirm  bool   // Delete awaiting confirmation
+	jobHistory  bool   // Showing the runs of the selected job
+	jobEditing  bool
+	jobEdit     common.Job // Job in the form; no ID for a new one
+	jobField    int        // Focused form field, see jobFieldName
+	jobChoice   int        // Index into jobChoices
+	jobName     textinput.Model
+	jobSchedule textinput.Model
+	jobEnv      textinput.Model
+	jobCommand  textinput.Model
+
+	// Registry logins
+	registryCursor  int
+	registryEditing bool
+	registryHost    textinput.Model
+	registryUser    textinput.Model
+	registryPass    textinput.Model
+	registryErr     string
+
+	// Update environment, see edit.go
+	editID      string
+	editName    string
+	editBase    common.CreateEnvPayload // As returned by GET_ENV_CONFIG
+	editImage   textinput.Model
+	editEnv     textinput.Model
+	editPorts   textinput.Model
+	editLoading bool
+	editErr     string
+	updating    bool
 }
 
 func NewModel(logger *utils.Logger) Model {
@@ -116,6 +262,11 @@ func NewModel(logger *utils.Logger) Model {
 	pw := textinput.New()
 	pw.Placeholder = "Password"
 	pw.EchoMode = textinput.EchoPassword
+	jh := textinput.New()
+	jh.Placeholder = "user@bastion:22 (optional)"
+	hp := textinput.New()
+	hp.Placeholder = "Password"
+	hp.EchoMode = textinput.EchoPassword
 
 	// Auto-fill from config
 	if cfg.Session.LastHost != "" {
@@ -127,6 +278,9 @@ func NewModel(logger *utils.Logger) Model {
 	if cfg.Session.LastPort != 0 {
 		p.SetValue(fmt.Sprintf("%d", cfg.Session.LastPort))
 	}
+	if cfg.Session.LastJump != "" {
+		jh.SetValue(cfg.Session.LastJump)
+	}
 
 	s := spinner.New()
 	s.Spinner = spinner.Dot
@@ -151,28 +305,106 @@ mcVer := textinput.New()
 
 	vp := viewport.New(80, 20)
 	vp.Style = lipgloss.NewStyle().Border(lipgloss.RoundedBorder())
+	iv := viewport.New(80, 20)
+	iv.Style = vp.Style
 
 	ci := textinput.New()
 	ci.Placeholder = "Type command..."
+	li := textinput.New()
+	li.Placeholder = limitsHelp
+	lim := textinput.New()
+	lim.Placeholder = "cpus=2 pids=512 (optional)"
+	cmp := textinput.New()
+	cmp.Placeholder = "./docker-compose.yml"
+	cmp.Width = 50
+	mnt := textinput.New()
+	mnt.Placeholder = "name:/data, /srv/mods:/mods:ro (optional)"
+	nets := textinput.New()
+	nets.Placeholder = "shop:db:postgres, public (optional)"
+	ni := textinput.New()
+	ni.Placeholder = "shop subnet=10.10.0.0/24"
+	ip := textinput.New()
+	ip.Placeholder = "postgres:16"
+	bi := textinput.New()
+	bi.Placeholder = "./mc-20250101-120000.tar.gz"
+	bi.Width = 50
+	jn := textinput.New()
+	jn.Placeholder = "nightly-backup"
+	js := textinput.New()
+	js.Placeholder = "0 4 * * *"
+	je := textinput.New()
+	je.Placeholder = "Environment name"
+	jc := textinput.New()
+	jc.Placeholder = "say Restarting in 5 minutes"
+	jc.CharLimit = 1024
+	jc.Width = 60
+	rh := textinput.New()
+	rh.Placeholder = "registry.example.com"
+	ru := textinput.New()
+	ru.Placeholder = "User"
+	rp := textinput.New()
+	rp.Placeholder = "Password or token"
+	rp.EchoMode = textinput.EchoPassword
 	ci.CharLimit = 200
 	ci.Width = 80
 
+	ep := textinput.New()
+	ep.CharLimit = 64
+	ex := textinput.New()
+	ex.Placeholder = "ls -la /data"
+	ex.CharLimit = 1024
+	ex.Width = 60
+
+	ei := textinput.New()
+	ei.Placeholder = "itzg/minecraft-server:java21"
+	ee := textinput.New()
+	ee.Placeholder = "KEY=value; KEY2=value"
+	ee.CharLimit = 1024
+	ee.Width = 60
+	epo := textinput.New()
+	epo.Placeholder = "25565:25565, 8080:80"
+
+	tb := textinput.New()
+	tb.Placeholder = "127.0.0.1:25565"
+	tt := textinput.New()
+	tt.Placeholder = "localhost:25565"
+
 	return Model{
 		state:        stateLogin,
 		clientConfig: cfg,
 		logger:       logger,
-		inputHost:    h, inputUser: u, inputPort: p, inputPassword: pw,
+		inputHost:    h, inputUser: u, inputPort: p, inputPassword: pw, inputJump: jh,
+		inputHopPass: hp, hopPasswords: make(map[hopLogin]string),
 		loginSpinner:  s,
 		finderSpinner: fs,
 		createSpinner: s,
-		logsViewport:  vp,
+		logsViewport:  vp, inspectViewport: iv,
 		consoleInput:  ci,
+		limitsInput:   li,
+		promptInput:   ep,
+		execInput:     ex,
+		inputLimits:   lim,
+		inputMounts:   mnt,
+		inputNets:     nets,
+		inputHealth:   textinput.New(),
+		networkInput:  ni, imagePullInput: ip, backupInput: bi,
+		jobName:       jn, jobSchedule: js, jobEnv: je, jobCommand: jc,
+		inputYAML:     cmp,
+		registryHost:  rh,
+		registryUser:  ru,
+		registryPass:  rp,
 		inputName:     nm, inputImage: img, inputType: 0,
 		mcOp: op, mcRam: ram, mcVersion: mcVer, mcModpack: mcMod, mcAikar: true,
 		rpcResp:     make(chan common.Response),
 		cpuHistory:  make([]float64, 0, 300),
 		ramHistory:  make([]float64, 0, 300),
 		tempHistory: make([]float64, 0, 300),
+		tunnels:      tunnel.NewManager(),
+		tunnelBind:   tb,
+		tunnelTarget: tt,
+		editImage:    ei,
+		editEnv:      ee,
+		editPorts:    epo,
 	}
 }
 
@@ -206,6 +438,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" {
+			m.tunnels.CloseAll()
 			if m.sshClient != nil {
 				m.sshClient.Close()
 			}
@@ -224,6 +457,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.loggingIn = true
 		return m, m.cmdLogin()
 
+	case needHopPasswordMsg:
+		return m, m.askHopPassword(msg.hop)
+
 	case loginSuccessMsg:
 		m.logger.System("Login successful, switching to dashboard")
 
@@ -231,14 +467,72 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.clientConfig.Session.LastHost = m.inputHost.Value()
 		m.clientConfig.Session.LastUser = m.inputUser.Value()
 		fmt.Sscanf(m.inputPort.Value(), "%d", &m.clientConfig.Session.LastPort)
+		m.clientConfig.Session.LastJump = m.inputJump.Value()
 
 		config.SaveClientConfig(m.clientConfig)
 		// Async save to avoid blocking
 		go utils.StorePassword(m.inputHost.Value(), m.inputUser.Value(), m.inputPassword.Value())
+		m.storeHopPasswords()
+
+		// Forwards keep their local listeners; route them over the new link
+		m.tunnels.SetRemote(msg.client)
+		if !m.tunnelsRestored {
+			m.tunnelsRestored = true
+			m.restoreTunnels()
+		}
+		if m.reconnecting {
+			m.reconnecting = false
+			m.sshClient = msg.client
+			m.decoder = json.NewDecoder(m.sshClient.GetStdout())
+			m.reconnectAttempts = 0
+			// The console went away with the old agent
+			m.attached, m.attachPending = false, false
+			if m.state == stateEnvDetails {
+				attach := m.cmdAttach(m.selectedEnvID)
+				return m, tea.Batch(m.waitForPacket(), attach)
+			}
+			return m, m.waitForPacket()
+		}
+
+	case backupTransferMsg:
+		return m, m.handleBackupTransfer(msg)
+
+	case shellExitMsg:
+		// Shell sessions always come back to the dashboard
+		m.state = stateDashboard
+		m.detailedView = false
+		m.dashMsg = ""
+		if msg.err != nil {
+			m.logger.Error("Shell exited: %v", msg.err)
+			m.dashMsg = "Shell: " + msg.err.Error()
+		}
+		return m, tea.Batch(tea.ClearScreen, m.cmdPollList(), m.cmdDetach())
 
 	case errMsg:
 		m.logger.Error("TUI Error Msg: %v", msg.error)
+		if m.sshClient != nil && m.state != stateLogin && m.state != stateFinder {
+			// The agent link dropped (or a reconnect attempt failed); retry
+			// with backoff unless retrying can't help
+			if !m.reconnecting {
+				m.reconnecting = true
+				m.reconnectAttempts = 0
+				m.sshClient.Close()
+			} else {
+				m.reconnectAttempts++
+			}
+			var perm permanentError
+			if errors.As(msg.error, &perm) || m.reconnectAttempts >= maxReconnectAttempts {
+				m.backToLogin("Connection lost: " + msg.error.Error())
+				return m, nil
+			}
+			return m, m.cmdReconnect(reconnectDelay(m.reconnectAttempts))
+		}
 	case common.Response:
+		if msg.ID == common.EventAgentError {
+			// The agent exits after this; reconnecting would only repeat it
+			m.backToLogin("Agent failed to start: " + msg.Error)
+			return m, nil
+		}
 		// Handle RPC Responses
 		if msg.ID == "telemetry" && msg.Success {
 			// Try to convert map to struct (hacky for MVP since JSON unmarshals to map)
@@ -276,6 +570,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.containerList = s.String()
 		}
+		if msg.ID == common.EventPull {
+			m.handlePullEvent(msg)
+		}
 		if msg.ID == "create" {
 			m.creating = false
 			if msg.Success {
@@ -288,14 +585,84 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 		if msg.ID == "logs" {
-			m.logsLoading = false
+			m.handleLogsResponse(msg)
+		}
+		if msg.ID == "logsolder" {
+			m.handleOlderLogsResponse(msg)
+		}
+		if msg.ID == "envconfig" {
+			m.handleEnvConfigResponse(msg)
+		}
+		if msg.ID == "update" {
+			m.handleUpdateResponse(msg)
+		}
+		if msg.ID == "limits" {
+			m.handleLimitsResponse(msg)
+		}
+		if msg.ID == "stats" {
+			m.handleStatsResponse(msg)
+		}
+		if msg.ID == "health" {
+			m.handleHealthResponse(msg)
+		}
+		if msg.ID == "exec" {
+			m.handleExecResponse(msg)
+		}
+		if msg.ID == "inspect" {
+			m.handleInspectResponse(msg)
+		}
+		if msg.ID == "volumes" {
+			m.handleVolumesResponse(msg)
+		}
+		if msg.ID == "networks" {
+			m.handleNetworksResponse(msg)
+		}
+		if msg.ID == "images" {
+			m.handleImagesResponse(msg)
+		}
+		if msg.ID == "df" {
+			m.handleDiskUsageResponse(msg)
+		}
+		if msg.ID == "image" || msg.ID == "prune" {
+			return m, tea.Batch(m.waitForPacket(), m.handleImageActionResponse(msg))
+		}
+		if msg.ID == "backups" {
+			m.handleBackupsResponse(msg)
+		}
+		if msg.ID == "backup" {
+			return m, tea.Batch(m.waitForPacket(), m.handleBackupActionResponse(msg))
+		}
+		if msg.ID == "jobs" {
+			m.handleJobsResponse(msg)
+		}
+		if msg.ID == "job" || msg.ID == "jobrun" {
+			return m, tea.Batch(m.waitForPacket(), m.handleJobActionResponse(msg))
+		}
+		if msg.ID == "network" {
+			return m, tea.Batch(m.waitForPacket(), m.handleNetworkActionResponse(msg))
+		}
+		if msg.ID == "action" {
 			if msg.Success {
-				if logs, ok := msg.Data.(string); ok {
-					m.logsViewport.SetContent(logs)
-					m.logsViewport.GotoBottom()
-				}
+				m.dashMsg = ""
+				m.sshClient.SendRequest(common.Request{ID: "list", Type: common.CmdListContainers})
 			} else {
-				m.logsViewport.SetContent("Error fetching logs: " + msg.Error)
+				m.dashMsg = msg.Error
+			}
+		}
+		if msg.ID == "attach" {
+			// Leaving the screen detached already if the reply was late
+			m.attached = msg.Success && m.attachPending && m.state == stateEnvDetails
+			m.attachPending = false
+			if !msg.Success {
+				m.logger.Error("Attach failed, falling back to log polling: %s", msg.Error)
+			}
+		}
+		if msg.ID == common.EventConsole {
+			b, _ := json.Marshal(msg.Data)
+			var out common.ConsoleOutput
+			json.Unmarshal(b, &out)
+			if out.ID == m.selectedEnvID {
+				m.appendConsole(out)
 			}
 		}
 		return m, m.waitForPacket()
@@ -330,6 +697,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateEnvDetails(msg)
 	case stateCreateEnv:
 		return m.updateCreateEnv(msg)
+	case stateTunnels:
+		return m.updateTunnels(msg)
+	case stateVolumes:
+		return m.updateVolumes(msg)
+	case stateNetworks:
+		return m.updateNetworks(msg)
+	case stateImages:
+		return m.updateImages(msg)
+	case stateBackups:
+		return m.updateBackups(msg)
+	case stateJobs:
+		return m.updateJobs(msg)
+	case stateRegistries:
+		return m.updateRegistries(msg)
+	case stateEditEnv:
+		return m.updateEditEnv(msg)
 	}
 	return m, nil
 }
@@ -348,6 +731,25 @@ func (m Model) View() string {
 		s = m.viewEnvDetails()
 	case stateCreateEnv:
 		s = m.viewCreateEnv()
+	case stateTunnels:
+		s = m.viewTunnels()
+	case stateVolumes:
+		s = m.viewVolumes()
+	case stateNetworks:
+		s = m.viewNetworks()
+	case stateImages:
+		s = m.viewImages()
+	case stateBackups:
+		s = m.viewBackups()
+	case stateJobs:
+		s = m.viewJobs()
+	case stateRegistries:
+		s = m.viewRegistries()
+	case stateEditEnv:
+		s = m.viewEditEnv()
+	}
+	if banner := m.viewMockBanner(); banner != "" {
+		s = lipgloss.JoinVertical(lipgloss.Center, banner, s)
 	}
 	res := lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, s)
 	
@@ -362,6 +764,9 @@ func (m Model) View() string {
 func (m Model) updateLogin(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	if _, ok := msg.(tea.KeyMsg); ok && m.hopPrompt != nil {
+		return m.updateHopPrompt(msg)
+	}
 	if key, ok := msg.(tea.KeyMsg); ok {
 		if key.String() == "enter" {
 			m.loggingIn = true
@@ -385,8 +790,11 @@ func (m Model) updateLogin(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else if m.inputPort.Focused() {
 				m.inputPort.Blur()
 				m.inputPassword.Focus()
-			} else {
+			} else if m.inputPassword.Focused() {
 				m.inputPassword.Blur()
+				m.inputJump.Focus()
+			} else {
+				m.inputJump.Blur()
 				m.inputHost.Focus()
 			}
 			return m, textinput.Blink
@@ -405,6 +813,9 @@ func (m Model) updateLogin(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m.inputPassword.Focused() {
 		m.inputPassword, cmd = m.inputPassword.Update(msg)
 	}
+	if m.inputJump.Focused() {
+		m.inputJump, cmd = m.inputJump.Update(msg)
+	}
 
 	if _, ok := msg.(loginSuccessMsg); ok {
 		m.loggingIn = false
@@ -412,12 +823,14 @@ func (m Model) updateLogin(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = stateDashboard
 		m.decoder = json.NewDecoder(m.sshClient.GetStdout())
 
-		return m, tea.Batch(m.cmdPollTelemetry(), m.cmdPollList(), m.cmdPollListTick(), m.waitForPacket())
+		return m, tea.Batch(m.cmdPollTelemetry(), m.cmdPollList(), m.cmdPollListTick(), m.cmdPollStats(), m.waitForPacket())
 	}
 
 	if err, ok := msg.(errMsg); ok {
 		m.loggingIn = false
 		m.loginErr = err.error.Error()
+		// Ask again next time, in case a jump host password was mistyped
+		clear(m.hopPasswords)
 	}
 
 	if m.loggingIn {
@@ -436,8 +849,12 @@ func (m Model) viewLogin() string {
 	b.WriteString(fmt.Sprintf("User: %s\n", m.inputUser.View()))
 	b.WriteString(fmt.Sprintf("Port: %s\n", m.inputPort.View()))
 	b.WriteString(fmt.Sprintf("Pass: %s\n", m.inputPassword.View()))
+	b.WriteString(fmt.Sprintf("Jump: %s\n", m.inputJump.View()))
 
-	if m.loggingIn {
+	if m.hopPrompt != nil {
+		b.WriteString(fmt.Sprintf("\nPassword for jump host %s: %s\n", m.hopPrompt, m.inputHopPass.View()))
+		b.WriteString("[Enter] Connect   [Esc] Cancel")
+	} else if m.loggingIn {
 		b.WriteString(fmt.Sprintf("\n%s Connecting...", m.loginSpinner.View()))
 	} else {
 		b.WriteString("\n[Enter] Connect   [Ctrl+F] Find Servers")
@@ -508,49 +925,125 @@ func (m Model) viewFinder() string {
 
 // --- Dashboard ---
 func (m Model) updateDashboard(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok && m.removeTarget != nil {
+		return m.updateRemoveConfirm(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.removeStack != "" {
+		return m.updateRemoveStackConfirm(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.envPrompt != promptNone {
+		return m.updateEnvPrompt(key)
+	}
+
 	if key, ok := msg.(tea.KeyMsg); ok {
+		if nm, cmd, handled := m.updateStackKeys(key); handled {
+			return nm, cmd
+		}
+		if nm, cmd, handled := m.updateEnvKeys(key); handled {
+			return nm, cmd
+		}
 		switch key.String() {
 		case "c":
 			m.state = stateCreateEnv
 			m.inputName.Focus()
 			return m, textinput.Blink
+		case "g":
+			m.state = stateRegistries
+			m.registryEditing = false
+			m.registryErr = ""
+			return m, nil
+		case "v":
+			m.state = stateVolumes
+			m.volumesLoading = true
+			return m, m.cmdListVolumes()
+		case "w":
+			m.state = stateNetworks
+			m.networksLoading = true
+			m.networkCreating = false
+			m.networkRemove = ""
+			m.networksErr = ""
+			return m, m.cmdListNetworks()
+		case "i":
+			m.state = stateImages
+			m.imagesLoading = true
+			m.imageConfirm = imageNone
+			m.imagesErr = ""
+			m.imagesMsg = ""
+			return m, tea.Batch(m.cmdListImages(), m.cmdDiskUsage())
+		case "j":
+			return m, m.openJobs()
+		case "m":
+			m.showAll = !m.showAll
+			if n := len(m.dashRows()); m.cursor >= n {
+				m.cursor = max(n-1, 0)
+			}
+			return m, nil
+		case "t":
+			m.state = stateTunnels
+			m.tunnelEditing = false
+			m.tunnelErr = ""
+			return m, nil
 		case "l":
 			// Refresh list
 			if m.sshClient != nil {
 				m.sshClient.SendRequest(common.Request{ID: "list", Type: common.CmdListContainers})
 			}
 		case "enter":
-			if len(m.containers) > 0 && m.cursor < len(m.containers) {
+			if c, ok := m.selectedContainer(); ok {
 				m.state = stateEnvDetails
-				m.selectedEnvID = m.containers[m.cursor].ID
+				m.selectedEnvID = c.ID
 				m.logsLoading = true
 				m.logsViewport.SetContent("Loading logs...")
+				m.logsText = ""
+				m.logsOldest = ""
+				m.logsAtStart = false
+				m.logsPaging = false
+				m.logsPaged = false
+				m.limits = common.Resources{}
+				m.limitsErr = ""
+				m.envCPUHistory = nil
+				m.envMemHistory = nil
+				m.health = common.HealthInfo{}
+				m.execInput.SetValue("")
+				m.execResult = nil
+				m.execRunning = false
+				m.execErr = ""
+				m.inspectView = false
+				m.inspect = nil
 				// m.consoleInput.Focus() // Removed to allow shortcuts first
-				return m, tea.Batch(m.cmdGetLogs(m.selectedEnvID), m.cmdPollLogsTick(), textinput.Blink)
+				attach := m.cmdAttach(m.selectedEnvID)
+				return m, tea.Batch(m.cmdGetLogs(m.selectedEnvID), attach, m.cmdGetLimits(m.selectedEnvID), m.cmdPollLogsTick(), textinput.Blink)
 			}
 		case "up":
 			if m.cursor > 0 {
 				m.cursor--
 			}
 		case "down":
-			if m.cursor < len(m.containers)-1 {
+			if m.cursor < len(m.dashRows())-1 {
 				m.cursor++
 			}
 		case "s":
 			// Toggle Start/Stop
-			if len(m.containers) > 0 && m.cursor < len(m.containers) {
-				c := m.containers[m.cursor]
+			if c, ok := m.selectedContainer(); ok {
 				cmdType := common.CmdStartEnv
-				if c.Status == "running" {
+				if c.Status == "running" || c.Status == "paused" {
 					cmdType = common.CmdStopEnv
 				}
 				m.sshClient.SendRequest(common.Request{ID: "action", Type: cmdType, Payload: c.ID})
 			}
+		case "u":
+			if c, ok := m.selectedContainer(); ok {
+				if c.Stack != "" {
+					m.dashMsg = fmt.Sprintf("%s is part of stack %s; change the compose file and redeploy it", c.Name, c.Stack)
+					break
+				}
+				cmd := m.startEdit(c)
+				return m, cmd
+			}
 		case "x":
 			// Remove
-			if len(m.containers) > 0 && m.cursor < len(m.containers) {
-				c := m.containers[m.cursor]
-				m.sshClient.SendRequest(common.Request{ID: "action", Type: common.CmdRemoveEnv, Payload: c.ID})
+			if c, ok := m.selectedContainer(); ok {
+				m.removeTarget = &c
 			}
 		case "q":
 			return m, tea.Quit
@@ -569,43 +1062,124 @@ func (m Model) updateDashboard(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if _, ok := msg.(listTickMsg); ok {
 		return m, tea.Batch(m.cmdPollList(), m.cmdPollListTick())
 	}
+	if _, ok := msg.(statsTickMsg); ok {
+		return m, m.cmdPollStats()
+	}
 
+	if m.envPrompt != promptNone {
+		var cmd tea.Cmd
+		m.promptInput, cmd = m.promptInput.Update(msg)
+		return m, cmd
+	}
 	return m, nil
 }
 
+// viewMockBanner warns on every screen that the agent simulates its
+// environments, and why, so nobody mistakes them for real ones.
+func (m Model) viewMockBanner() string {
+	b := m.telemetry.Backend
+	if b.Name != common.BackendMock || m.state == stateLogin || m.state == stateFinder {
+		return ""
+	}
+	msg := "MOCK BACKEND: environments are simulated, nothing runs on the host (requested with -backend=mock)"
+	if b.Fallback != "" {
+		msg = "MOCK BACKEND: environments are simulated, nothing runs on the host\n" + b.Fallback
+	}
+	return styleBanner.Width(min(max(m.width-4, 40), 100)).Render(msg)
+}
+
+// viewBackend names the container engine the agent drives.
+func (m Model) viewBackend() string {
+	b := m.telemetry.Backend
+	switch b.Name {
+	case "":
+		return styleDim.Render("...")
+	case common.BackendMock:
+		return styleWarn.Render("mock")
+	}
+	s := b.Name + " " + b.Version
+	if b.Rootless {
+		s += " (rootless)"
+	}
+	return styleGreen.Render(s)
+}
+
 func (m Model) viewDashboard() string {
 	// Telemetry Header
-	stats := fmt.Sprintf("CPU: %s | RAM: %s | Disk: %s | Temp: %.1fC",
+	stats := fmt.Sprintf("CPU: %s | RAM: %s | Disk: %s | Temp: %.1fC | Engine: %s",
 		styleGreen.Render(fmt.Sprintf("%.1f%%", m.telemetry.CPUUsage)),
 		styleGreen.Render(fmt.Sprintf("%.1f%%", m.telemetry.RAMUsage)),
 		styleGreen.Render(fmt.Sprintf("%dGB", m.telemetry.DiskFree/1024/1024/1024)),
 		m.telemetry.CPUTemp,
+		m.viewBackend(),
 	)
 
 	// Menu
-	menu := styleDim.Render("[Enter] Details  [C] Create  [L] Refresh  [S] Start/Stop  [X] Remove  [Q] Quit")
+	menu := styleDim.Render("[Enter] Details  [C] Create  [L] Refresh  [S] Start/Stop  [R] Restart  [P] Pause  [N] Rename  [K] Signal  [U] Update  [X] Remove\n[M] Show All/Managed  [A] Adopt  [T] Tunnels  [V] Volumes  [W] Networks  [I] Images  [J] Jobs  [G] Registries  [Q] Quit")
 
 	// Content
 	var s strings.Builder
-	s.WriteString("Active Environments:\n")
-	for i, c := range m.containers {
+	rows := m.dashRows()
+	if m.showAll {
+		s.WriteString("All Containers:\n")
+	} else {
+		s.WriteString("Active Environments:")
+		if n := m.hiddenCount(); n > 0 {
+			s.WriteString(styleDim.Render(fmt.Sprintf(" (%d other containers hidden, [M] to show)", n)))
+		}
+		s.WriteString("\n")
+	}
+	for i, row := range rows {
+		if row.stack != "" {
+			s.WriteString(m.viewStackRow(row, i == m.cursor) + "\n")
+			continue
+		}
+		c := row.container
 		pref := "  "
 		if i == m.cursor {
 			pref = styleGreen.Render("> ")
 		}
-
-		statusStyle := styleDim
-		if c.Status == "running" {
-			statusStyle = styleGreen
+		name := c.Name
+		if c.Stack != "" {
+			// Indented under the stack header
+			pref = "  " + pref
+			name = c.Service
 		}
 
-		s.WriteString(fmt.Sprintf("%s%s - %s [%s]\n", pref, c.Name, c.Image, statusStyle.Render(c.Status)))
+		status, statusStyle := envStatus(c)
+		line := fmt.Sprintf("%s%s - %s [%s]", pref, name, c.Image, statusStyle.Render(status))
+		if ports := formatPorts(c.Ports); ports != "" {
+			line += " " + styleDim.Render(ports)
+		}
+		if st, ok := m.stats[c.ID]; ok && c.Status == "running" {
+			line += "  " + styleDim.Render(formatStats(st))
+		}
+		if !c.Managed() {
+			line += styleDim.Render(" (unmanaged)")
+		}
+		s.WriteString(line + "\n")
 	}
 
 	content := s.String()
-	if len(m.containers) == 0 {
+	if len(rows) == 0 {
 		content += styleDim.Render("(No environments running)")
 	}
+	if m.removeTarget != nil {
+		content += "\n" + m.viewRemoveConfirm()
+	} else if m.removeStack != "" {
+		content += "\n" + m.viewRemoveStackConfirm()
+	} else if m.envPrompt != promptNone {
+		content += "\n" + m.viewEnvPrompt()
+	} else if m.dashMsg != "" {
+		content += "\n" + styleErr.Render(m.dashMsg)
+	}
+
+	if n := len(m.tunnels.List()); n > 0 {
+		stats += fmt.Sprintf(" | Tunnels: %s", styleGreen.Render(fmt.Sprintf("%d", n)))
+	}
+	if m.reconnecting {
+		stats += "\n" + styleErr.Render(fmt.Sprintf("Connection lost, reconnecting (attempt %d of %d)...", m.reconnectAttempts+1, maxReconnectAttempts))
+	}
 
 	return lipgloss.JoinVertical(lipgloss.Left,
 		styleBox.Render(stats),
@@ -614,10 +1188,39 @@ func (m Model) viewDashboard() string {
 	)
 }
 
+// formatPorts renders published ports as "host->container/proto". Docker
+// reports IPv4 and IPv6 bindings separately; they are shown once.
+func formatPorts(ports []common.PortBinding) string {
+	seen := make(map[string]bool)
+	var parts []string
+	for _, p := range ports {
+		if p.HostPort == 0 {
+			continue
+		}
+		host := fmt.Sprintf("%d", p.HostPort)
+		if ip := net.ParseIP(p.HostIP); ip != nil && !ip.IsUnspecified() {
+			host = net.JoinHostPort(p.HostIP, host)
+		}
+		s := fmt.Sprintf("%s->%d/%s", host, p.ContainerPort, p.Protocol)
+		if !seen[s] {
+			seen[s] = true
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
 // --- Env Details ---
 func (m Model) updateEnvDetails(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	if key, ok := msg.(tea.KeyMsg); ok && m.limitsInput.Focused() {
+		return m.updateLimitsPrompt(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.execInput.Focused() {
+		return m.updateExecPrompt(key)
+	}
+
 	if key, ok := msg.(tea.KeyMsg); ok {
 		// Always allow Esc to handle focus/exit
 		if key.String() == "esc" {
@@ -628,7 +1231,7 @@ func (m Model) updateEnvDetails(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.state = stateDashboard
 			// Reset view settings when leaving
 			m.detailedView = false
-			return m, nil
+			return m, m.cmdDetach()
 		}
 
 		// Mode-specific handling
@@ -638,6 +1241,10 @@ func (m Model) updateEnvDetails(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.consoleInput.Value() != "" {
 					cmdStr := m.consoleInput.Value()
 					m.consoleInput.SetValue("")
+					if m.attached {
+						// The reply arrives as console output
+						return m, m.cmdSendInput(m.selectedEnvID, cmdStr)
+					}
 					return m, tea.Batch(
 						m.cmdSendInput(m.selectedEnvID, cmdStr),
 						// Force an immediate log refresh
@@ -651,6 +1258,36 @@ func (m Model) updateEnvDetails(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.consoleInput.Focus()
 				return m, textinput.Blink
 			}
+			if key.String() == "s" {
+				return m, m.cmdShell(m.selectedEnvID)
+			}
+			if key.String() == "l" {
+				cmd := m.startLimitsPrompt()
+				return m, cmd
+			}
+			if key.String() == "e" && !m.execRunning {
+				cmd := m.startExecPrompt()
+				return m, cmd
+			}
+			if key.String() == "i" {
+				cmd := m.toggleInspect()
+				return m, cmd
+			}
+			if key.String() == "b" {
+				cmd := m.openBackups()
+				return m, cmd
+			}
+			if key.String() == "r" && m.inspectView {
+				m.inspectLoading = true
+				return m, m.cmdInspect(m.selectedEnvID)
+			}
+			if key.String() == "o" {
+				return m, m.cmdGetOlderLogs()
+			}
+			if key.String() == "pgup" && m.logsViewport.AtTop() {
+				// Scrolling past the top loads the page before it
+				return m, m.cmdGetOlderLogs()
+			}
 			if key.String() == "r" {
 				m.logsLoading = true
 				m.logsViewport.SetContent("Refreshing...")
@@ -675,17 +1312,33 @@ func (m Model) updateEnvDetails(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if _, ok := msg.(telemetryTickMsg); ok {
 		return m, m.cmdPollTelemetry()
 	}
+	if _, ok := msg.(listTickMsg); ok {
+		return m, m.pollCmd(msg)
+	}
+	if _, ok := msg.(statsTickMsg); ok {
+		return m, tea.Batch(m.pollCmd(msg), m.cmdGetHealth())
+	}
 
-	// Handle log tick
+	// Handle log tick; an attached console streams instead of polling
 	if _, ok := msg.(logTickMsg); ok {
+		if m.attached || m.logsPaged {
+			return m, m.cmdPollLogsTick()
+		}
 		return m, tea.Batch(m.cmdGetLogs(m.selectedEnvID), m.cmdPollLogsTick())
 	}
 
-	m.logsViewport, cmd = m.logsViewport.Update(msg)
-	var ciCmd tea.Cmd
+	if m.inspectView {
+		// Scroll keys move the inspect tab, sized like the logs
+		m.inspectViewport.Width, m.inspectViewport.Height = m.logsViewport.Width, m.logsViewport.Height
+		m.inspectViewport, cmd = m.inspectViewport.Update(msg)
+	} else {
+		m.logsViewport, cmd = m.logsViewport.Update(msg)
+	}
+	var ciCmd, liCmd tea.Cmd
 	m.consoleInput, ciCmd = m.consoleInput.Update(msg)
+	m.limitsInput, liCmd = m.limitsInput.Update(msg)
 
-	return m, tea.Batch(cmd, ciCmd)
+	return m, tea.Batch(cmd, ciCmd, liCmd)
 }
 
 func (m Model) viewEnvDetails() string {
@@ -708,7 +1361,16 @@ func (m Model) viewEnvDetails() string {
 	if m.consoleInput.Focused() {
 		help = styleDim.Render("[Esc] Stop Typing   [Enter] Send Command")
 	} else {
-		help = styleDim.Render("[Esc] Back   [Enter] Type Command   [R] Refresh Logs   [D/Tab] Toggle Graphs")
+		help = styleDim.Render("[Esc] Back   [Enter] Type Command   [S] Shell   [E] Exec   [L] Limits   [I] Inspect   [B] Backups   [O] Older Logs   [R] Refresh Logs   [D/Tab] Toggle Graphs")
+	}
+	if m.inspectView && !m.consoleInput.Focused() {
+		help = styleDim.Render("[Esc] Back   [I] Logs   [Up/Down/PgUp/PgDn] Scroll   [R] Refresh   [E] Exec   [D/Tab] Toggle Graphs")
+	}
+	if m.limitsInput.Focused() {
+		help = styleDim.Render("[Enter] Apply Limits   [Esc] Cancel   (" + limitsHelp + ")")
+	}
+	if m.execInput.Focused() {
+		help = styleDim.Render("[Enter] Run   [Esc] Cancel")
 	}
 
 	var topView string
@@ -721,37 +1383,48 @@ func (m Model) viewEnvDetails() string {
 		}
 		graphWidth := m.width - 6
 
+		// Container CPU and memory (% of its limit); the host is in the stats line
 		series := map[string]struct {
 			Data    []float64
 			ColorFn func(float64) lipgloss.Style
 		}{
-			"CPU": {m.cpuHistory, func(_ float64) lipgloss.Style { return lipgloss.NewStyle().Foreground(lipgloss.Color("39")) }}, // Blue
-			"RAM": {m.ramHistory, func(_ float64) lipgloss.Style { return lipgloss.NewStyle().Foreground(lipgloss.Color("255")) }}, // White
-			"Temp": {m.tempHistory, func(val float64) lipgloss.Style {
-				if val > 80 {
-					return lipgloss.NewStyle().Foreground(lipgloss.Color("196")) // Red
-				}
-				if val > 60 {
-					return lipgloss.NewStyle().Foreground(lipgloss.Color("220")) // Yellow
-				}
-				return lipgloss.NewStyle().Foreground(lipgloss.Color("46")) // Green
-			}},
+			"CPU": {m.envCPUHistory, func(_ float64) lipgloss.Style { return lipgloss.NewStyle().Foreground(lipgloss.Color("39")) }}, // Blue
+			"RAM": {m.envMemHistory, func(_ float64) lipgloss.Style { return lipgloss.NewStyle().Foreground(lipgloss.Color("255")) }}, // White
 		}
 		
 		topView = lipgloss.JoinVertical(lipgloss.Left, 
 			title, 
+			smallStats,
 			renderCombinedGraph(series, graphWidth, graphHeight),
 		)
 	} else {
 		topView = lipgloss.JoinVertical(lipgloss.Left, title, smallStats)
 	}
 
-	return lipgloss.JoinVertical(lipgloss.Left,
-		topView,
-		m.logsViewport.View(),
-		m.consoleInput.View(),
-		help,
-	)
+	if health := m.viewHealth(); health != "" {
+		topView = lipgloss.JoinVertical(lipgloss.Left, topView, health)
+	}
+	topView = lipgloss.JoinHorizontal(lipgloss.Top, topView, "   ", m.viewLimits())
+
+	// Command output takes its room from the logs
+	logs := m.logsViewport
+	if m.inspectView {
+		iv := m.inspectViewport
+		iv.Width, iv.Height = logs.Width, logs.Height
+		logs = iv
+	}
+	exec := m.viewExec()
+	if exec != "" {
+		logs.Height -= lipgloss.Height(exec)
+		if logs.Height < 3 {
+			logs.Height = 3
+		}
+	}
+	parts := []string{topView, logs.View(), m.consoleInput.View()}
+	if exec != "" {
+		parts = append(parts, exec)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, append(parts, help)...)
 }
 
 func renderCombinedGraph(series map[string]struct{Data []float64; ColorFn func(float64) lipgloss.Style}, width, height int) string {
@@ -832,7 +1505,10 @@ func renderCombinedGraph(series map[string]struct{Data []float64; ColorFn func(f
 	// 4. Legend
 	var legend []string
 	for _, label := range order {
-		s := series[label]
+		s, ok := series[label]
+		if !ok {
+			continue
+		}
 		curr := 0.0
 		if len(s.Data) > 0 {
 			curr = s.Data[len(s.Data)-1]
@@ -863,7 +1539,35 @@ func (m Model) updateCreateEnv(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		if key.String() == "enter" {
+			m.pull = pullState{}
+			if modules.Registry[m.inputType].Type() == common.EnvTypeCompose {
+				data, err := m.readCompose()
+				if err != nil {
+					m.createErr = err.Error()
+					return m, nil
+				}
+				m.creating = true
+				m.createErr = ""
+				return m, tea.Batch(m.createSpinner.Tick, m.cmdDeployStack(m.inputName.Value(), data))
+			}
+			if _, err := parseLimits(m.inputLimits.Value()); err != nil {
+				m.createErr = err.Error()
+				return m, nil
+			}
+			if _, err := parseMounts(m.inputMounts.Value()); err != nil {
+				m.createErr = err.Error()
+				return m, nil
+			}
+			if _, err := parseNetworks(m.inputNets.Value()); err != nil {
+				m.createErr = err.Error()
+				return m, nil
+			}
+			if _, err := parseHealthcheck(m.inputHealth.Value()); err != nil {
+				m.createErr = err.Error()
+				return m, nil
+			}
 			m.creating = true
+			m.createErr = ""
 			return m, tea.Batch(m.createSpinner.Tick, m.cmdCreate())
 		}
 		
@@ -888,17 +1592,37 @@ func (m Model) updateCreateEnv(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Tab cycle
 		if key.String() == "tab" {
-			if mod.Type() == common.EnvTypeStandard {
+			if mod.Type() == common.EnvTypeCompose {
 				if m.inputName.Focused() {
 					m.inputName.Blur()
-					m.inputImage.Focus()
+					m.inputYAML.Focus()
 				} else {
+					m.inputYAML.Blur()
+					m.inputName.Focus()
+				}
+			} else if mod.Type() == common.EnvTypeStandard {
+				if m.inputName.Focused() {
+					m.inputName.Blur()
+					m.inputImage.Focus()
+				} else if m.inputImage.Focused() {
 					m.inputImage.Blur()
+					m.inputLimits.Focus()
+				} else if m.inputLimits.Focused() {
+					m.inputLimits.Blur()
+					m.inputMounts.Focus()
+				} else if m.inputMounts.Focused() {
+					m.inputMounts.Blur()
+					m.inputNets.Focus()
+				} else if m.inputNets.Focused() {
+					m.inputNets.Blur()
+					m.inputHealth.Focus()
+				} else {
+					m.inputHealth.Blur()
 					m.inputName.Focus()
 				}
 			} else {
 				// Minecraft Cycle
-				// Name -> Version -> Modpack -> Op -> Ram -> Name
+				// Name -> Version -> Modpack -> Op -> Ram -> Limits -> Mounts -> Networks -> Health -> Name
 				if m.inputName.Focused() {
 					m.inputName.Blur()
 					m.mcVersion.Focus()
@@ -913,6 +1637,18 @@ func (m Model) updateCreateEnv(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.mcRam.Focus()
 				} else if m.mcRam.Focused() {
 					m.mcRam.Blur()
+					m.inputLimits.Focus()
+				} else if m.inputLimits.Focused() {
+					m.inputLimits.Blur()
+					m.inputMounts.Focus()
+				} else if m.inputMounts.Focused() {
+					m.inputMounts.Blur()
+					m.inputNets.Focus()
+				} else if m.inputNets.Focused() {
+					m.inputNets.Blur()
+					m.inputHealth.Focus()
+				} else if m.inputHealth.Focused() {
+					m.inputHealth.Blur()
 					m.inputName.Focus()
 				} else {
 					m.inputName.Focus()
@@ -940,6 +1676,21 @@ func (m Model) updateCreateEnv(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m.mcRam.Focused() {
 		m.mcRam, cmd = m.mcRam.Update(msg)
 	}
+	if m.inputLimits.Focused() {
+		m.inputLimits, cmd = m.inputLimits.Update(msg)
+	}
+	if m.inputMounts.Focused() {
+		m.inputMounts, cmd = m.inputMounts.Update(msg)
+	}
+	if m.inputNets.Focused() {
+		m.inputNets, cmd = m.inputNets.Update(msg)
+	}
+	if m.inputHealth.Focused() {
+		m.inputHealth, cmd = m.inputHealth.Update(msg)
+	}
+	if m.inputYAML.Focused() {
+		m.inputYAML, cmd = m.inputYAML.Update(msg)
+	}
 
 	if m.creating {
 		var sCmd tea.Cmd
@@ -973,14 +1724,36 @@ func (m Model) viewCreateEnv() string {
 		b.WriteString(fmt.Sprintf("  OP Users: %s\n", m.mcOp.View()))
 		b.WriteString(fmt.Sprintf("  RAM Limit: %s\n", m.mcRam.View()))
 	}
+	if mod.Type() == common.EnvTypeCompose {
+		b.WriteString(fmt.Sprintf("Compose file: %s\n", m.inputYAML.View()))
+		b.WriteString(styleDim.Render("  Read from this machine and deployed as one stack. Services need an image;\n  bind mounts must be absolute paths on the server.\n"))
+	} else {
+		b.WriteString(fmt.Sprintf("Limits: %s\n", m.inputLimits.View()))
+		b.WriteString(fmt.Sprintf("Mounts: %s\n", m.inputMounts.View()))
+		b.WriteString(fmt.Sprintf("Networks: %s\n", m.inputNets.View()))
+		// Empty keeps the module's check, shown as the placeholder
+		health := m.inputHealth
+		health.Placeholder = formatHealthcheck(mod.GetDefaults().Health)
+		if health.Placeholder == "" {
+			health.Placeholder = "curl -f http://localhost/ interval=30s retries=3 (optional)"
+		}
+		b.WriteString(fmt.Sprintf("Health: %s\n", health.View()))
+	}
+	for _, mt := range mod.GetDefaults().Mounts {
+		b.WriteString(styleDim.Render(fmt.Sprintf("  %s is kept in a volume by default\n", mt.Target)))
+	}
 
 	if m.creating {
-		b.WriteString(fmt.Sprintf("\n%s Creating...", m.createSpinner.View()))
+		b.WriteString(fmt.Sprintf("\n%s Creating...\n", m.createSpinner.View()))
+		b.WriteString(m.viewPull())
 	} else {
 		b.WriteString("\n[Enter] Create  [Tab] Next Field  [Esc] Cancel")
 	}
 
-	if m.createErr != "" {
+	if m.pull.err != "" {
+		// Already contains the reason; the create error would repeat it
+		b.WriteString(styleErr.Render("\nImage pull failed: " + m.pull.err))
+	} else if m.createErr != "" {
 		b.WriteString(styleErr.Render("\nError: " + m.createErr))
 	}
 
@@ -992,6 +1765,14 @@ func (m Model) cmdCreate() tea.Cmd {
 		mod := modules.Registry[m.inputType]
 		payload := mod.GetDefaults()
 		payload.Name = m.inputName.Value()
+		// Validated before the request was started
+		payload.Resources, _ = parseLimits(m.inputLimits.Value())
+		extra, _ := parseMounts(m.inputMounts.Value())
+		payload.Mounts = mergeMounts(payload.Mounts, extra)
+		payload.Networks, _ = parseNetworks(m.inputNets.Value())
+		if h, _ := parseHealthcheck(m.inputHealth.Value()); h != nil {
+			payload.Health = h
+		}
 
 		if mod.Type() == common.EnvTypeStandard {
 			payload.Image = m.inputImage.Value()
@@ -1021,6 +1802,8 @@ func (m Model) cmdCreate() tea.Cmd {
 			payload.Minecraft = mc
 		}
 
+		payload.Auth = m.registryAuth(payload.Image)
+
 		m.sshClient.SendRequest(common.Request{
 			ID:      "create",
 			Type:    common.CmdCreateEnv,
@@ -1040,16 +1823,37 @@ type logTickMsg time.Time
 type loginSuccessMsg struct{ client ssh.RemoteInterface }
 type errMsg struct{ error }
 
+// permanentError marks login failures that retrying won't fix, such as a
+// bad setting in client.ini, a changed host key or a refused password.
+type permanentError struct{ error }
+
 func (m Model) cmdLogin() tea.Cmd {
 	// Capture values to ensure closure uses correct data
 	host := m.inputHost.Value()
 	user := m.inputUser.Value()
 	pass := m.inputPassword.Value()
 	portStr := m.inputPort.Value()
+	jump := m.inputJump.Value()
+	hopPasswords := make(map[hopLogin]string, len(m.hopPasswords))
+	for hop, p := range m.hopPasswords {
+		hopPasswords[hop] = p
+	}
+	var agentArgs []string
+	var backendErr error
+	if h, ok := m.clientConfig.Hosts[host]; ok && h.Backend != "" {
+		switch h.Backend {
+		case common.BackendDocker, common.BackendPodman, common.BackendMock:
+			agentArgs = []string{"-backend=" + h.Backend}
+		default:
+			backendErr = permanentError{fmt.Errorf("invalid Backend %q for %s in client.ini (want docker, podman or mock)", h.Backend, host)}
+		}
+	}
 
 	return func() tea.Msg {
+		if backendErr != nil {
+			return errMsg{backendErr}
+		}
 		var c ssh.RemoteInterface
-		var err error
 
 		if m.DevMode {
 			c = ssh.NewLocalMockClient()
@@ -1063,14 +1867,47 @@ func (m Model) cmdLogin() tea.Cmd {
 			// Log connection attempt
 			m.logger.System("Attempting SSH connection to %s@%s:%d", user, host, port)
 
-			c, err = ssh.NewClient(host, user, port, pass, "")
+			sc, err := ssh.NewClient(host, user, port, pass, "")
 			if err != nil {
 				return errMsg{err}
 			}
+			// Every hop checks its key on its own; new hosts are trusted on first use
+			known := ssh.DefaultKnownHostsPath()
+			sc.HostKeyCallback = ssh.KnownHosts(known)
+			sc.HostKeyAlgorithms = ssh.KnownHostKeyAlgorithms(known, host, port)
+
+			if jump != "" {
+				hops, err := ssh.ParseJumpSpec(jump)
+				if err != nil {
+					return errMsg{err}
+				}
+				for i, hop := range hops {
+					if hop.User == "" {
+						hop.User = user
+					}
+					// Each hop authenticates with its own password
+					hopPass, ok := hopPassword(hopPasswords, hopLogin{hop.User, hop.Host})
+					if !ok {
+						return needHopPasswordMsg{hopLogin{hop.User, hop.Host}}
+					}
+					hops[i] = ssh.NewJumpHost(hop.Host, hop.User, hop.Port, hopPass, "")
+					hops[i].HostKeyCallback = sc.HostKeyCallback
+					hops[i].HostKeyAlgorithms = ssh.KnownHostKeyAlgorithms(known, hop.Host, hop.Port)
+				}
+				sc.JumpHosts = hops
+				m.logger.System("Using %d jump host(s): %s", len(hops), jump)
+			}
+			c = sc
 		}
 
 		if err := c.Connect(); err != nil {
-			return errMsg{fmt.Errorf("connect failed: %w", err)}
+			err = fmt.Errorf("connect failed: %w", err)
+			// A changed host key is a security stop, and repeating a refused
+			// password can lock the account
+			if errors.Is(err, ssh.ErrHostKeyChanged) || errors.Is(err, ssh.ErrAuthFailed) {
+				err = permanentError{err}
+			}
+			return errMsg{err}
 		}
 
 		if !m.DevMode {
@@ -1084,13 +1921,65 @@ func (m Model) cmdLogin() tea.Cmd {
 			}
 		}
 
-		if err := c.StartAgent(); err != nil {
+		if err := c.StartAgent(agentArgs...); err != nil {
 			return errMsg{fmt.Errorf("failed to start agent: %w", err)}
 		}
 		return loginSuccessMsg{client: c}
 	}
 }
 
+// backToLogin ends the session and shows err on the login screen, for
+// failures that retrying won't fix.
+func (m *Model) backToLogin(err string) {
+	m.logger.Error("Giving up on the session: %s", err)
+	if m.sshClient != nil {
+		m.sshClient.Close()
+		m.sshClient = nil
+	}
+	m.reconnecting = false
+	m.attached, m.attachPending = false, false
+	m.loggingIn = false
+	m.loginErr = err
+	m.state = stateLogin
+}
+
+const (
+	maxReconnectAttempts = 8
+	maxReconnectDelay    = time.Minute
+)
+
+// reconnectDelay is the pause before reconnect attempt n (from 0): 3
+// seconds, doubling up to a minute, so it gives up after about 5 minutes.
+func reconnectDelay(n int) time.Duration {
+	d := 3 * time.Second << n
+	if n > 5 || d > maxReconnectDelay {
+		return maxReconnectDelay
+	}
+	return d
+}
+
+// cmdReconnect retries the login with the current form values after delay.
+func (m Model) cmdReconnect(delay time.Duration) tea.Cmd {
+	login := m.cmdLogin()
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return login()
+	})
+}
+
+// pollCmd keeps the background polling chains alive on screens that
+// don't otherwise handle the tick messages.
+func (m Model) pollCmd(msg tea.Msg) tea.Cmd {
+	switch msg.(type) {
+	case telemetryTickMsg:
+		return m.cmdPollTelemetry()
+	case listTickMsg:
+		return tea.Batch(m.cmdPollList(), m.cmdPollListTick())
+	case statsTickMsg:
+		return m.cmdPollStats()
+	}
+	return nil
+}
+
 func (m Model) cmdPollTelemetry() tea.Cmd {
 	return tea.Tick(1*time.Second, func(t time.Time) tea.Msg {
 		if m.sshClient != nil {
@@ -1167,7 +2056,7 @@ func (m Model) cmdGetLogs(id string) tea.Cmd {
 			m.sshClient.SendRequest(common.Request{
 				ID:      "logs",
 				Type:    common.CmdGetLogs,
-				Payload: id,
+				Payload: common.LogsQuery{ID: id},
 			})
 		}
 		return nil
@@ -1191,6 +2080,58 @@ func (m Model) cmdSendInput(id, data string) tea.Cmd {
 	}
 }
 
+// maxConsoleBytes bounds the scrollback kept for an attached console,
+// unless the user paged back through the history.
+const maxConsoleBytes = 256 * 1024
+
+// appendConsole adds pushed output to the log view, following the tail
+// unless the user has scrolled up.
+func (m *Model) appendConsole(out common.ConsoleOutput) {
+	if out.Closed {
+		m.attached = false
+		return
+	}
+
+	follow := m.logsViewport.AtBottom()
+	m.logsText += out.Data
+	if len(m.logsText) > maxConsoleBytes && !m.logsPaged {
+		m.logsText = m.logsText[len(m.logsText)-maxConsoleBytes:]
+	}
+	m.logsViewport.SetContent(m.logsText)
+	if follow {
+		m.logsViewport.GotoBottom()
+	}
+}
+
+func (m *Model) cmdAttach(id string) tea.Cmd {
+	m.attachPending = true
+	client := m.sshClient
+	return func() tea.Msg {
+		if client != nil {
+			client.SendRequest(common.Request{ID: "attach", Type: common.CmdAttachEnv, Payload: id})
+		}
+		return nil
+	}
+}
+
+// cmdDetach closes the console stream for the environment being left,
+// including one still being attached: the agent handles the requests in
+// order, so the detach follows the attach.
+func (m *Model) cmdDetach() tea.Cmd {
+	if !m.attached && !m.attachPending {
+		return nil
+	}
+	m.attached, m.attachPending = false, false
+	id := m.selectedEnvID
+	client := m.sshClient
+	return func() tea.Msg {
+		if client != nil {
+			client.SendRequest(common.Request{ID: "detach", Type: common.CmdDetachEnv, Payload: id})
+		}
+		return nil
+	}
+}
+
 func (m Model) cmdLoadCredentials() tea.Cmd {
 	return func() tea.Msg {
 		host := m.inputHost.Value()
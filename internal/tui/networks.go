@@ -0,0 +1,233 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Networks ---
+func (m Model) updateNetworks(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, m.pollCmd(msg)
+	}
+
+	if m.networkCreating {
+		switch key.String() {
+		case "esc":
+			m.networkCreating = false
+			m.networkInput.Blur()
+			return m, nil
+		case "enter":
+			p, err := parseNetworkSpec(m.networkInput.Value())
+			if err != nil {
+				m.networksErr = err.Error()
+				return m, nil
+			}
+			m.networkCreating = false
+			m.networkInput.Blur()
+			m.networksErr = ""
+			m.logger.Audit("Creating network %s", p.Name)
+			return m, m.cmdNetworkAction(common.CmdCreateNetwork, p)
+		}
+		var cmd tea.Cmd
+		m.networkInput, cmd = m.networkInput.Update(msg)
+		return m, cmd
+	}
+
+	if m.networkRemove != "" {
+		name := m.networkRemove
+		m.networkRemove = ""
+		if key.String() == "y" {
+			m.logger.Audit("Removing network %s", name)
+			return m, m.cmdNetworkAction(common.CmdRemoveNetwork, name)
+		}
+		return m, nil
+	}
+
+	switch key.String() {
+	case "esc":
+		m.state = stateDashboard
+		return m, nil
+	case "l":
+		m.networksLoading = true
+		return m, m.cmdListNetworks()
+	case "n":
+		m.networkCreating = true
+		m.networksErr = ""
+		m.networkInput.SetValue("")
+		m.networkInput.Focus()
+		return m, textinput.Blink
+	case "x":
+		if m.networkCursor < len(m.networks) {
+			n := m.networks[m.networkCursor]
+			if n.Builtin {
+				m.networksErr = fmt.Sprintf("%s is built in and cannot be removed", n.Name)
+			} else {
+				m.networkRemove = n.Name
+				m.networksErr = ""
+			}
+		}
+	case "up":
+		if m.networkCursor > 0 {
+			m.networkCursor--
+		}
+	case "down":
+		if m.networkCursor < len(m.networks)-1 {
+			m.networkCursor++
+		}
+	}
+	return m, nil
+}
+
+func (m *Model) handleNetworksResponse(msg common.Response) {
+	m.networksLoading = false
+	if !msg.Success {
+		m.networksErr = msg.Error
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var list []common.NetworkInfo
+	json.Unmarshal(b, &list)
+	m.networks = list
+	if m.networkCursor >= len(list) {
+		m.networkCursor = 0
+	}
+}
+
+// handleNetworkActionResponse refreshes the list after a create or remove.
+func (m *Model) handleNetworkActionResponse(msg common.Response) tea.Cmd {
+	if !msg.Success {
+		m.networksErr = msg.Error
+		return nil
+	}
+	m.networksErr = ""
+	m.networksLoading = true
+	return m.cmdListNetworks()
+}
+
+func (m Model) viewNetworks() string {
+	b := strings.Builder{}
+	b.WriteString(styleGreen.Render("Networks") + "\n\n")
+
+	if m.networksLoading && len(m.networks) == 0 {
+		b.WriteString(styleDim.Render("Loading networks...") + "\n")
+	} else if len(m.networks) == 0 {
+		b.WriteString(styleDim.Render("(No networks)") + "\n")
+	}
+	for i, n := range m.networks {
+		pref := "  "
+		if i == m.networkCursor {
+			pref = styleGreen.Render("> ")
+		}
+		users := styleDim.Render("unused")
+		if len(n.UsedBy) > 0 {
+			users = strings.Join(n.UsedBy, ", ")
+		}
+		name := n.Name
+		if len(name) > 30 {
+			name = name[:27] + "..."
+		}
+		line := fmt.Sprintf("%s%-30s %-8s %-18s %s", pref, name, n.Driver, n.Subnet, users)
+		switch {
+		case n.Builtin:
+			line += styleDim.Render(" (built in)")
+		case !n.Managed:
+			line += styleDim.Render(" (external)")
+		}
+		if n.Internal {
+			line += styleDim.Render(" (internal)")
+		}
+		b.WriteString(line + "\n")
+	}
+
+	if m.networkCreating {
+		b.WriteString("\nNew network: " + m.networkInput.View() + "\n")
+		b.WriteString(styleDim.Render("name [subnet=10.10.0.0/24] [driver=bridge] [internal]  [Enter] Create  [Esc] Cancel"))
+	} else if m.networkRemove != "" {
+		b.WriteString(styleErr.Render(fmt.Sprintf("\nRemove network %s?", m.networkRemove)) + "\n")
+		b.WriteString(styleDim.Render("[Y] Remove  [Esc] Cancel"))
+	} else {
+		b.WriteString(styleDim.Render("\n[N] New  [X] Remove  [L] Refresh  [Esc] Back"))
+	}
+	if m.networksErr != "" {
+		b.WriteString(styleErr.Render("\nError: " + m.networksErr))
+	}
+	return styleBox.Render(b.String())
+}
+
+// parseNetworkSpec reads the new network prompt: a name followed by
+// optional "subnet=", "driver=" and "internal" words.
+func parseNetworkSpec(s string) (common.CreateNetworkPayload, error) {
+	var p common.CreateNetworkPayload
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return p, fmt.Errorf("network name is required")
+	}
+	p.Name = fields[0]
+	for _, f := range fields[1:] {
+		k, v, _ := strings.Cut(f, "=")
+		switch strings.ToLower(k) {
+		case "subnet":
+			p.Subnet = v
+		case "driver":
+			p.Driver = v
+		case "internal":
+			p.Internal = true
+		default:
+			return p, fmt.Errorf("unknown network option %q", f)
+		}
+	}
+	return p, nil
+}
+
+// parseNetworks reads the Networks field of the create form: entries
+// separated by commas, each a network name optionally followed by
+// aliases, e.g. "shop:db:postgres, public".
+func parseNetworks(s string) ([]common.NetworkAttachment, error) {
+	var res []common.NetworkAttachment
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		n := common.NetworkAttachment{Name: parts[0]}
+		for _, a := range parts[1:] {
+			if a = strings.TrimSpace(a); a == "" {
+				return nil, fmt.Errorf("empty alias in %q", entry)
+			}
+			n.Aliases = append(n.Aliases, a)
+		}
+		if n.Name == "" {
+			return nil, fmt.Errorf("missing network name in %q", entry)
+		}
+		res = append(res, n)
+	}
+	return res, nil
+}
+
+func (m Model) cmdListNetworks() tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "networks", Type: common.CmdListNetworks})
+		}
+		return nil
+	}
+}
+
+// cmdNetworkAction sends a create or remove request. The reply uses the
+// "network" ID so the panel can refresh itself.
+func (m Model) cmdNetworkAction(t common.CommandType, payload interface{}) tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "network", Type: t, Payload: payload})
+		}
+		return nil
+	}
+}
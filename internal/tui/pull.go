@@ -0,0 +1,101 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+)
+
+// maxPullLayers bounds how many layers the create screen lists.
+const maxPullLayers = 8
+
+// pullState tracks the image pull of the environment being created or
+// updated, or of a pull started from the images screen.
+type pullState struct {
+	layers []common.PullProgress // In order of first appearance
+	status string                // Latest message about the whole image
+	err    string                // The pull failed
+	warn   string                // The pull failed but a local copy is used
+}
+
+func (m *Model) handlePullEvent(msg common.Response) {
+	if !m.creating && !m.updating && !m.imagePulling {
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var p common.PullProgress
+	if json.Unmarshal(b, &p) != nil {
+		return
+	}
+
+	if p.Done {
+		if p.Error != "" && p.Status != "" {
+			m.pull.warn = p.Status + ": " + p.Error
+		} else if p.Error != "" {
+			m.pull.err = p.Error
+		}
+		m.pull.status = p.Status
+		return
+	}
+	if p.Layer == "" {
+		m.pull.status = p.Status
+		return
+	}
+	for i := range m.pull.layers {
+		if m.pull.layers[i].Layer == p.Layer {
+			m.pull.layers[i] = p
+			return
+		}
+	}
+	m.pull.layers = append(m.pull.layers, p)
+}
+
+func (m Model) viewPull() string {
+	b := strings.Builder{}
+	if m.pull.status != "" {
+		b.WriteString(styleDim.Render(m.pull.status) + "\n")
+	}
+
+	layers := m.pull.layers
+	if len(layers) > maxPullLayers {
+		// Finished layers scroll off first
+		var active []common.PullProgress
+		for _, l := range layers {
+			if l.Total > 0 && l.Current < l.Total {
+				active = append(active, l)
+			}
+		}
+		b.WriteString(styleDim.Render(fmt.Sprintf("%d layers, %d in progress", len(layers), len(active))) + "\n")
+		layers = active
+		if len(layers) > maxPullLayers {
+			layers = layers[:maxPullLayers]
+		}
+	}
+	for _, l := range layers {
+		id := l.Layer
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		line := fmt.Sprintf("  %-12s %-16s", id, l.Status)
+		if l.Total > 0 {
+			line += fmt.Sprintf(" %s %s/%s", progressBar(l.Current, l.Total, 20),
+				formatBytes(uint64(l.Current)), formatBytes(uint64(l.Total)))
+		}
+		b.WriteString(line + "\n")
+	}
+
+	if m.pull.warn != "" {
+		b.WriteString(styleDim.Render(m.pull.warn) + "\n")
+	}
+	return b.String()
+}
+
+func progressBar(current, total int64, width int) string {
+	filled := int(current * int64(width) / total)
+	if filled > width {
+		filled = width
+	}
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
@@ -0,0 +1,189 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/config"
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/utils"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Registries ---
+func (m Model) updateRegistries(msg tea.Msg) (tea.Model, tea.Cmd) {
+	hosts := m.registryHosts()
+
+	if key, ok := msg.(tea.KeyMsg); ok {
+		if m.registryEditing {
+			switch key.String() {
+			case "esc":
+				m.registryEditing = false
+				m.blurRegistryForm()
+				return m, nil
+			case "tab":
+				switch {
+				case m.registryHost.Focused():
+					m.registryHost.Blur()
+					m.registryUser.Focus()
+				case m.registryUser.Focused():
+					m.registryUser.Blur()
+					m.registryPass.Focus()
+				default:
+					m.registryPass.Blur()
+					m.registryHost.Focus()
+				}
+				return m, textinput.Blink
+			case "enter":
+				if err := m.saveRegistry(); err != nil {
+					m.registryErr = err.Error()
+					return m, nil
+				}
+				m.registryErr = ""
+				m.registryEditing = false
+				m.blurRegistryForm()
+				return m, nil
+			}
+		} else {
+			switch key.String() {
+			case "esc":
+				m.state = stateDashboard
+				return m, nil
+			case "up":
+				if m.registryCursor > 0 {
+					m.registryCursor--
+				}
+			case "down":
+				if m.registryCursor < len(hosts)-1 {
+					m.registryCursor++
+				}
+			case "n":
+				m.registryEditing = true
+				m.registryErr = ""
+				m.registryHost.SetValue("")
+				m.registryUser.SetValue("")
+				m.registryPass.SetValue("")
+				m.registryHost.Focus()
+				return m, textinput.Blink
+			case "x":
+				if m.registryCursor < len(hosts) {
+					m.deleteRegistry(hosts[m.registryCursor])
+					if m.registryCursor > 0 && m.registryCursor >= len(hosts)-1 {
+						m.registryCursor--
+					}
+				}
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	switch {
+	case m.registryHost.Focused():
+		m.registryHost, cmd = m.registryHost.Update(msg)
+	case m.registryUser.Focused():
+		m.registryUser, cmd = m.registryUser.Update(msg)
+	case m.registryPass.Focused():
+		m.registryPass, cmd = m.registryPass.Update(msg)
+	}
+	return m, tea.Batch(cmd, m.pollCmd(msg))
+}
+
+func (m *Model) blurRegistryForm() {
+	m.registryHost.Blur()
+	m.registryUser.Blur()
+	m.registryPass.Blur()
+	m.registryPass.SetValue("")
+}
+
+func (m Model) registryHosts() []string {
+	var hosts []string
+	for host := range m.clientConfig.Registries {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// saveRegistry stores the username in client.ini and the password in the
+// OS keyring. A previous login for the host is replaced.
+func (m *Model) saveRegistry() error {
+	host := strings.TrimSpace(m.registryHost.Value())
+	user := strings.TrimSpace(m.registryUser.Value())
+	if host == "" || user == "" || m.registryPass.Value() == "" {
+		return fmt.Errorf("registry, username and password are required")
+	}
+
+	if old, ok := m.clientConfig.Registries[host]; ok && old.Username != user {
+		utils.DeleteRegistryPassword(host, old.Username)
+	}
+	if err := utils.StoreRegistryPassword(host, user, m.registryPass.Value()); err != nil {
+		return fmt.Errorf("failed to store password in keyring: %w", err)
+	}
+	m.clientConfig.Registry(host).Username = user
+	if err := config.SaveClientConfig(m.clientConfig); err != nil {
+		return err
+	}
+	m.logger.Audit("Saved registry login %s@%s", user, host)
+	return nil
+}
+
+func (m *Model) deleteRegistry(host string) {
+	if r, ok := m.clientConfig.Registries[host]; ok {
+		utils.DeleteRegistryPassword(host, r.Username)
+	}
+	delete(m.clientConfig.Registries, host)
+	if err := config.SaveClientConfig(m.clientConfig); err != nil {
+		m.logger.Error("Failed to save config: %v", err)
+	}
+	m.logger.Audit("Removed registry login for %s", host)
+}
+
+// registryAuth looks up stored credentials for the registry an image comes
+// from. It returns nil when there is no login, so public pulls still work.
+func (m Model) registryAuth(image string) *common.RegistryAuth {
+	host := common.RegistryHost(image)
+	r, ok := m.clientConfig.Registries[host]
+	if !ok || r.Username == "" {
+		return nil
+	}
+	pass, err := utils.GetRegistryPassword(host, r.Username)
+	if err != nil {
+		m.logger.Error("No keyring password for registry %s: %v", host, err)
+		return nil
+	}
+	return &common.RegistryAuth{ServerAddress: host, Username: r.Username, Password: pass}
+}
+
+func (m Model) viewRegistries() string {
+	b := strings.Builder{}
+	b.WriteString(styleGreen.Render("Registry Logins") + "\n\n")
+
+	hosts := m.registryHosts()
+	if len(hosts) == 0 {
+		b.WriteString(styleDim.Render("(No logins, public images only)") + "\n")
+	}
+	for i, host := range hosts {
+		pref := "  "
+		if i == m.registryCursor {
+			pref = styleGreen.Render("> ")
+		}
+		b.WriteString(fmt.Sprintf("%s%-30s %s\n", pref, host, m.clientConfig.Registries[host].Username))
+	}
+
+	if m.registryEditing {
+		b.WriteString("\nNew login (password is kept in the OS keyring)\n")
+		b.WriteString(fmt.Sprintf("Registry: %s\n", m.registryHost.View()))
+		b.WriteString(fmt.Sprintf("Username: %s\n", m.registryUser.View()))
+		b.WriteString(fmt.Sprintf("Password: %s\n", m.registryPass.View()))
+		b.WriteString(styleDim.Render("\n[Enter] Save  [Tab] Next Field  [Esc] Cancel"))
+	} else {
+		b.WriteString(styleDim.Render("\n[N] Add/Replace  [X] Remove  [Esc] Back"))
+	}
+
+	if m.registryErr != "" {
+		b.WriteString(styleErr.Render("\nError: " + m.registryErr))
+	}
+	return styleBox.Render(b.String())
+}
@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"io"
+	"os"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/ssh"
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
+	"github.com/muesli/cancelreader"
+)
+
+type shellExitMsg struct{ err error }
+
+// shellCommand hands the terminal to an interactive container shell.
+// It implements tea.ExecCommand so Bubble Tea releases the screen first.
+type shellCommand struct {
+	client      ssh.RemoteInterface
+	containerID string
+	stdin       io.Reader
+	stdout      io.Writer
+}
+
+func (c *shellCommand) SetStdin(r io.Reader)  { c.stdin = r }
+func (c *shellCommand) SetStdout(w io.Writer) { c.stdout = w }
+func (c *shellCommand) SetStderr(io.Writer)   {}
+
+func (c *shellCommand) Run() error {
+	if c.stdin == nil {
+		c.stdin = os.Stdin
+	}
+	if c.stdout == nil {
+		c.stdout = os.Stdout
+	}
+
+	// Raw passthrough: the container's TTY does echo and line editing
+	if f, ok := c.stdin.(*os.File); ok && term.IsTerminal(f.Fd()) {
+		state, err := term.MakeRaw(f.Fd())
+		if err == nil {
+			defer term.Restore(f.Fd(), state)
+		}
+	}
+
+	size := func() (int, int) {
+		if f, ok := c.stdout.(*os.File); ok {
+			if w, h, err := term.GetSize(f.Fd()); err == nil {
+				return w, h
+			}
+		}
+		return 80, 24
+	}
+
+	// Cancelable so the copy goroutine stops reading once the shell exits
+	// and doesn't swallow the first key meant for the dashboard.
+	in, err := cancelreader.NewReader(c.stdin)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	defer in.Cancel()
+
+	cols, rows := size()
+	sh, err := c.client.OpenShell(c.containerID, cols, rows, in, c.stdout)
+	if err != nil {
+		return err
+	}
+	defer sh.Close()
+
+	stop := utils.NotifyResize(func() {
+		w, h := size()
+		if w != cols || h != rows {
+			cols, rows = w, h
+			sh.Resize(w, h)
+		}
+	})
+	defer stop()
+
+	return sh.Wait()
+}
+
+func (m Model) cmdShell(id string) tea.Cmd {
+	m.logger.Audit("Opened shell in %s", id)
+	return tea.Exec(&shellCommand{client: m.sshClient, containerID: id}, func(err error) tea.Msg {
+		return shellExitMsg{err: err}
+	})
+}
@@ -0,0 +1,270 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// dashRow is one line of the environment list: a container, or the
+// header of a compose stack. Members of an expanded stack follow their
+// header as container rows.
+type dashRow struct {
+	stack     string // Set for stack headers
+	members   []common.ContainerInfo
+	container common.ContainerInfo
+}
+
+// dashRows groups stack containers under one entry, placed where the
+// first of them appears in the list. Containers not managed by PerSSH are
+// left out unless showAll is set.
+func (m Model) dashRows() []dashRow {
+	stacks := make(map[string][]common.ContainerInfo)
+	for _, c := range m.containers {
+		if c.Stack != "" {
+			stacks[c.Stack] = append(stacks[c.Stack], c)
+		}
+	}
+
+	var rows []dashRow
+	seen := make(map[string]bool)
+	for _, c := range m.containers {
+		if !c.Managed() && !m.showAll {
+			continue
+		}
+		if c.Stack == "" {
+			rows = append(rows, dashRow{container: c})
+			continue
+		}
+		if seen[c.Stack] {
+			continue
+		}
+		seen[c.Stack] = true
+		members := stacks[c.Stack]
+		sort.Slice(members, func(i, j int) bool { return members[i].Service < members[j].Service })
+		rows = append(rows, dashRow{stack: c.Stack, members: members})
+		if m.expanded[c.Stack] {
+			for _, mc := range members {
+				rows = append(rows, dashRow{container: mc})
+			}
+		}
+	}
+	return rows
+}
+
+// hiddenCount is the number of containers the managed-only list leaves
+// out.
+func (m Model) hiddenCount() int {
+	n := 0
+	for _, c := range m.containers {
+		if !c.Managed() {
+			n++
+		}
+	}
+	return n
+}
+
+func (m Model) selectedRow() (dashRow, bool) {
+	rows := m.dashRows()
+	if m.cursor < 0 || m.cursor >= len(rows) {
+		return dashRow{}, false
+	}
+	return rows[m.cursor], true
+}
+
+// selectedContainer is the container under the cursor; false on stack
+// headers.
+func (m Model) selectedContainer() (common.ContainerInfo, bool) {
+	row, ok := m.selectedRow()
+	if !ok || row.stack != "" {
+		return common.ContainerInfo{}, false
+	}
+	return row.container, true
+}
+
+// updateStackKeys handles the dashboard keys on a stack header. It
+// reports false for keys it does not own.
+func (m Model) updateStackKeys(key tea.KeyMsg) (Model, tea.Cmd, bool) {
+	row, ok := m.selectedRow()
+	if !ok || row.stack == "" {
+		return m, nil, false
+	}
+	switch key.String() {
+	case "enter", " ":
+		if m.expanded == nil {
+			m.expanded = make(map[string]bool)
+		}
+		m.expanded[row.stack] = !m.expanded[row.stack]
+		return m, nil, true
+	case "s":
+		if running, _ := stackStatus(row.members); running > 0 {
+			m.logger.Audit("Stopping stack %s", row.stack)
+			return m, m.cmdEnvAction(common.CmdStopStack, row.stack), true
+		}
+		m.logger.Audit("Starting stack %s", row.stack)
+		return m, m.cmdEnvAction(common.CmdStartStack, row.stack), true
+	case "x":
+		m.removeStack = row.stack
+		return m, nil, true
+	case "r", "p", "n", "k", "a":
+		m.dashMsg = "Expand the stack and select a service"
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+func stackStatus(members []common.ContainerInfo) (running, total int) {
+	for _, c := range members {
+		if c.Status == "running" {
+			running++
+		}
+	}
+	return running, len(members)
+}
+
+func (m Model) viewStackRow(row dashRow, selected bool) string {
+	pref := "  "
+	if selected {
+		pref = styleGreen.Render("> ")
+	}
+	arrow := "▸"
+	if m.expanded[row.stack] {
+		arrow = "▾"
+	}
+	running, total := stackStatus(row.members)
+	statusStyle := styleDim
+	if running > 0 {
+		statusStyle = styleGreen
+	}
+	status := statusStyle.Render(fmt.Sprintf("%d/%d running", running, total))
+	unhealthy := 0
+	for _, c := range row.members {
+		if c.Status == "running" && c.Health == "unhealthy" {
+			unhealthy++
+		}
+	}
+	if unhealthy > 0 {
+		status += styleWarn.Render(fmt.Sprintf(", %d unhealthy", unhealthy))
+	}
+	return fmt.Sprintf("%s%s %s (stack) [%s]", pref, arrow, row.stack, status)
+}
+
+// stackVolumes lists the volumes a stack created, which are the ones
+// removing it with volumes deletes.
+func (m Model) stackVolumes(stack string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, c := range m.containers {
+		if c.Stack != stack {
+			continue
+		}
+		for _, v := range namedVolumes(c) {
+			if strings.HasPrefix(v, stack+"_") && !seen[v] {
+				seen[v] = true
+				names = append(names, v)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// updateRemoveStackConfirm mirrors updateRemoveConfirm for whole stacks.
+func (m Model) updateRemoveStackConfirm(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	stack := m.removeStack
+	switch key.String() {
+	case "y":
+		m.removeStack = ""
+		m.logger.Audit("Removing stack %s (keeping volumes)", stack)
+		return m, m.cmdEnvAction(common.CmdRemoveStack, common.RemoveStackPayload{Name: stack, KeepVolumes: true})
+	case "d":
+		if len(m.stackVolumes(stack)) == 0 {
+			return m, nil
+		}
+		m.removeStack = ""
+		m.logger.Audit("Removing stack %s and volumes %s", stack, strings.Join(m.stackVolumes(stack), ", "))
+		return m, m.cmdEnvAction(common.CmdRemoveStack, common.RemoveStackPayload{Name: stack})
+	case "n", "esc":
+		m.removeStack = ""
+	}
+	return m, nil
+}
+
+func (m Model) viewRemoveStackConfirm() string {
+	s := styleErr.Render(fmt.Sprintf("Remove stack %s and all its containers?", m.removeStack)) + "\n"
+	if vols := m.stackVolumes(m.removeStack); len(vols) > 0 {
+		s += fmt.Sprintf("Volumes: %s\n", strings.Join(vols, ", "))
+		s += styleDim.Render("[Y] Remove, keep volumes  [D] Remove and delete volumes  [Esc] Cancel")
+	} else {
+		s += styleDim.Render("[Y] Remove  [Esc] Cancel")
+	}
+	return s
+}
+
+// composeImages reads the images of a compose file so the matching
+// registry logins can be sent along. The agent does the real parsing.
+func composeImages(data []byte) ([]string, error) {
+	var f struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid compose file: %w", err)
+	}
+	if len(f.Services) == 0 {
+		return nil, fmt.Errorf("compose file has no services")
+	}
+	var images []string
+	for _, s := range f.Services {
+		if s.Image != "" {
+			images = append(images, s.Image)
+		}
+	}
+	return images, nil
+}
+
+// readCompose loads the compose file named in the create form.
+func (m Model) readCompose() ([]byte, error) {
+	path := strings.TrimSpace(m.inputYAML.Value())
+	if path == "" {
+		return nil, fmt.Errorf("compose file path is required")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := composeImages(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// cmdDeployStack sends the compose file with the logins for its images.
+// The reply uses the "create" ID, like single environments.
+func (m Model) cmdDeployStack(name string, data []byte) tea.Cmd {
+	return func() tea.Msg {
+		images, _ := composeImages(data)
+		var auths []common.RegistryAuth
+		seen := make(map[string]bool)
+		for _, img := range images {
+			if a := m.registryAuth(img); a != nil && !seen[a.ServerAddress] {
+				seen[a.ServerAddress] = true
+				auths = append(auths, *a)
+			}
+		}
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{
+				ID:      "create",
+				Type:    common.CmdDeployStack,
+				Payload: common.DeployStackPayload{Name: name, Compose: string(data), Auth: auths},
+			})
+		}
+		return nil
+	}
+}
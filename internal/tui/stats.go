@@ -0,0 +1,74 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type statsTickMsg time.Time
+
+// statsHistoryLen matches the host graph history.
+const statsHistoryLen = 300
+
+// cmdPollStats samples every running container. The agent needs about a
+// second per sample, so this runs slower than telemetry.
+func (m Model) cmdPollStats() tea.Cmd {
+	return tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "stats", Type: common.CmdContainerStats, Payload: ""})
+		}
+		return statsTickMsg(t)
+	})
+}
+
+func (m *Model) handleStatsResponse(msg common.Response) {
+	if !msg.Success {
+		m.logger.Error("Container stats failed: %s", msg.Error)
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var list []common.ContainerStats
+	json.Unmarshal(b, &list)
+
+	m.stats = make(map[string]common.ContainerStats, len(list))
+	for _, s := range list {
+		m.stats[s.ID] = s
+	}
+
+	if m.state != stateEnvDetails {
+		return
+	}
+	s, ok := m.stats[m.selectedEnvID]
+	if !ok {
+		// Stopped; keep the graph moving at zero
+		s = common.ContainerStats{}
+	}
+	mem := 0.0
+	if s.MemLimit > 0 {
+		mem = float64(s.MemUsage) / float64(s.MemLimit) * 100
+	}
+	m.envCPUHistory = appendHistory(m.envCPUHistory, s.CPUPercent)
+	m.envMemHistory = appendHistory(m.envMemHistory, mem)
+}
+
+func appendHistory(h []float64, v float64) []float64 {
+	h = append(h, v)
+	if len(h) > statsHistoryLen {
+		h = h[1:]
+	}
+	return h
+}
+
+// formatStats renders the dashboard columns for one environment.
+func formatStats(s common.ContainerStats) string {
+	return fmt.Sprintf("CPU %5.1f%%  MEM %s/%s  NET %s/%s  IO %s/%s  PIDS %d",
+		s.CPUPercent,
+		formatBytes(s.MemUsage), formatBytes(s.MemLimit),
+		formatBytes(s.NetRx), formatBytes(s.NetTx),
+		formatBytes(s.BlockRead), formatBytes(s.BlockWrite),
+		s.PIDs)
+}
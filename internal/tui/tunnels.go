@@ -0,0 +1,225 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/config"
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/tunnel"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Tunnels ---
+func (m Model) updateTunnels(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	list := m.tunnels.List()
+
+	if key, ok := msg.(tea.KeyMsg); ok {
+		if m.tunnelEditing {
+			switch key.String() {
+			case "esc":
+				m.tunnelEditing = false
+				m.tunnelBind.Blur()
+				m.tunnelTarget.Blur()
+				return m, nil
+			case "tab":
+				if m.tunnelMode == tunnel.ModeDynamic {
+					return m, nil
+				}
+				if m.tunnelBind.Focused() {
+					m.tunnelBind.Blur()
+					m.tunnelTarget.Focus()
+				} else {
+					m.tunnelTarget.Blur()
+					m.tunnelBind.Focus()
+				}
+				return m, textinput.Blink
+			case "enter":
+				spec := tunnel.Spec{Mode: m.tunnelMode, Bind: m.tunnelBind.Value(), Target: m.tunnelTarget.Value()}
+				if spec.Mode == tunnel.ModeDynamic {
+					spec.Target = ""
+				}
+				if _, err := m.tunnels.Open(m.tunnelEnv, spec); err != nil {
+					m.tunnelErr = err.Error()
+					return m, nil
+				}
+				m.logger.Audit("Opened tunnel %s for %s", spec, m.tunnelEnv)
+				m.saveTunnels()
+				m.tunnelErr = ""
+				m.tunnelEditing = false
+				m.tunnelBind.Blur()
+				m.tunnelTarget.Blur()
+				return m, nil
+			}
+		} else {
+			switch key.String() {
+			case "esc":
+				m.state = stateDashboard
+				return m, nil
+			case "up":
+				if m.tunnelCursor > 0 {
+					m.tunnelCursor--
+				}
+			case "down":
+				if m.tunnelCursor < len(list)-1 {
+					m.tunnelCursor++
+				}
+			case "n":
+				m.startTunnelForm(tunnel.ModeLocal)
+				return m, textinput.Blink
+			case "r":
+				m.startTunnelForm(tunnel.ModeRemote)
+				return m, textinput.Blink
+			case "d":
+				m.startTunnelForm(tunnel.ModeDynamic)
+				return m, textinput.Blink
+			case "x":
+				if m.tunnelCursor < len(list) {
+					t := list[m.tunnelCursor]
+					m.tunnels.Close(t.ID)
+					m.logger.Audit("Closed tunnel %s", t.Spec)
+					m.saveTunnels()
+					if m.tunnelCursor > 0 && m.tunnelCursor >= len(list)-1 {
+						m.tunnelCursor--
+					}
+				}
+			}
+		}
+	}
+
+	if m.tunnelBind.Focused() {
+		m.tunnelBind, cmd = m.tunnelBind.Update(msg)
+	}
+	if m.tunnelTarget.Focused() {
+		m.tunnelTarget, cmd = m.tunnelTarget.Update(msg)
+	}
+
+	return m, tea.Batch(cmd, m.pollCmd(msg))
+}
+
+// startTunnelForm prefills the forward form. Local forwards target the
+// selected environment's first published port; reverse forwards default to
+// the docker0 gateway so containers on the default bridge can reach them.
+func (m *Model) startTunnelForm(mode tunnel.Mode) {
+	m.tunnelEditing = true
+	m.tunnelMode = mode
+	m.tunnelErr = ""
+	m.tunnelEnv = ""
+	m.tunnelBind.SetValue("")
+	m.tunnelTarget.SetValue("")
+
+	c, selected := m.selectedContainer()
+	if selected {
+		m.tunnelEnv = c.Name
+	}
+
+	switch mode {
+	case tunnel.ModeLocal:
+		if selected {
+			for _, p := range c.Ports {
+				if p.HostPort != 0 && p.Protocol == "tcp" {
+					m.tunnelBind.SetValue(fmt.Sprintf("127.0.0.1:%d", p.HostPort))
+					m.tunnelTarget.SetValue(fmt.Sprintf("localhost:%d", p.HostPort))
+					break
+				}
+			}
+		}
+	case tunnel.ModeRemote:
+		m.tunnelBind.SetValue("172.17.0.1:8080")
+		m.tunnelTarget.SetValue("127.0.0.1:8080")
+	case tunnel.ModeDynamic:
+		m.tunnelBind.SetValue("127.0.0.1:1080")
+	}
+
+	m.tunnelTarget.Blur()
+	m.tunnelBind.Focus()
+}
+
+// restoreTunnels reopens the forwards remembered for the current host.
+func (m *Model) restoreTunnels() {
+	host := m.inputHost.Value()
+	for _, line := range m.clientConfig.Host(host).Forwards {
+		spec, env, err := tunnel.ParseSpec(line)
+		if err != nil {
+			m.logger.Error("Skipping saved forward: %v", err)
+			continue
+		}
+		if _, err := m.tunnels.Open(env, spec); err != nil {
+			m.logger.Error("Failed to restore forward %s: %v", spec, err)
+		}
+	}
+}
+
+// saveTunnels persists the open forwards for the current host in client.ini.
+func (m *Model) saveTunnels() {
+	var lines []string
+	for _, t := range m.tunnels.List() {
+		lines = append(lines, t.Spec.Encode(t.Env))
+	}
+	m.clientConfig.Host(m.inputHost.Value()).Forwards = lines
+	if err := config.SaveClientConfig(m.clientConfig); err != nil {
+		m.logger.Error("Failed to save forwards: %v", err)
+	}
+}
+
+func (m Model) viewTunnels() string {
+	b := strings.Builder{}
+	b.WriteString(styleGreen.Render("Tunnels") + "\n\n")
+
+	list := m.tunnels.List()
+	if len(list) == 0 {
+		b.WriteString(styleDim.Render("(No active forwards)") + "\n")
+	}
+	for i, t := range list {
+		pref := "  "
+		if i == m.tunnelCursor {
+			pref = styleGreen.Render("> ")
+		}
+		line := fmt.Sprintf("%s%-12s %s  conns:%d  in:%s  out:%s",
+			pref, t.Env, t.Spec, t.Conns, formatBytes(t.BytesIn), formatBytes(t.BytesOut))
+		b.WriteString(line + "\n")
+		if t.Err != "" {
+			b.WriteString("    " + styleErr.Render(t.Err) + "\n")
+		}
+	}
+
+	if m.tunnelEditing {
+		switch m.tunnelMode {
+		case tunnel.ModeRemote:
+			b.WriteString(fmt.Sprintf("\nNew reverse forward for %s\n", styleGreen.Render(m.tunnelEnv)))
+			b.WriteString(fmt.Sprintf("Host bind:    %s\n", m.tunnelBind.View()))
+			b.WriteString(fmt.Sprintf("Local target: %s\n", m.tunnelTarget.View()))
+		case tunnel.ModeDynamic:
+			b.WriteString("\nNew SOCKS5 proxy (resolves names on the host)\n")
+			b.WriteString(fmt.Sprintf("Local bind:   %s\n", m.tunnelBind.View()))
+		default:
+			b.WriteString(fmt.Sprintf("\nNew forward for %s\n", styleGreen.Render(m.tunnelEnv)))
+			b.WriteString(fmt.Sprintf("Local bind:   %s\n", m.tunnelBind.View()))
+			b.WriteString(fmt.Sprintf("Host target:  %s\n", m.tunnelTarget.View()))
+		}
+		b.WriteString(styleDim.Render("\n[Enter] Open  [Tab] Next Field  [Esc] Cancel"))
+	} else {
+		b.WriteString(styleDim.Render("\n[N] Local  [R] Reverse  [D] SOCKS5  [X] Close  [Esc] Back"))
+	}
+
+	if m.tunnelErr != "" {
+		b.WriteString(styleErr.Render("\nError: " + m.tunnelErr))
+	}
+
+	return styleBox.Render(b.String())
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,212 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/COMPANYNAMEHERE/PerSSH/internal/common"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Volumes ---
+func (m Model) updateVolumes(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "esc":
+			m.state = stateDashboard
+			return m, nil
+		case "l":
+			m.volumesLoading = true
+			return m, m.cmdListVolumes()
+		case "up":
+			if m.volumeCursor > 0 {
+				m.volumeCursor--
+			}
+		case "down":
+			if m.volumeCursor < len(m.volumes)-1 {
+				m.volumeCursor++
+			}
+		}
+	}
+	return m, m.pollCmd(msg)
+}
+
+func (m *Model) handleVolumesResponse(msg common.Response) {
+	m.volumesLoading = false
+	m.volumesErr = ""
+	if !msg.Success {
+		m.volumesErr = msg.Error
+		return
+	}
+	b, _ := json.Marshal(msg.Data)
+	var list []common.VolumeInfo
+	json.Unmarshal(b, &list)
+	m.volumes = list
+	if m.volumeCursor >= len(list) {
+		m.volumeCursor = 0
+	}
+}
+
+func (m Model) viewVolumes() string {
+	b := strings.Builder{}
+	b.WriteString(styleGreen.Render("Volumes") + "\n\n")
+
+	if m.volumesLoading {
+		b.WriteString(styleDim.Render("Measuring volumes...") + "\n")
+	} else if len(m.volumes) == 0 {
+		b.WriteString(styleDim.Render("(No volumes)") + "\n")
+	}
+	for i, v := range m.volumes {
+		pref := "  "
+		if i == m.volumeCursor {
+			pref = styleGreen.Render("> ")
+		}
+		size := "?"
+		if v.Size >= 0 {
+			size = formatBytes(uint64(v.Size))
+		}
+		users := styleDim.Render("unused")
+		if len(v.UsedBy) > 0 {
+			users = strings.Join(v.UsedBy, ", ")
+		}
+		name := v.Name
+		if len(name) > 40 {
+			name = name[:37] + "..."
+		}
+		line := fmt.Sprintf("%s%-40s %9s  %s", pref, name, size, users)
+		if !v.Managed {
+			line += styleDim.Render(" (external)")
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString(styleDim.Render("\n[L] Refresh  [Esc] Back"))
+	if m.volumesErr != "" {
+		b.WriteString(styleErr.Render("\nError: " + m.volumesErr))
+	}
+	return styleBox.Render(b.String())
+}
+
+// parseMounts reads docker -v style entries separated by commas:
+// "/data" (volume named after the environment), "name:/data",
+// "/srv/mods:/mods:ro".
+func parseMounts(s string) ([]common.Mount, error) {
+	var res []common.Mount
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		var mt common.Mount
+		if n := len(parts); n > 1 && (parts[n-1] == "ro" || parts[n-1] == "rw") {
+			mt.ReadOnly = parts[n-1] == "ro"
+			parts = parts[:n-1]
+		}
+		switch len(parts) {
+		case 1:
+			mt.Target = parts[0]
+		case 2:
+			mt.Source, mt.Target = parts[0], parts[1]
+		default:
+			return nil, fmt.Errorf("invalid mount %q", entry)
+		}
+		if !strings.HasPrefix(mt.Target, "/") {
+			return nil, fmt.Errorf("mount target in %q must be an absolute path", entry)
+		}
+		res = append(res, mt)
+	}
+	return res, nil
+}
+
+// mergeMounts overrides module defaults that share a target.
+func mergeMounts(defaults, extra []common.Mount) []common.Mount {
+	res := append([]common.Mount(nil), extra...)
+	for _, d := range defaults {
+		overridden := false
+		for _, e := range extra {
+			if e.Target == d.Target {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+// namedVolumes lists the volumes (not bind mounts) an environment uses.
+func namedVolumes(c common.ContainerInfo) []string {
+	var names []string
+	for _, mt := range c.Mounts {
+		if !mt.IsBind() {
+			names = append(names, mt.Source)
+		}
+	}
+	return names
+}
+
+// updateRemoveConfirm handles the prompt shown before removing an
+// environment. Volumes are only deleted on an explicit choice.
+func (m Model) updateRemoveConfirm(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.removeTarget
+	switch key.String() {
+	case "y":
+		m.removeTarget = nil
+		m.logger.Audit("Removing %s (keeping volumes)", c.Name)
+		return m, m.cmdRemove(*c, true)
+	case "d":
+		if len(namedVolumes(*c)) == 0 {
+			return m, nil
+		}
+		m.removeTarget = nil
+		m.logger.Audit("Removing %s and volumes %s", c.Name, strings.Join(namedVolumes(*c), ", "))
+		return m, m.cmdRemove(*c, false)
+	case "n", "esc":
+		m.removeTarget = nil
+	}
+	return m, nil
+}
+
+func (m Model) viewRemoveConfirm() string {
+	c := m.removeTarget
+	s := styleErr.Render(fmt.Sprintf("Remove %s?", c.Name)) + "\n"
+	if !c.Managed() {
+		s += styleWarn.Render("This container was not created by PerSSH.") + "\n"
+	}
+	if vols := namedVolumes(*c); len(vols) > 0 {
+		s += fmt.Sprintf("Volumes: %s\n", strings.Join(vols, ", "))
+		s += styleDim.Render("[Y] Remove, keep volumes  [D] Remove and delete volumes  [Esc] Cancel")
+	} else {
+		s += styleDim.Render("[Y] Remove  [Esc] Cancel")
+	}
+	return s
+}
+
+func (m Model) cmdListVolumes() tea.Cmd {
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{ID: "volumes", Type: common.CmdListVolumes})
+		}
+		return nil
+	}
+}
+
+// cmdRemove removes c. The confirmation warns about unmanaged containers,
+// so the agent is told to remove them anyway.
+func (m Model) cmdRemove(c common.ContainerInfo, keepVolumes bool) tea.Cmd {
+	payload := common.RemoveEnvPayload{ID: c.ID, KeepVolumes: keepVolumes, Force: !c.Managed()}
+	return func() tea.Msg {
+		if m.sshClient != nil {
+			m.sshClient.SendRequest(common.Request{
+				ID:      "action",
+				Type:    common.CmdRemoveEnv,
+				Payload: payload,
+			})
+		}
+		return nil
+	}
+}
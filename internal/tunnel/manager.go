@@ -0,0 +1,287 @@
+package tunnel
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// Remote opens connections and listeners on the far side of the SSH link.
+// ssh.RemoteInterface satisfies it.
+type Remote interface {
+	Dial(network, addr string) (net.Conn, error)
+	Listen(network, addr string) (net.Listener, error)
+}
+
+// Mode selects the forwarding direction, named after the ssh flags.
+type Mode string
+
+const (
+	ModeLocal   Mode = "L" // Client listens, host dials Target
+	ModeRemote  Mode = "R" // Host listens, client dials Target
+	ModeDynamic Mode = "D" // Client listens as a SOCKS5 proxy, host dials
+)
+
+// Spec describes a single forward.
+type Spec struct {
+	Mode   Mode
+	Bind   string // Listen address, e.g. "127.0.0.1:25565" (on the host for ModeRemote)
+	Target string // Dial address, e.g. "localhost:25565" (unused for ModeDynamic)
+}
+
+func (s Spec) String() string {
+	if s.Mode == ModeDynamic {
+		return fmt.Sprintf("D %s (SOCKS5)", s.Bind)
+	}
+	return fmt.Sprintf("%s %s -> %s", s.mode(), s.Bind, s.Target)
+}
+
+func (s Spec) mode() Mode {
+	if s.Mode == "" {
+		return ModeLocal
+	}
+	return s.Mode
+}
+
+// Status is a point-in-time snapshot of a tunnel for display.
+type Status struct {
+	ID       int
+	Env      string // Environment the forward was opened for (display only)
+	Spec     Spec
+	Conns    int64
+	BytesIn  uint64 // Host side -> client side
+	BytesOut uint64 // Client side -> host side
+	Err      string
+}
+
+type tunnel struct {
+	id       int
+	env      string
+	spec     Spec
+	listener net.Listener
+	err      error
+	conns    atomic.Int64
+	bytesIn  atomic.Uint64
+	bytesOut atomic.Uint64
+	closed   bool
+}
+
+// Manager keeps local listeners open across reconnects. Only the remote is
+// swapped when the SSH connection is replaced; remote listeners die with the
+// old connection and are opened again on the new one.
+type Manager struct {
+	mu      sync.Mutex
+	remote  Remote
+	tunnels []*tunnel
+	nextID  int
+}
+
+func NewManager() *Manager {
+	return &Manager{nextID: 1}
+}
+
+// SetRemote installs the current SSH connection and re-listens on any
+// forward whose listener was lost.
+func (m *Manager) SetRemote(r Remote) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remote = r
+
+	for _, t := range m.tunnels {
+		if t.spec.mode() == ModeRemote && t.listener != nil {
+			// Bound to the previous connection
+			t.listener.Close()
+			t.listener = nil
+		}
+		if t.listener == nil && !t.closed {
+			m.listen(t)
+		}
+	}
+}
+
+// Open starts a new forward.
+func (m *Manager) Open(env string, spec Spec) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := &tunnel{id: m.nextID, env: env, spec: spec}
+	if err := m.listen(t); err != nil {
+		return 0, err
+	}
+	m.nextID++
+	m.tunnels = append(m.tunnels, t)
+	return t.id, nil
+}
+
+// Close stops a forward and drops it from the list.
+func (m *Manager) Close(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, t := range m.tunnels {
+		if t.id == id {
+			t.closed = true
+			if t.listener != nil {
+				t.listener.Close()
+			}
+			m.tunnels = append(m.tunnels[:i], m.tunnels[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("tunnel %d not found", id)
+}
+
+// CloseAll stops every forward.
+func (m *Manager) CloseAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.tunnels {
+		t.closed = true
+		if t.listener != nil {
+			t.listener.Close()
+		}
+	}
+	m.tunnels = nil
+}
+
+// List returns a snapshot of all forwards.
+func (m *Manager) List() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Status, 0, len(m.tunnels))
+	for _, t := range m.tunnels {
+		s := Status{
+			ID:       t.id,
+			Env:      t.env,
+			Spec:     t.spec,
+			Conns:    t.conns.Load(),
+			BytesIn:  t.bytesIn.Load(),
+			BytesOut: t.bytesOut.Load(),
+		}
+		if t.err != nil {
+			s.Err = t.err.Error()
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+// listen must be called with m.mu held.
+func (m *Manager) listen(t *tunnel) error {
+	var ln net.Listener
+	var err error
+	if t.spec.mode() == ModeRemote {
+		if m.remote == nil {
+			err = fmt.Errorf("not connected")
+		} else {
+			ln, err = m.remote.Listen("tcp", t.spec.Bind)
+		}
+	} else {
+		ln, err = net.Listen("tcp", t.spec.Bind)
+	}
+	if err != nil {
+		t.err = err
+		return err
+	}
+	t.listener = ln
+	t.err = nil
+	go m.acceptLoop(t, ln)
+	return nil
+}
+
+func (m *Manager) acceptLoop(t *tunnel, ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			m.mu.Lock()
+			// A replaced listener must not clobber its successor
+			if !t.closed && t.listener == ln {
+				t.err = err
+				t.listener = nil
+			}
+			m.mu.Unlock()
+			return
+		}
+		go m.forward(t, conn)
+	}
+}
+
+func (m *Manager) forward(t *tunnel, accepted net.Conn) {
+	defer accepted.Close()
+
+	m.mu.Lock()
+	r := m.remote
+	m.mu.Unlock()
+
+	var err error
+	switch t.spec.mode() {
+	case ModeRemote:
+		// Accepted on the host, dial the service on this machine
+		var local net.Conn
+		if local, err = net.Dial("tcp", t.spec.Target); err == nil {
+			defer local.Close()
+			t.conns.Add(1)
+			defer t.conns.Add(-1)
+			pipe(accepted, local, &t.bytesIn, &t.bytesOut)
+			return
+		}
+
+	default:
+		target := t.spec.Target
+		if t.spec.mode() == ModeDynamic {
+			if target, err = socksHandshake(accepted); err != nil {
+				break
+			}
+		}
+		var remote net.Conn
+		if r == nil {
+			err = fmt.Errorf("not connected")
+		} else {
+			remote, err = r.Dial("tcp", target)
+		}
+		// The SOCKS client waits for an answer either way
+		if t.spec.mode() == ModeDynamic {
+			socksReply(accepted, err)
+		}
+		if err == nil {
+			defer remote.Close()
+			t.conns.Add(1)
+			defer t.conns.Add(-1)
+			pipe(accepted, remote, &t.bytesOut, &t.bytesIn)
+			return
+		}
+	}
+
+	m.mu.Lock()
+	t.err = err
+	m.mu.Unlock()
+}
+
+// pipe copies both directions until either side closes.
+func pipe(a, b net.Conn, aToB, bToA *atomic.Uint64) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(&countingWriter{w: b, n: aToB}, a)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(&countingWriter{w: a, n: bToA}, b)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Uint64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(uint64(n))
+	return n, err
+}
@@ -0,0 +1,208 @@
+package tunnel
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// loopRemote stands in for the SSH link; the "host" is this machine.
+type loopRemote struct{}
+
+func (loopRemote) Dial(network, addr string) (net.Conn, error) {
+	return net.Dial(network, addr)
+}
+
+func (loopRemote) Listen(network, addr string) (net.Listener, error) {
+	return net.Listen(network, addr)
+}
+
+func startEcho(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				line, _ := bufio.NewReader(c).ReadString('\n')
+				c.Write([]byte(line))
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// boundAddr returns the actual listen address of the only tunnel.
+func boundAddr(m *Manager) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tunnels[0].listener.Addr().String()
+}
+
+func roundTrip(t *testing.T, c net.Conn) {
+	t.Helper()
+	c.Write([]byte("ping\n"))
+	reply, err := bufio.NewReader(c).ReadString('\n')
+	c.Close()
+	if err != nil || reply != "ping\n" {
+		t.Fatalf("Expected echo, got %q (%v)", reply, err)
+	}
+}
+
+func TestLocalForward(t *testing.T) {
+	target := startEcho(t)
+
+	m := NewManager()
+	defer m.CloseAll()
+	m.SetRemote(loopRemote{})
+
+	id, err := m.Open("web", Spec{Mode: ModeLocal, Bind: "127.0.0.1:0", Target: target})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	c, err := net.Dial("tcp", boundAddr(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip(t, c)
+
+	// Counters are updated by the copy goroutines; give them a moment.
+	deadline := time.Now().Add(time.Second)
+	for {
+		s := m.List()[0]
+		if s.BytesIn == 5 && s.BytesOut == 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Unexpected counters: in=%d out=%d", s.BytesIn, s.BytesOut)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := m.Close(id); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(m.List()) != 0 {
+		t.Error("Tunnel still listed after Close")
+	}
+}
+
+func TestRemoteForwardNeedsConnection(t *testing.T) {
+	m := NewManager()
+	defer m.CloseAll()
+
+	if _, err := m.Open("", Spec{Mode: ModeRemote, Bind: "127.0.0.1:0", Target: "127.0.0.1:1"}); err == nil {
+		t.Fatal("Expected remote forward to fail without a connection")
+	}
+
+	m.SetRemote(loopRemote{})
+	target := startEcho(t)
+	if _, err := m.Open("", Spec{Mode: ModeRemote, Bind: "127.0.0.1:0", Target: target}); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	c, err := net.Dial("tcp", boundAddr(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip(t, c)
+}
+
+func TestDynamicForward(t *testing.T) {
+	target := startEcho(t)
+	host, portStr, _ := net.SplitHostPort(target)
+	port, _ := net.LookupPort("tcp", portStr)
+
+	m := NewManager()
+	defer m.CloseAll()
+	m.SetRemote(loopRemote{})
+	if _, err := m.Open("", Spec{Mode: ModeDynamic, Bind: "127.0.0.1:0"}); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	c, err := net.Dial("tcp", boundAddr(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(c)
+
+	c.Write([]byte{5, 1, 0})
+	greet := make([]byte, 2)
+	if _, err := r.Read(greet); err != nil || greet[1] != 0 {
+		t.Fatalf("Unexpected greeting reply %v (%v)", greet, err)
+	}
+
+	req := []byte{5, 1, 0, 3, byte(len(host))}
+	req = append(req, host...)
+	req = append(req, byte(port>>8), byte(port))
+	c.Write(req)
+	reply := make([]byte, 10)
+	if _, err := r.Read(reply); err != nil || reply[1] != 0 {
+		t.Fatalf("Unexpected connect reply %v (%v)", reply, err)
+	}
+
+	c.Write([]byte("ping\n"))
+	line, err := r.ReadString('\n')
+	c.Close()
+	if err != nil || line != "ping\n" {
+		t.Fatalf("Expected echo through SOCKS, got %q (%v)", line, err)
+	}
+}
+
+func TestDynamicForwardRefusesWhileDisconnected(t *testing.T) {
+	m := NewManager()
+	defer m.CloseAll()
+	if _, err := m.Open("", Spec{Mode: ModeDynamic, Bind: "127.0.0.1:0"}); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	c, err := net.Dial("tcp", boundAddr(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	c.Write([]byte{5, 1, 0})
+	greet := make([]byte, 2)
+	if _, err := io.ReadFull(c, greet); err != nil {
+		t.Fatal(err)
+	}
+	c.Write([]byte{5, 1, 0, 1, 127, 0, 0, 1, 0, 80})
+	reply := make([]byte, 10)
+	if _, err := io.ReadFull(c, reply); err != nil || reply[1] != 0x05 {
+		t.Fatalf("Expected a refused reply, got %v (%v)", reply, err)
+	}
+}
+
+func TestSpecEncoding(t *testing.T) {
+	cases := []struct {
+		spec Spec
+		env  string
+	}{
+		{Spec{Mode: ModeLocal, Bind: "127.0.0.1:25565", Target: "localhost:25565"}, "mc"},
+		{Spec{Mode: ModeRemote, Bind: "172.17.0.1:8080", Target: "127.0.0.1:3000"}, ""},
+		{Spec{Mode: ModeDynamic, Bind: "127.0.0.1:1080"}, "db"},
+	}
+	for _, c := range cases {
+		spec, env, err := ParseSpec(c.spec.Encode(c.env))
+		if err != nil {
+			t.Fatalf("ParseSpec(%q) failed: %v", c.spec.Encode(c.env), err)
+		}
+		if spec != c.spec || env != c.env {
+			t.Errorf("Roundtrip mismatch: %+v/%q vs %+v/%q", spec, env, c.spec, c.env)
+		}
+	}
+
+	if _, _, err := ParseSpec("L 127.0.0.1:80"); err == nil {
+		t.Error("Expected error for local forward without target")
+	}
+}
@@ -0,0 +1,102 @@
+package tunnel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// Minimal SOCKS5 server side (RFC 1928): no authentication, CONNECT only.
+// Name resolution happens on the host, so container names on Docker
+// networks resolve the same way they would there.
+
+const socksVersion = 5
+
+// socksHandshake negotiates with the client and returns the requested target.
+func socksHandshake(c net.Conn) (string, error) {
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(c, hdr); err != nil {
+		return "", err
+	}
+	if hdr[0] != socksVersion {
+		return "", fmt.Errorf("socks: unsupported version %d", hdr[0])
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(c, methods); err != nil {
+		return "", err
+	}
+	noAuth := false
+	for _, m := range methods {
+		if m == 0x00 {
+			noAuth = true
+		}
+	}
+	if !noAuth {
+		c.Write([]byte{socksVersion, 0xFF})
+		return "", fmt.Errorf("socks: client requires authentication")
+	}
+	if _, err := c.Write([]byte{socksVersion, 0x00}); err != nil {
+		return "", err
+	}
+
+	// VER CMD RSV ATYP
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(c, req); err != nil {
+		return "", err
+	}
+	if req[1] != 0x01 {
+		writeSocksStatus(c, 0x07) // Command not supported
+		return "", fmt.Errorf("socks: unsupported command %d", req[1])
+	}
+
+	var host string
+	switch req[3] {
+	case 0x01: // IPv4
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(c, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case 0x04: // IPv6
+		ip := make([]byte, 16)
+		if _, err := io.ReadFull(c, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case 0x03: // Domain name
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(c, l); err != nil {
+			return "", err
+		}
+		name := make([]byte, l[0])
+		if _, err := io.ReadFull(c, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		writeSocksStatus(c, 0x08) // Address type not supported
+		return "", fmt.Errorf("socks: unsupported address type %d", req[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(c, port); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// socksReply reports the outcome of the CONNECT to the client.
+func socksReply(c net.Conn, dialErr error) {
+	if dialErr != nil {
+		writeSocksStatus(c, 0x05) // Connection refused
+		return
+	}
+	writeSocksStatus(c, 0x00)
+}
+
+func writeSocksStatus(c net.Conn, status byte) {
+	// Bound address is not meaningful through the tunnel; report 0.0.0.0:0
+	c.Write([]byte{socksVersion, status, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+}
@@ -0,0 +1,46 @@
+package tunnel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Encode renders the spec in the form stored in client.ini:
+// "<mode> <bind> <target|-> [env]".
+func (s Spec) Encode(env string) string {
+	target := s.Target
+	if target == "" {
+		target = "-"
+	}
+	line := fmt.Sprintf("%s %s %s", s.mode(), s.Bind, target)
+	if env != "" {
+		line += " " + env
+	}
+	return line
+}
+
+// ParseSpec is the inverse of Encode and returns the spec and its environment.
+func ParseSpec(line string) (Spec, string, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return Spec{}, "", fmt.Errorf("invalid forward %q", line)
+	}
+
+	spec := Spec{Mode: Mode(strings.ToUpper(fields[0])), Bind: fields[1]}
+	switch spec.Mode {
+	case ModeLocal, ModeRemote:
+		if len(fields) < 3 || fields[2] == "-" {
+			return Spec{}, "", fmt.Errorf("forward %q needs a target", line)
+		}
+		spec.Target = fields[2]
+	case ModeDynamic:
+	default:
+		return Spec{}, "", fmt.Errorf("unknown forward mode %q", fields[0])
+	}
+
+	env := ""
+	if len(fields) > 3 {
+		env = fields[3]
+	}
+	return spec, env, nil
+}
@@ -25,3 +25,23 @@ func DeletePassword(host, user string) error {
 	key := fmt.Sprintf("%s@%s", user, host)
 	return keyring.Delete(serviceName, key)
 }
+
+// StoreRegistryPassword saves the password of a container registry login.
+func StoreRegistryPassword(registry, user, password string) error {
+	return keyring.Set(serviceName, registryKey(registry, user), password)
+}
+
+// GetRegistryPassword retrieves a container registry password.
+func GetRegistryPassword(registry, user string) (string, error) {
+	return keyring.Get(serviceName, registryKey(registry, user))
+}
+
+// DeleteRegistryPassword removes a container registry password.
+func DeleteRegistryPassword(registry, user string) error {
+	return keyring.Delete(serviceName, registryKey(registry, user))
+}
+
+// registryKey keeps registry logins apart from SSH logins to the same host.
+func registryKey(registry, user string) string {
+	return fmt.Sprintf("registry:%s@%s", user, registry)
+}
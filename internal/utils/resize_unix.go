@@ -0,0 +1,37 @@
+//go:build !windows
+
+package utils
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// NotifyResize calls fn whenever the controlling terminal is resized
+// (SIGWINCH). The returned function stops the notifications and waits for
+// a call to fn under way, so fn may use what the caller releases after.
+func NotifyResize(fn func()) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	signal.Notify(ch, syscall.SIGWINCH)
+
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-ch:
+				fn()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+		<-exited
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package utils
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNotifyResizeStopWaitsForCall(t *testing.T) {
+	called := make(chan struct{}, 1)
+	release := make(chan struct{})
+	stop := NotifyResize(func() {
+		select {
+		case called <- struct{}{}:
+		default:
+		}
+		<-release
+	})
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGWINCH)
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fn was not called on SIGWINCH")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("stop returned while fn was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package utils
+
+import "time"
+
+// NotifyResize calls fn periodically since Windows consoles have no resize
+// signal; fn is expected to ignore unchanged sizes. The returned function
+// stops the calls and waits for one under way.
+func NotifyResize(fn func()) (stop func()) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+		<-exited
+	}
+}